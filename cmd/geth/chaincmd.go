@@ -21,9 +21,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
-	"strings"
 	"sync/atomic"
 	"time"
 
@@ -31,6 +31,7 @@ import (
 	"github.com/DxChainNetwork/dxc/common"
 	"github.com/DxChainNetwork/dxc/common/hexutil"
 	"github.com/DxChainNetwork/dxc/consensus/dpos"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
 	"github.com/DxChainNetwork/dxc/core"
 	"github.com/DxChainNetwork/dxc/core/rawdb"
 	"github.com/DxChainNetwork/dxc/core/state"
@@ -40,6 +41,7 @@ import (
 	"github.com/DxChainNetwork/dxc/log"
 	"github.com/DxChainNetwork/dxc/metrics"
 	"github.com/DxChainNetwork/dxc/node"
+	"github.com/DxChainNetwork/dxc/params"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -197,16 +199,25 @@ func initGenesis(ctx *cli.Context) error {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
 
-	ga := new(core.GenesisAlloc)
-	err = json.NewDecoder(strings.NewReader(dpos.GenesisAlloc)).Decode(ga)
+	// Merge the user allocation over the system contracts, which may be overridden
+	// by the dpos config with inline code or code files next to the genesis file.
+	var dposConfig *params.DposConfig
+	if genesis.Config != nil {
+		dposConfig = genesis.Config.Dpos
+	}
+	if dposConfig != nil {
+		if err := systemcontract.LoadContractFiles(dposConfig.SystemContracts, filepath.Dir(genesisPath)); err != nil {
+			utils.Fatalf("Failed to load system contracts: %v", err)
+		}
+	}
+	ga, err := dpos.SystemContractsAlloc(dposConfig)
 	if err != nil {
-		panic(err)
+		utils.Fatalf("Invalid system contracts: %v", err)
 	}
-
 	for i, v := range genesis.Alloc {
-		(*ga)[i] = v
+		ga[i] = v
 	}
-	genesis.Alloc = *ga
+	genesis.Alloc = ga
 
 	// Open and initialise both full and light databases
 	stack, _ := makeConfigNode(ctx)
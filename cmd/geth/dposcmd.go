@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/cmd/utils"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus/dpos"
+	"github.com/DxChainNetwork/dxc/core"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/eth/ethconfig"
+	"github.com/DxChainNetwork/dxc/ethdb"
+	"github.com/DxChainNetwork/dxc/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dposCommand = cli.Command{
+		Name:      "dpos",
+		Usage:     "A set of commands for the dpos consensus engine",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			dposUpgradesCmd,
+			dposPreviewCmd,
+			dposDevsCmd,
+		},
+	}
+	dposUpgradesCmd = cli.Command{
+		Action: utils.MigrateFlags(dposUpgrades),
+		Name:   "upgrades",
+		Usage:  "List the scheduled system contract upgrades",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.TestnetFlag,
+		},
+		Description: `
+geth dpos upgrades
+lists the system contract upgrades of the stored chain config, marking the ones
+at or below the head block as applied and the others as pending.`,
+	}
+	dposPreviewCmd = cli.Command{
+		Action: utils.MigrateFlags(dposPreview),
+		Name:   "preview",
+		Usage:  "Preview the outcome of the next epoch transition",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.TestnetFlag,
+		},
+		Description: `
+geth dpos preview
+runs the election of the next epoch against a copy of the head state and prints
+the predicted validators, the validators kicked out in the current epoch, the
+rewards distributed at the transition and the pending governance proposals.
+The node must be stopped, use dpos.previewNextEpoch() on a running one.`,
+	}
+	dposDevGasPriceFlag = cli.Uint64Flag{
+		Name:  "gasprice",
+		Usage: "Gas price of the built transaction in wei",
+		Value: ethconfig.Defaults.Miner.GasPrice.Uint64(),
+	}
+	dposDevsCmd = cli.Command{
+		Name:  "devs",
+		Usage: "Manage the developer allowlist of contract creation",
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(dposDevsAdd),
+				Name:      "add",
+				Usage:     "Build the transaction adding a developer to the allowlist",
+				ArgsUsage: "<address>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.TestnetFlag,
+					dposDevGasPriceFlag,
+				},
+				Description: `
+geth dpos devs add <address>
+builds the unsigned transaction of the address list admin adding the address to
+the developers allowed to create contracts, on top of the head state. It must be
+signed by the admin and sent with eth.sendRawTransaction.`,
+			},
+			{
+				Action:    utils.MigrateFlags(dposDevsRemove),
+				Name:      "remove",
+				Usage:     "Build the transaction removing a developer from the allowlist",
+				ArgsUsage: "<address>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.TestnetFlag,
+					dposDevGasPriceFlag,
+				},
+				Description: `
+geth dpos devs remove <address>
+builds the unsigned transaction of the address list admin removing the address
+from the developers allowed to create contracts, on top of the head state. It must
+be signed by the admin and sent with eth.sendRawTransaction.`,
+			},
+			{
+				Action: utils.MigrateFlags(dposDevsList),
+				Name:   "list",
+				Usage:  "List the developers allowed to create contracts",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.SyncModeFlag,
+					utils.MainnetFlag,
+					utils.TestnetFlag,
+				},
+				Description: `
+geth dpos devs list
+lists the allowlisted developers from the developer index, which is maintained
+by the nodes running with --dpos.devindex.`,
+			},
+		},
+	}
+)
+
+// readStoredChainConfig loads the chain config stored along with the genesis.
+func readStoredChainConfig(db ethdb.Database) (*params.ChainConfig, error) {
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return nil, errors.New("no genesis block found")
+	}
+	config := rawdb.ReadChainConfig(db, genesis)
+	if config == nil {
+		return nil, errors.New("no chain config found")
+	}
+	return config, nil
+}
+
+func dposUpgrades(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	config, err := readStoredChainConfig(db)
+	if err != nil {
+		return err
+	}
+	head := rawdb.ReadHeadHeader(db)
+	if head == nil {
+		return errors.New("no head block found")
+	}
+	if len(config.SystemContractUpgrades) == 0 {
+		fmt.Println("No system contract upgrades scheduled")
+		return nil
+	}
+	fmt.Printf("Head block: %d\n", head.Number)
+	for _, upgrade := range config.SystemContractUpgrades {
+		status := "pending"
+		if upgrade.Block.Cmp(head.Number) <= 0 {
+			status = "applied"
+		}
+		migration := upgrade.Migration
+		if migration == "" {
+			migration = "-"
+		}
+		fmt.Printf("%-8s block=%v address=%s codeHash=%s storage=%d migration=%s\n",
+			status, upgrade.Block, upgrade.Address.Hex(), crypto.Keccak256Hash(upgrade.Code).Hex(), len(upgrade.Storage), migration)
+	}
+	return nil
+}
+
+func dposPreview(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	config, err := readStoredChainConfig(db)
+	if err != nil {
+		return err
+	}
+	if config.Dpos == nil {
+		return errors.New("not a dpos chain")
+	}
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return errors.New("no head block found")
+	}
+	stateDb := state.NewDatabase(db)
+	statedb, err := state.New(head.Root(), stateDb, nil)
+	if err != nil {
+		return fmt.Errorf("head state missing: %v", err)
+	}
+	engine := dpos.New(config, db)
+	defer engine.Close()
+
+	chain, err := core.NewHeaderChain(db, config, engine, func() bool { return false })
+	if err != nil {
+		return err
+	}
+	engine.SetChain(chain)
+	engine.SetStateFn(func(root common.Hash) (*state.StateDB, error) {
+		return state.New(root, stateDb, nil)
+	})
+	preview, err := engine.PreviewNextEpoch(chain, head.Header(), statedb)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Head block: %d\n", preview.Number)
+	fmt.Printf("Next epoch: %d (election at block %d, checkpoint %d)\n", preview.Epoch, preview.Election, preview.Checkpoint)
+	if preview.Random {
+		fmt.Println("More candidates than seats, the elected validators depend on the block hashes up to the election")
+	}
+	fmt.Printf("Validators: %d, added %d, removed %d\n", len(preview.Validators), len(preview.Added), len(preview.Removed))
+	for _, val := range preview.Added {
+		fmt.Printf("  + %s\n", val.Hex())
+	}
+	for _, val := range preview.Removed {
+		fmt.Printf("  - %s\n", val.Hex())
+	}
+	for _, val := range preview.Kickouts {
+		fmt.Printf("Kicked out: %s\n", val.Hex())
+	}
+	fmt.Printf("Next block reward: %v, tvl: %v\n", preview.EpochInfo.BlockReward, preview.EpochInfo.Tvl)
+	for _, reward := range preview.Rewards {
+		fmt.Printf("Reward %s validator=%v delegators=%v rate=%d elected=%t\n",
+			reward.Validator.Hex(), reward.ValidatorReward.ToInt(), reward.DelegatorsReward.ToInt(), reward.Rate, reward.Elected)
+	}
+	for _, prop := range preview.Proposals {
+		status := "success"
+		if prop.Simulation.Error != "" {
+			status = prop.Simulation.Error
+		}
+		fmt.Printf("Proposal %v to=%s action=%v: %s\n", prop.Id.ToInt(), prop.To.Hex(), prop.Action.ToInt(), status)
+	}
+	return nil
+}
+
+func dposDevsAdd(ctx *cli.Context) error {
+	return dposDevsBuild(ctx, true)
+}
+
+func dposDevsRemove(ctx *cli.Context) error {
+	return dposDevsBuild(ctx, false)
+}
+
+// dposDevsBuild prints the unsigned transaction of the address list admin adding
+// the developer to the allowlist, or removing it.
+func dposDevsBuild(ctx *cli.Context, add bool) error {
+	if ctx.NArg() != 1 || !common.IsHexAddress(ctx.Args().First()) {
+		return errors.New("expected a developer address as the only argument")
+	}
+	dev := common.HexToAddress(ctx.Args().First())
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	config, err := readStoredChainConfig(db)
+	if err != nil {
+		return err
+	}
+	if config.Dpos == nil {
+		return errors.New("not a dpos chain")
+	}
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil {
+		return errors.New("no head block found")
+	}
+	statedb, err := state.New(head.Root(), state.NewDatabase(db), nil)
+	if err != nil {
+		return fmt.Errorf("head state missing: %v", err)
+	}
+	tx, admin, err := dpos.BuildDeveloperTx(statedb, dev, add, new(big.Int).SetUint64(ctx.Uint64(dposDevGasPriceFlag.Name)))
+	if err != nil {
+		return err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(tx, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Head block: %d\n", head.Number())
+	fmt.Printf("Admin: %s\n", admin.Hex())
+	fmt.Printf("Chain id: %v\n", config.ChainID)
+	fmt.Printf("Transaction: %s\n", out)
+	fmt.Printf("Unsigned: %s\n", hexutil.Encode(raw))
+	return nil
+}
+
+func dposDevsList(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	number, _, ok := rawdb.ReadDposDevIndexHead(db)
+	if !ok {
+		return errors.New("no developer index found, run the node with --dpos.devindex")
+	}
+	fmt.Printf("Indexed block: %d\n", number)
+	opts := new(dpos.DeveloperPageOptions)
+	for {
+		page, err := dpos.ListDevelopers(db, opts)
+		if err != nil {
+			return err
+		}
+		for _, dev := range page.Developers {
+			fmt.Println(dev.Hex())
+		}
+		if page.Next == nil {
+			return nil
+		}
+		opts.Cursor = page.Next
+	}
+}
@@ -91,6 +91,10 @@ var (
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.DposEpochIndexFlag,
+		utils.DposVoteIndexFlag,
+		utils.DposDevIndexFlag,
+		utils.DposSigningKeyFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -235,6 +239,8 @@ func init() {
 		utils.ShowDeprecated,
 		// See snapshot.go
 		snapshotCommand,
+		// See dposcmd.go
+		dposCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
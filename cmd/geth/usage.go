@@ -47,6 +47,10 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.DposEpochIndexFlag,
+			utils.DposVoteIndexFlag,
+			utils.DposDevIndexFlag,
+			utils.DposSigningKeyFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
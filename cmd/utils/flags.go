@@ -214,6 +214,22 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	DposEpochIndexFlag = cli.BoolFlag{
+		Name:  "dpos.epochindex",
+		Usage: "Index the dpos staking state at every epoch checkpoint to serve historical queries after pruning",
+	}
+	DposVoteIndexFlag = cli.BoolFlag{
+		Name:  "dpos.voteindex",
+		Usage: "Index the dpos votes by validator and by voter to serve paginated vote queries",
+	}
+	DposDevIndexFlag = cli.BoolFlag{
+		Name:  "dpos.devindex",
+		Usage: "Index the developer allowlist of the address list contract to serve developer listings",
+	}
+	DposSigningKeyFlag = cli.StringFlag{
+		Name:  "dpos.signingkey",
+		Usage: "Account sealing the blocks of the etherbase validator, once bound to it through the SigningKeys contract",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -1513,6 +1529,22 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(DposEpochIndexFlag.Name) {
+		cfg.DposEpochIndex = ctx.GlobalBool(DposEpochIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposVoteIndexFlag.Name) {
+		cfg.DposVoteIndex = ctx.GlobalBool(DposVoteIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposDevIndexFlag.Name) {
+		cfg.DposDevIndex = ctx.GlobalBool(DposDevIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposSigningKeyFlag.Name) {
+		key := ctx.GlobalString(DposSigningKeyFlag.Name)
+		if !common.IsHexAddress(key) {
+			Fatalf("Invalid dpos signing key: %v", key)
+		}
+		cfg.DposSigningKey = common.HexToAddress(key)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
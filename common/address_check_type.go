@@ -1,5 +1,7 @@
 package common
 
+import "fmt"
+
 const (
 	CheckNone AddressCheckType = iota
 	CheckFrom
@@ -8,3 +10,35 @@ const (
 )
 
 type AddressCheckType int
+
+// String implements fmt.Stringer.
+func (t AddressCheckType) String() string {
+	switch t {
+	case CheckNone:
+		return "none"
+	case CheckFrom:
+		return "from"
+	case CheckTo:
+		return "to"
+	case CheckBothInAny:
+		return "any"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t AddressCheckType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *AddressCheckType) UnmarshalText(input []byte) error {
+	for _, typ := range []AddressCheckType{CheckNone, CheckFrom, CheckTo, CheckBothInAny} {
+		if typ.String() == string(input) {
+			*t = typ
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown address check type %q", input)
+}
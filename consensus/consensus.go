@@ -144,8 +144,14 @@ type PoSA interface {
 	// IsSysTransaction checks whether a specific transaction is a system transaction.
 	IsSysTransaction(sender common.Address, tx *types.Transaction, header *types.Header) (bool, error)
 
-	// CanCreate determines where a given address can create a new contract.
-	CanCreate(state StateReader, addr common.Address, height *big.Int) bool
+	// CanCreate determines whether the caller can create a new contract within a
+	// transaction sent by the origin.
+	CanCreate(state StateReader, origin, caller common.Address, height *big.Int) bool
+
+	// ContractCreated records the contract created by the caller, the contracts
+	// created by the new one in turn may be authorized through it. It returns the
+	// gas charged to the creation for the record.
+	ContractCreated(state StateReadWriter, caller, contract common.Address, height *big.Int) uint64
 
 	// ValidateTx do a consensus-related validation on the given transaction at the given header and state.
 	ValidateTx(sender common.Address, tx *types.Transaction, header *types.Header, parentState *state.StateDB) error
@@ -163,3 +169,10 @@ type PoSA interface {
 type StateReader interface {
 	GetState(addr common.Address, hash common.Hash) common.Hash
 }
+
+type StateReadWriter interface {
+	StateReader
+	SetState(addr common.Address, hash common.Hash, value common.Hash)
+	GetNonce(addr common.Address) uint64
+	SetNonce(addr common.Address, nonce uint64)
+}
@@ -17,6 +17,7 @@
 package dpos
 
 import (
+	"context"
 	"fmt"
 	"github.com/DxChainNetwork/dxc/common"
 	"github.com/DxChainNetwork/dxc/common/hexutil"
@@ -24,8 +25,10 @@ import (
 	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
 	"github.com/DxChainNetwork/dxc/core/state"
 	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/log"
 	"github.com/DxChainNetwork/dxc/rpc"
 	"math/big"
+	"sort"
 )
 
 // API is a user facing RPC API to allow controlling the validator and voting
@@ -49,13 +52,21 @@ type ProposalInfo struct {
 	Status      uint8
 }
 
-func (api *API) GetHeaderAndState(number *rpc.BlockNumber) (*types.Header, *state.StateDB, error) {
-	var header *types.Header
-	if number == nil || *number == rpc.LatestBlockNumber {
-		header = api.chain.CurrentHeader()
-	} else {
-		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+// headerByNumber resolves the requested block number, the latest block if none
+// is requested, or nil if the block is unknown.
+func (api *API) headerByNumber(number *rpc.BlockNumber) *types.Header {
+	switch {
+	case number == nil || *number == rpc.LatestBlockNumber:
+		return api.chain.CurrentHeader()
+	case *number == rpc.FinalizedBlockNumber:
+		return api.dpos.FinalizedHeader(api.chain)
+	default:
+		return api.chain.GetHeaderByNumber(uint64(number.Int64()))
 	}
+}
+
+func (api *API) GetHeaderAndState(number *rpc.BlockNumber) (*types.Header, *state.StateDB, error) {
+	header := api.headerByNumber(number)
 	if header == nil {
 		return nil, nil, errUnknownBlock
 	}
@@ -63,22 +74,129 @@ func (api *API) GetHeaderAndState(number *rpc.BlockNumber) (*types.Header, *stat
 	return header, statedb, err
 }
 
+// epochIndex returns the staking state indexed at the given block, answering the
+// queries of the epoch checkpoints whose state was pruned with the exact state.
+// The other blocks are not indexed, the state error then points at the checkpoint
+// of their epoch.
+//
+// The index answers GetValidator, GetTotalDeposit, GetTotalVotes,
+// GetCurrentEpochValidators and GetEffictiveValidators at the checkpoints, as well
+// as EpochInfo and ValidatorRewardInfoByEpoch once settled. The other methods need
+// the state of the block.
+func (api *API) epochIndex(header *types.Header, err error) (*EpochIndex, error) {
+	if header == nil {
+		return nil, err
+	}
+	number := header.Number.Uint64()
+	index := api.dpos.readEpochIndex(api.chain, number)
+	if index == nil {
+		return nil, err
+	}
+	if checkpoint := api.dpos.config.EpochStart(number); checkpoint != number {
+		return nil, fmt.Errorf("%w, the staking state is indexed at the epoch checkpoint %d", err, checkpoint)
+	}
+	return index, nil
+}
+
 // GetSnapshot retrieves the state snapshot at a given block.
 func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
-	// Retrieve the requested block number (or current if none requested)
-	var header *types.Header
-	if number == nil || *number == rpc.LatestBlockNumber {
-		header = api.chain.CurrentHeader()
-	} else {
-		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
-	}
-	// Ensure we have an actually valid block and return its snapshot
+	header := api.headerByNumber(number)
 	if header == nil {
 		return nil, errUnknownBlock
 	}
 	return api.dpos.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 }
 
+// ConsensusParams is the block period and epoch schedule in effect at a block.
+type ConsensusParams struct {
+	Period      uint64 `json:"period"`
+	Epoch       uint64 `json:"epoch"`
+	EpochNumber uint64 `json:"epochNumber"`
+	EpochStart  uint64 `json:"epochStart"`
+}
+
+// GetConsensusParams retrieves the block period and epoch schedule in effect at a given block.
+func (api *API) GetConsensusParams(number *rpc.BlockNumber) (*ConsensusParams, error) {
+	header := api.headerByNumber(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	config, num := api.dpos.config, header.Number.Uint64()
+	return &ConsensusParams{
+		Period:      config.PeriodAt(num),
+		Epoch:       config.Epoch,
+		EpochNumber: config.EpochNumber(num),
+		EpochStart:  config.EpochStart(num),
+	}, nil
+}
+
+// GetValidatorSetProof retrieves the epoch checkpoint headers following the checkpoint
+// at the given number, up to the given block, which prove the validator set transitions
+// in between.
+func (api *API) GetValidatorSetProof(from hexutil.Uint64, to *rpc.BlockNumber) (*ValidatorSetProof, error) {
+	header := api.headerByNumber(to)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.dpos.ValidatorSetProof(api.chain, uint64(from), header)
+}
+
+// EpochLiveness is the block production record of all validators within an epoch.
+type EpochLiveness struct {
+	Epoch      uint64                       `json:"epoch"`
+	StartBlock uint64                       `json:"startBlock"`
+	Validators map[common.Address]*Liveness `json:"validators"`
+}
+
+// ValidatorLiveness is the block production record of a validator within an epoch.
+type ValidatorLiveness struct {
+	Epoch uint64 `json:"epoch"`
+	*Liveness
+}
+
+// GetEpochLiveness retrieves the produced, missed and out-of-turn block counts of
+// every validator in the epoch of a given block, up to that block.
+func (api *API) GetEpochLiveness(number *rpc.BlockNumber) (*EpochLiveness, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	epoch := api.dpos.config.EpochNumber(snap.Number)
+	liveness := &EpochLiveness{
+		Epoch:      epoch,
+		StartBlock: api.dpos.config.EpochStart(snap.Number),
+		Validators: make(map[common.Address]*Liveness),
+	}
+	for validator, record := range snap.Liveness[epoch] {
+		liveness.Validators[validator] = record
+	}
+	return liveness, nil
+}
+
+// GetValidatorLiveness retrieves the produced, missed and out-of-turn block counts
+// of a validator in each of the recent epochs tracked at a given block.
+func (api *API) GetValidatorLiveness(validator common.Address, number *rpc.BlockNumber) ([]*ValidatorLiveness, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	epochs := make([]uint64, 0, len(snap.Liveness))
+	for epoch := range snap.Liveness {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+
+	result := make([]*ValidatorLiveness, 0, len(epochs))
+	for _, epoch := range epochs {
+		record := snap.Liveness[epoch][validator]
+		if record == nil {
+			record = new(Liveness)
+		}
+		result = append(result, &ValidatorLiveness{Epoch: epoch, Liveness: record})
+	}
+	return result, nil
+}
+
 // GetSnapshotAtHash retrieves the state snapshot at a given block.
 func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
 	header := api.chain.GetHeaderByHash(hash)
@@ -90,14 +208,7 @@ func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
 
 // GetValidators retrieves the list of authorized validators at the specified block.
 func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
-	// Retrieve the requested block number (or current if none requested)
-	var header *types.Header
-	if number == nil || *number == rpc.LatestBlockNumber {
-		header = api.chain.CurrentHeader()
-	} else {
-		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
-	}
-	// Ensure we have an actually valid block and return the validators from its snapshot
+	header := api.headerByNumber(number)
 	if header == nil {
 		return nil, errUnknownBlock
 	}
@@ -139,6 +250,12 @@ func (api *API) GetValidator(addr common.Address, number *rpc.BlockNumber) (*sys
 	validators := systemcontract.NewValidators()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
+		index, err := api.epochIndex(header, err)
+		if index != nil {
+			if record, ok := index.record(addr); ok {
+				return &record.Validator, nil
+			}
+		}
 		return &systemcontract.Validator{}, err
 	}
 	val, err := validators.GetValidator(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, addr)
@@ -148,11 +265,84 @@ func (api *API) GetValidator(addr common.Address, number *rpc.BlockNumber) (*sys
 	return val, nil
 }
 
+// SigningKey is the key a validator seals its blocks with, and the key requested
+// to take over at the next checkpoint.
+type SigningKey struct {
+	Validator common.Address  `json:"validator"`
+	Key       common.Address  `json:"key"`
+	Pending   *common.Address `json:"pending,omitempty"`
+}
+
+// GetSigningKey retrieves the signing key bound to the validator at the given block.
+func (api *API) GetSigningKey(addr common.Address, number *rpc.BlockNumber) (*SigningKey, error) {
+	_, statedb, err := api.GetHeaderAndState(number)
+	if err != nil {
+		return nil, err
+	}
+	key := &SigningKey{Validator: addr, Key: addr}
+	if active := readSigningKeyEntry(statedb, addr, signingKeySlot); active != (common.Address{}) {
+		key.Key = active
+	}
+	if pending := readSigningKeyEntry(statedb, addr, pendingKeySlot); pending != (common.Address{}) {
+		key.Pending = &pending
+	}
+	return key, nil
+}
+
+// blacklistHeader returns the state at the given block along with a header of the
+// block on top of it, the blacklist and event rules cached for it being the ones
+// enforced on the transactions following the given block.
+func (api *API) blacklistHeader(number *rpc.BlockNumber) (*types.Header, *state.StateDB, error) {
+	header, statedb, err := api.GetHeaderAndState(number)
+	if err != nil {
+		return nil, nil, err
+	}
+	next := &types.Header{
+		ParentHash: header.Hash(),
+		Number:     new(big.Int).Add(header.Number, common.Big1),
+		GasLimit:   header.GasLimit,
+		Time:       header.Time + api.dpos.config.PeriodAt(header.Number.Uint64()+1),
+		Difficulty: new(big.Int).Set(diffInTurn),
+		Coinbase:   header.Coinbase,
+	}
+	return next, statedb, nil
+}
+
+// GetBlacklist retrieves the blacklisted addresses and their entries as of the given
+// block, including the expired ones.
+func (api *API) GetBlacklist(number *rpc.BlockNumber) (map[common.Address][]BlacklistEntry, error) {
+	header, statedb, err := api.blacklistHeader(number)
+	if err != nil {
+		return nil, err
+	}
+	if sophon := api.dpos.chainConfig.SophonBlock; sophon == nil || sophon.Cmp(header.Number) >= 0 {
+		return map[common.Address][]BlacklistEntry{}, nil
+	}
+	return api.dpos.getBlacklist(header, statedb)
+}
+
+// GetEventCheckRules retrieves the event rules checking the log topics against the
+// blacklist as of the given block.
+func (api *API) GetEventCheckRules(number *rpc.BlockNumber) (map[common.Hash]*EventCheckRule, error) {
+	header, statedb, err := api.blacklistHeader(number)
+	if err != nil {
+		return nil, err
+	}
+	if sophon := api.dpos.chainConfig.SophonBlock; sophon == nil || sophon.Cmp(header.Number) >= 0 {
+		return map[common.Hash]*EventCheckRule{}, nil
+	}
+	return api.dpos.getEventCheckRules(header, statedb)
+}
+
 // GetTotalDeposit return total deposit
 func (api *API) GetTotalDeposit(number *rpc.BlockNumber) (*big.Int, error) {
 	validators := systemcontract.NewValidators()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
+		index, err := api.epochIndex(header, err)
+		if index != nil {
+			return index.TotalDeposit, nil
+		}
 		return big.NewInt(0), err
 	}
 	deposit, err := validators.TotalDeposit(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig)
@@ -167,6 +357,10 @@ func (api *API) GetTotalVotes(number *rpc.BlockNumber) (*big.Int, error) {
 	nodeVotes := systemcontract.NewNodeVotes()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
+		index, err := api.epochIndex(header, err)
+		if index != nil {
+			return index.TotalVotes, nil
+		}
 		return big.NewInt(0), err
 	}
 	totalVotes, err := nodeVotes.TotalVotes(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig)
@@ -181,6 +375,10 @@ func (api *API) GetCurrentEpochValidators(number *rpc.BlockNumber) ([]common.Add
 	validators := systemcontract.NewValidators()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
+		index, err := api.epochIndex(header, err)
+		if index != nil {
+			return index.Validators, nil
+		}
 		return []common.Address{}, err
 	}
 	curValidators, err := validators.GetCurrentEpochValidators(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig)
@@ -192,40 +390,19 @@ func (api *API) GetCurrentEpochValidators(number *rpc.BlockNumber) ([]common.Add
 
 // GetEffictiveValidators return all effictive validators
 func (api *API) GetEffictiveValidators(number *rpc.BlockNumber) ([]common.Address, error) {
-	validators := systemcontract.NewValidators()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
+		index, err := api.epochIndex(header, err)
+		if index != nil {
+			return index.Effective, nil
+		}
 		return []common.Address{}, err
 	}
-
-	size := big.NewInt(50)
-	var allValidators []common.Address
-
-	count, err := validators.EffictiveValsLength(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig)
+	vals, err := api.dpos.effectiveValidators(api.chain, header, statedb)
 	if err != nil {
 		return []common.Address{}, err
 	}
-
-	if count.Cmp(size) <= 0 {
-		page := big.NewInt(1)
-		allValidators, err = validators.GetEffictiveValidatorsWithPage(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, page, size)
-		if err != nil {
-			return []common.Address{}, err
-		}
-	} else {
-		var res big.Int
-		div := res.Div(count, size)
-		div = res.Add(div, big.NewInt(1))
-		for i := int64(1); i <= div.Int64(); i++ {
-			voters, err := validators.GetEffictiveValidatorsWithPage(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, big.NewInt(i), size)
-			if err != nil {
-				return []common.Address{}, err
-			}
-			allValidators = append(allValidators, voters...)
-		}
-	}
-
-	return allValidators, nil
+	return vals, nil
 }
 
 // GetInvalidValidators return all invalid validators
@@ -318,6 +495,31 @@ func (api *API) GetValidatorVoters(addr common.Address, number *rpc.BlockNumber)
 	return allVoters, nil
 }
 
+// ValidatorVotes returns a page of the votes for the validator from the vote index.
+func (api *API) ValidatorVotes(validator common.Address, opts *VotePageOptions) (*VotePage, error) {
+	return api.dpos.votesPage(validator, true, opts)
+}
+
+// VoterVotes returns a page of the votes of the voter from the vote index.
+func (api *API) VoterVotes(voter common.Address, opts *VotePageOptions) (*VotePage, error) {
+	return api.dpos.votesPage(voter, false, opts)
+}
+
+// IsDeveloper reports whether the address is in the developer allowlist of the
+// address list contract at the given block.
+func (api *API) IsDeveloper(addr common.Address, number *rpc.BlockNumber) (bool, error) {
+	_, statedb, err := api.GetHeaderAndState(number)
+	if err != nil {
+		return false, err
+	}
+	return isDeveloper(statedb, addr), nil
+}
+
+// ListDevelopers returns a page of the allowlisted developers from the developer index.
+func (api *API) ListDevelopers(opts *DeveloperPageOptions) (*DeveloperPage, error) {
+	return api.dpos.developersPage(opts)
+}
+
 // EffictiveValsLength return effictive validators length
 func (api *API) EffictiveValsLength(number *rpc.BlockNumber) (*big.Int, error) {
 	validators := systemcontract.NewValidators()
@@ -703,41 +905,15 @@ func (api *API) VotesRewardRedeemInfo(val common.Address, voter common.Address,
 
 // VotesRewardRedeemInfos nodevotes.VotesRewardRedeemInfos
 func (api *API) VotesRewardRedeemInfos(voter common.Address, number *rpc.BlockNumber) ([]systemcontract.VotesRewardRedeemInfo, error) {
-	nodeVotes := systemcontract.NewNodeVotes()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
 		return []systemcontract.VotesRewardRedeemInfo{}, err
 	}
-
-	size := big.NewInt(50)
-	var allInfos []systemcontract.VotesRewardRedeemInfo
-
-	count, err := nodeVotes.VoteListLength(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, voter)
+	infos, err := api.dpos.voterInfos(api.chain, header, statedb, voter)
 	if err != nil {
 		return []systemcontract.VotesRewardRedeemInfo{}, err
 	}
-
-	if count.Cmp(size) <= 0 {
-		page := big.NewInt(1)
-		allInfos, err = nodeVotes.VotesRewardRedeemInfoWithPage(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, voter, page, size)
-		if err != nil {
-			return []systemcontract.VotesRewardRedeemInfo{}, err
-		}
-	} else {
-		var res big.Int
-		div := res.Div(count, size)
-		div = res.Add(div, big.NewInt(1))
-		for i := int64(1); i <= div.Int64(); i++ {
-			infos, err := nodeVotes.VotesRewardRedeemInfoWithPage(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, voter, big.NewInt(i), size)
-			if err != nil {
-				return []systemcontract.VotesRewardRedeemInfo{}, err
-			}
-			allInfos = append(allInfos, infos...)
-		}
-		return allInfos, nil
-	}
-
-	return allInfos, nil
+	return infos, nil
 }
 
 // systemRewards
@@ -757,6 +933,12 @@ func (api *API) EpochInfo(epoch *big.Int, number *rpc.BlockNumber) (*systemcontr
 	systemRewards := systemcontract.NewSystemRewards()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
+		// The info of an epoch is settled by its checkpoint
+		if first, _ := api.dpos.epochBlocks(epoch.Uint64()); header != nil && first <= header.Number.Uint64() {
+			if index := api.dpos.readEpochIndex(api.chain, first); index != nil {
+				return &index.Info, nil
+			}
+		}
 		return &systemcontract.EpochInfo{}, err
 	}
 	epochInfo, err := systemRewards.GetEpochInfo(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, epoch)
@@ -812,6 +994,14 @@ func (api *API) ValidatorRewardInfoByEpoch(addr common.Address, epoch *big.Int,
 	systemRewards := systemcontract.NewSystemRewards()
 	header, statedb, err := api.GetHeaderAndState(number)
 	if err != nil {
+		// The rewards of an epoch are settled by the checkpoint of the next one
+		if next, _ := api.dpos.epochBlocks(epoch.Uint64() + 1); header != nil && next <= header.Number.Uint64() {
+			if index := api.dpos.readEpochIndex(api.chain, next); index != nil {
+				if record, ok := index.record(addr); ok {
+					return &record.Reward, nil
+				}
+			}
+		}
 		return &systemcontract.Reward{}, err
 	}
 	rewards, err := systemRewards.GetValRewardInfoByEpoch(statedb, header, newChainContext(api.chain, api.dpos), api.dpos.chainConfig, addr, epoch)
@@ -853,6 +1043,78 @@ func (api *API) PunishInfo(addr common.Address, epoch *big.Int, number *rpc.Bloc
 	return punish, nil
 }
 
+// SubmitDoubleSignEvidence verifies the double sign evidence and queues it, the local
+// validator will pack it into a block to slash the offender. The evidence is checked
+// as the next block would.
+func (api *API) SubmitDoubleSignEvidence(ev DoubleSignEvidence) (common.Hash, error) {
+	header, statedb, err := api.GetHeaderAndState(nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	next := &types.Header{Number: new(big.Int).Add(header.Number, common.Big1), ParentHash: header.Hash()}
+	offender, height, err := api.dpos.verifyEvidenceAt(api.chain, next, statedb, &ev)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Double sign evidence submitted", "validator", offender, "number", height)
+	return api.dpos.addEvidence(&ev), nil
+}
+
+// GetPendingDoubleSignEvidences returns the double sign evidences waiting to be packed.
+func (api *API) GetPendingDoubleSignEvidences() []*DoubleSignEvidence {
+	return api.dpos.pendingEvidences()
+}
+
+// RewardHistory retrieves the rewards the given account earned as a validator and
+// as a voter in each epoch from fromEpoch to toEpoch inclusive.
+func (api *API) RewardHistory(addr common.Address, fromEpoch hexutil.Uint64, toEpoch hexutil.Uint64) (*RewardHistory, error) {
+	header, statedb, err := api.GetHeaderAndState(nil)
+	if err != nil {
+		return nil, err
+	}
+	return api.dpos.rewardHistory(api.chain, header, statedb, addr, uint64(fromEpoch), uint64(toEpoch))
+}
+
+// SimulateGovernanceProposal executes the system governance proposal against a copy
+// of the latest state, as the next block would if the proposal passed, and returns
+// its receipt, logs, state changes and revert reason.
+func (api *API) SimulateGovernanceProposal(args GovernanceProposal) (*GovernanceSimulation, error) {
+	header, statedb, err := api.GetHeaderAndState(nil)
+	if err != nil {
+		return nil, err
+	}
+	return api.dpos.simulateProposal(api.chain, header, statedb, args.proposal())
+}
+
+// PendingGovernanceProposals returns the passed system governance proposals which
+// are not executed yet, they will be executed by the block following the given one.
+func (api *API) PendingGovernanceProposals(number *rpc.BlockNumber) ([]*GovernanceProposal, error) {
+	header, statedb, err := api.GetHeaderAndState(number)
+	if err != nil {
+		return nil, err
+	}
+	props, err := api.dpos.passedProposals(api.chain, header, statedb)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]*GovernanceProposal, 0, len(props))
+	for _, prop := range props {
+		pending = append(pending, newGovernanceProposal(prop))
+	}
+	return pending, nil
+}
+
+// PreviewNextEpoch runs the election of the next epoch against a copy of the latest
+// state and returns the predicted validators, the validators kicked out, the rewards
+// distributed at the transition and the governance proposals to be executed.
+func (api *API) PreviewNextEpoch() (*EpochPreview, error) {
+	header, statedb, err := api.GetHeaderAndState(nil)
+	if err != nil {
+		return nil, err
+	}
+	return api.dpos.PreviewNextEpoch(api.chain, header, statedb)
+}
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
@@ -908,3 +1170,87 @@ func (api *API) Status() (*status, error) {
 		NumBlocks:     numBlocks,
 	}, nil
 }
+
+// ValidatorSetChanges creates a subscription that is triggered each time a checkpoint
+// block changes the validator set.
+func (api *API) ValidatorSetChanges(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		changes := make(chan *ValidatorSetChangeEvent, 16)
+		sub := api.dpos.SubscribeValidatorSetChanges(changes)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-changes:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// Proposals creates a subscription that is triggered each time a passed governance
+// proposal is executed.
+func (api *API) Proposals(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		proposals := make(chan *ProposalEvent, 16)
+		sub := api.dpos.SubscribeProposals(proposals)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-proposals:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// Punishments creates a subscription that is triggered each time a validator is
+// punished for missing its block, kicked out, or slashed for double signing.
+func (api *API) Punishments(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		punishments := make(chan *PunishmentEvent, 16)
+		sub := api.dpos.SubscribePunishments(punishments)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-punishments:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
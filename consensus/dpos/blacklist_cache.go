@@ -0,0 +1,184 @@
+package dpos
+
+import (
+	"math/big"
+	"reflect"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+// Topics of the events of the address list contract updating the blacklist and the
+// event check rules.
+var (
+	blackAddrAddedTopic   = crypto.Keccak256Hash([]byte("BlackAddrAdded(address,uint8)"))
+	blackAddrRemovedTopic = crypto.Keccak256Hash([]byte("BlackAddrRemoved(address,uint8)"))
+	ruleAddedTopic        = crypto.Keccak256Hash([]byte("RuleAdded(bytes32,uint128,uint8)"))
+	ruleUpdatedTopic      = crypto.Keccak256Hash([]byte("RuleUpdated(bytes32,uint128,uint8)"))
+	ruleRemovedTopic      = crypto.Keccak256Hash([]byte("RuleRemoved(bytes32,uint128,uint8)"))
+)
+
+// blacklistSnapshot is the blacklist in effect for a block, along with the entries
+// of the address list contracts it's incrementally updated from.
+type blacklistSnapshot struct {
+	directions map[common.Address]blacklistDirection // Entries of the address list contract
+	v2         blacklist                             // Entries of the address list v2 contract
+	merged     blacklist                             // All the entries, the ones of the address list contract first
+}
+
+// newBlacklistSnapshot creates a blacklist snapshot out of the entries of the address
+// list contracts.
+func newBlacklistSnapshot(directions map[common.Address]blacklistDirection, v2 blacklist) *blacklistSnapshot {
+	merged := make(blacklist, len(directions)+len(v2))
+	for addr, direction := range directions {
+		merged[addr] = []BlacklistEntry{{Direction: direction}}
+	}
+	for addr, entries := range v2 {
+		merged[addr] = append(merged[addr], entries...)
+	}
+	return &blacklistSnapshot{directions: directions, v2: v2, merged: merged}
+}
+
+// equal reports whether the two snapshots hold the same entries.
+func (s *blacklistSnapshot) equal(other *blacklistSnapshot) bool {
+	return reflect.DeepEqual(s.directions, other.directions) && reflect.DeepEqual(s.v2, other.v2)
+}
+
+// parentReceipts retrieves the receipts of the parent of the header, nil if they
+// are not available.
+func (d *Dpos) parentReceipts(header *types.Header) types.Receipts {
+	return rawdb.ReadRawReceipts(d.db, header.ParentHash, header.Number.Uint64()-1)
+}
+
+// updateBlacklist applies the updates of the parent block of the header to the
+// blacklist of the parent. The entries of the address list contract are updated
+// from the events of the parent block, the ones of the v2 contract are read again
+// from the parent state. It returns nil if the receipts of the parent are missing.
+func (d *Dpos) updateBlacklist(prev *blacklistSnapshot, header *types.Header, parentState *state.StateDB) *blacklistSnapshot {
+	number := header.Number.Uint64() - 1
+
+	updated := lastBlacklistUpdatedNumber(parentState) == number
+	updatedV2 := d.config.IsBlacklistV2(header.Number) && lastBlacklistV2UpdatedNumber(parentState) == number
+	if !updated && !updatedV2 {
+		return prev
+	}
+	directions, v2 := prev.directions, prev.v2
+	if updated {
+		receipts := d.parentReceipts(header)
+		if receipts == nil {
+			return nil
+		}
+		directions = make(map[common.Address]blacklistDirection, len(prev.directions))
+		for addr, direction := range prev.directions {
+			directions[addr] = direction
+		}
+		applyBlacklistEvents(directions, receipts)
+	}
+	if updatedV2 {
+		v2 = make(blacklist)
+		readBlacklistV2(parentState, v2)
+	}
+	blacklistIncrementalMeter.Mark(1)
+	return newBlacklistSnapshot(directions, v2)
+}
+
+// applyBlacklistEvents updates the directions of the blacklisted addresses with the
+// BlackAddrAdded and BlackAddrRemoved events of the address list contract. The
+// contract keeps a from and a to list, blacklisting in both directions adds to both,
+// while removing from both emits an event for each.
+func applyBlacklistEvents(directions map[common.Address]blacklistDirection, receipts types.Receipts) {
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if l.Address != systemcontract.AddressListContractAddr || len(l.Topics) != 2 || len(l.Data) != common.HashLength {
+				continue
+			}
+			addr := common.BytesToAddress(l.Topics[1].Bytes())
+			direction := blacklistDirection(new(big.Int).SetBytes(l.Data).Uint64())
+
+			prev, exist := directions[addr]
+			switch l.Topics[0] {
+			case blackAddrAddedTopic:
+				if exist && prev != direction {
+					direction = DirectionBoth
+				}
+				directions[addr] = direction
+
+			case blackAddrRemovedTopic:
+				switch {
+				case !exist:
+					log.Warn("Removed address not blacklisted", "addr", addr, "direction", direction)
+				case prev == direction || direction == DirectionBoth:
+					delete(directions, addr)
+				case prev == DirectionBoth && direction == DirectionFrom:
+					directions[addr] = DirectionTo
+				case prev == DirectionBoth && direction == DirectionTo:
+					directions[addr] = DirectionFrom
+				}
+			}
+		}
+	}
+}
+
+// updateEventCheckRules applies the updates of the parent block of the header to
+// the event check rules of the parent, from the events of the parent block. It
+// returns nil if the receipts of the parent are missing.
+func (d *Dpos) updateEventCheckRules(prev map[common.Hash]*EventCheckRule, header *types.Header, parentState *state.StateDB) map[common.Hash]*EventCheckRule {
+	if lastRulesUpdatedNumber(parentState) != header.Number.Uint64()-1 {
+		return prev
+	}
+	receipts := d.parentReceipts(header)
+	if receipts == nil {
+		return nil
+	}
+	rules := make(map[common.Hash]*EventCheckRule, len(prev))
+	for sig, rule := range prev {
+		rules[sig] = rule
+	}
+	applyEventCheckRuleEvents(rules, receipts)
+	rulesIncrementalMeter.Mark(1)
+	return rules
+}
+
+// applyEventCheckRuleEvents updates the rules with the RuleAdded, RuleUpdated and
+// RuleRemoved events of the address list contract. The updated rules are copied,
+// the given ones being shared with the rules of the previous blocks.
+func applyEventCheckRuleEvents(rules map[common.Hash]*EventCheckRule, receipts types.Receipts) {
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if l.Address != systemcontract.AddressListContractAddr || len(l.Topics) != 2 || len(l.Data) != 2*common.HashLength {
+				continue
+			}
+			var (
+				sig       = l.Topics[1]
+				idx       = int(new(big.Int).SetBytes(l.Data[:common.HashLength]).Uint64())
+				checkType = common.AddressCheckType(new(big.Int).SetBytes(l.Data[common.HashLength:]).Uint64())
+			)
+			switch l.Topics[0] {
+			case ruleAddedTopic, ruleUpdatedTopic, ruleRemovedTopic:
+			default:
+				continue
+			}
+			rule := &EventCheckRule{EventSig: sig, Checks: make(map[int]common.AddressCheckType)}
+			if prev, exist := rules[sig]; exist {
+				for i, ct := range prev.Checks {
+					rule.Checks[i] = ct
+				}
+			}
+			if l.Topics[0] == ruleRemovedTopic {
+				delete(rule.Checks, idx)
+			} else {
+				rule.Checks[idx] = checkType
+			}
+			if len(rule.Checks) == 0 {
+				delete(rules, sig)
+			} else {
+				rules[sig] = rule
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package dpos
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/types"
+)
+
+func TestBlacklistEvents(t *testing.T) {
+	var (
+		contract = systemcontract.AddressListContractAddr
+		from, to = common.Address{0x01}, common.Address{0x02}
+		sig      = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	)
+	black := func(topic common.Hash, addr common.Address, direction blacklistDirection) *types.Log {
+		return &types.Log{Address: contract, Topics: []common.Hash{topic, addr.Hash()}, Data: common.BigToHash(big.NewInt(int64(direction))).Bytes()}
+	}
+	rule := func(topic common.Hash, idx int64, checkType common.AddressCheckType) *types.Log {
+		data := append(common.BigToHash(big.NewInt(idx)).Bytes(), common.BigToHash(big.NewInt(int64(checkType))).Bytes()...)
+		return &types.Log{Address: contract, Topics: []common.Hash{topic, sig}, Data: data}
+	}
+	// Blacklisting in both directions is undone by one removal per direction
+	directions := map[common.Address]blacklistDirection{from: DirectionFrom}
+	applyBlacklistEvents(directions, types.Receipts{{Logs: []*types.Log{
+		black(blackAddrAddedTopic, from, DirectionTo),
+		black(blackAddrAddedTopic, to, DirectionBoth),
+		black(blackAddrRemovedTopic, to, DirectionFrom),
+	}}})
+	if want := map[common.Address]blacklistDirection{from: DirectionBoth, to: DirectionTo}; !reflect.DeepEqual(directions, want) {
+		t.Fatalf("directions mismatch: have %v, want %v", directions, want)
+	}
+	applyBlacklistEvents(directions, types.Receipts{{Logs: []*types.Log{black(blackAddrRemovedTopic, to, DirectionTo)}}})
+	if _, exist := directions[to]; exist {
+		t.Fatalf("removed address still blacklisted")
+	}
+	// The updated rules are copied, leaving the ones of the parent untouched
+	prev := &EventCheckRule{EventSig: sig, Checks: map[int]common.AddressCheckType{1: common.CheckFrom}}
+	rules := map[common.Hash]*EventCheckRule{sig: prev}
+	applyEventCheckRuleEvents(rules, types.Receipts{{Logs: []*types.Log{
+		rule(ruleAddedTopic, 2, common.CheckTo),
+		rule(ruleUpdatedTopic, 1, common.CheckBothInAny),
+	}}})
+	if want := map[int]common.AddressCheckType{1: common.CheckBothInAny, 2: common.CheckTo}; !reflect.DeepEqual(rules[sig].Checks, want) {
+		t.Fatalf("checks mismatch: have %v, want %v", rules[sig].Checks, want)
+	}
+	if len(prev.Checks) != 1 || prev.Checks[1] != common.CheckFrom {
+		t.Fatalf("parent rule modified: %v", prev.Checks)
+	}
+	applyEventCheckRuleEvents(rules, types.Receipts{{Logs: []*types.Log{
+		rule(ruleRemovedTopic, 1, common.CheckBothInAny),
+		rule(ruleRemovedTopic, 2, common.CheckTo),
+	}}})
+	if len(rules) != 0 {
+		t.Fatalf("rule without checks kept: %v", rules)
+	}
+}
@@ -0,0 +1,266 @@
+package dpos
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+// Storage layout of the address list v2 contract.
+var (
+	blacklistV2LenSlot         = common.BigToHash(common.Big0) // Number of entries, listed from keccak(slot 1) four slots each
+	blacklistV2EntriesSlot     = common.BigToHash(common.Big1) // Entries as address, scope, direction and expiry
+	blacklistV2IndexSlot       = common.BigToHash(common.Big2) // Address and scope to entry index+1
+	blacklistV2LastUpdatedSlot = common.BigToHash(common.Big3) // Last block the entries were updated at
+)
+
+// blacklistV2EntrySize is the number of storage slots of an entry.
+const blacklistV2EntrySize = 4
+
+var (
+	// errBlacklistAdmin is returned if a blacklist entry is requested by another
+	// account than the address list admin.
+	errBlacklistAdmin = errors.New("blacklist entry not requested by the admin")
+
+	// errBlacklistAdminEntry is returned if the admin itself is requested to be
+	// blacklisted.
+	errBlacklistAdminEntry = errors.New("cannot add admin to blacklist")
+
+	// errInvalidBlacklistEntry is returned if a blacklist entry is requested with an
+	// unknown direction or an expiry out of range.
+	errInvalidBlacklistEntry = errors.New("invalid blacklist entry")
+
+	// errBlacklistEntryExpired is returned if a blacklist entry is requested which
+	// expires before applying to any block.
+	errBlacklistEntryExpired = errors.New("blacklist entry already expired")
+
+	// errUnknownBlacklistEntry is returned if the removal of a blacklist entry that
+	// doesn't exist is requested.
+	errUnknownBlacklistEntry = errors.New("unknown blacklist entry")
+)
+
+var (
+	// blacklistEntryRequestedTopic is the topic of the BlacklistEntryRequested(admin, addr, scope, direction, expiry) event.
+	blacklistEntryRequestedTopic = crypto.Keccak256Hash([]byte("BlacklistEntryRequested(address,address,address,uint8,uint64)"))
+
+	// blacklistEntryRemovalRequestedTopic is the topic of the BlacklistEntryRemovalRequested(admin, addr, scope) event.
+	blacklistEntryRemovalRequestedTopic = crypto.Keccak256Hash([]byte("BlacklistEntryRemovalRequested(address,address,address)"))
+)
+
+// addressListV2Code is the runtime code of the address list v2 contract. Its
+// requests are only logged, the engine checks they are sent by the admin of the
+// address list contract and maintains the entries read by the getters:
+//
+//	function setBlacklistEntry(address addr, uint8 direction, uint64 expiry, address scope) external; // emits BlacklistEntryRequested
+//	function removeBlacklistEntry(address addr, address scope) external;                            // emits BlacklistEntryRemovalRequested
+//	function entriesLength() external view returns (uint256);
+//	function entryAt(uint256 i) external view returns (address addr, address scope, uint8 direction, uint64 expiry);
+//	function lastUpdatedNumber() external view returns (uint256);
+var addressListV2Code = assembleContract(
+	contractMethod{"setBlacklistEntry(address,uint8,uint64,address)", func(c *contractCode) {
+		// mstore(0, direction); mstore(32, expiry); LOG4(0, 64, topic, caller, addr, scope)
+		c.nonPayable()
+		c.arg(1).push(0).op(vm.MSTORE).arg(2).push(0x20).op(vm.MSTORE)
+		c.addressArg(3).addressArg(0).op(vm.CALLER).pushHash(blacklistEntryRequestedTopic)
+		c.push(0x40).push(0).op(vm.LOG4, vm.STOP)
+	}},
+	contractMethod{"removeBlacklistEntry(address,address)", func(c *contractCode) {
+		// LOG4(0, 0, topic, caller, addr, scope)
+		c.nonPayable()
+		c.addressArg(1).addressArg(0).op(vm.CALLER).pushHash(blacklistEntryRemovalRequestedTopic)
+		c.push(0).op(vm.DUP1, vm.LOG4, vm.STOP)
+	}},
+	contractMethod{"entriesLength()", func(c *contractCode) {
+		c.pushHash(blacklistV2LenSlot).op(vm.SLOAD).returnWord()
+	}},
+	contractMethod{"entryAt(uint256)", func(c *contractCode) {
+		// require(i < length); slot := keccak(1) + i*4; return the four slots
+		c.arg(0).op(vm.DUP1).pushHash(blacklistV2LenSlot).op(vm.SLOAD, vm.GT, vm.ISZERO).revertIf()
+		c.push(2).op(vm.SHL).pushHash(blacklistV2EntrySlot(0, 0)).op(vm.ADD)
+		for field := uint64(0); field < blacklistV2EntrySize; field++ {
+			c.op(vm.DUP1).push(field).op(vm.ADD, vm.SLOAD).push(field * 0x20).op(vm.MSTORE)
+		}
+		c.push(blacklistV2EntrySize * 0x20).push(0).op(vm.RETURN)
+	}},
+	contractMethod{"lastUpdatedNumber()", func(c *contractCode) {
+		c.pushHash(blacklistV2LastUpdatedSlot).op(vm.SLOAD).returnWord()
+	}},
+)
+
+// blacklistV2EntrySlot returns the storage slot of the given field of the i-th entry.
+func blacklistV2EntrySlot(i uint64, field uint64) common.Hash {
+	base := crypto.Keccak256Hash(blacklistV2EntriesSlot.Bytes()).Big()
+	return common.BigToHash(base.Add(base, new(big.Int).SetUint64(i*blacklistV2EntrySize+field)))
+}
+
+// blacklistV2IndexKey returns the storage slot of the index of the entry of the
+// address and scope.
+func blacklistV2IndexKey(addr, scope common.Address) common.Hash {
+	return crypto.Keccak256Hash(addr.Hash().Bytes(), scope.Hash().Bytes(), blacklistV2IndexSlot.Bytes())
+}
+
+// addressListAdmin returns the admin of the address list contract, packed after the
+// initialized and devVerifyEnabled flags in the first slot.
+func addressListAdmin(state consensus.StateReader) common.Address {
+	value := state.GetState(systemcontract.AddressListContractAddr, common.Hash{})
+	return common.BytesToAddress(value[common.HashLength-2-common.AddressLength : common.HashLength-2])
+}
+
+// lastBlacklistV2UpdatedNumber returns the last block the entries of the address
+// list v2 contract were updated at.
+func lastBlacklistV2UpdatedNumber(state consensus.StateReader) uint64 {
+	return state.GetState(systemcontract.AddressListV2ContractAddr, blacklistV2LastUpdatedSlot).Big().Uint64()
+}
+
+// readBlacklistV2 adds the entries of the address list v2 contract to the blacklist.
+func readBlacklistV2(state consensus.StateReader, bl blacklist) {
+	contract := systemcontract.AddressListV2ContractAddr
+
+	count := state.GetState(contract, blacklistV2LenSlot).Big().Uint64()
+	for i := uint64(0); i < count; i++ {
+		addr := common.BytesToAddress(state.GetState(contract, blacklistV2EntrySlot(i, 0)).Bytes())
+		bl[addr] = append(bl[addr], BlacklistEntry{
+			Direction: blacklistDirection(state.GetState(contract, blacklistV2EntrySlot(i, 2)).Big().Uint64()),
+			Expiry:    state.GetState(contract, blacklistV2EntrySlot(i, 3)).Big().Uint64(),
+			Scope:     common.BytesToAddress(state.GetState(contract, blacklistV2EntrySlot(i, 1)).Bytes()),
+		})
+	}
+}
+
+// writeBlacklistV2Entry sets the entry of the address and scope, replacing the
+// existing one if any.
+func writeBlacklistV2Entry(state *state.StateDB, addr common.Address, entry BlacklistEntry) {
+	contract := systemcontract.AddressListV2ContractAddr
+
+	key := blacklistV2IndexKey(addr, entry.Scope)
+	i := state.GetState(contract, key).Big().Uint64()
+	if i == 0 {
+		count := state.GetState(contract, blacklistV2LenSlot).Big().Uint64()
+		state.SetState(contract, blacklistV2LenSlot, common.BigToHash(new(big.Int).SetUint64(count+1)))
+		state.SetState(contract, key, common.BigToHash(new(big.Int).SetUint64(count+1)))
+		i = count + 1
+	}
+	state.SetState(contract, blacklistV2EntrySlot(i-1, 0), addr.Hash())
+	state.SetState(contract, blacklistV2EntrySlot(i-1, 1), entry.Scope.Hash())
+	state.SetState(contract, blacklistV2EntrySlot(i-1, 2), common.BigToHash(new(big.Int).SetUint64(uint64(entry.Direction))))
+	state.SetState(contract, blacklistV2EntrySlot(i-1, 3), common.BigToHash(new(big.Int).SetUint64(entry.Expiry)))
+}
+
+// removeBlacklistV2Entry deletes the entry of the address and scope, moving the
+// last entry in its place.
+func removeBlacklistV2Entry(state *state.StateDB, addr, scope common.Address) error {
+	contract := systemcontract.AddressListV2ContractAddr
+
+	key := blacklistV2IndexKey(addr, scope)
+	i := state.GetState(contract, key).Big().Uint64()
+	if i == 0 {
+		return errUnknownBlacklistEntry
+	}
+	last := state.GetState(contract, blacklistV2LenSlot).Big().Uint64() - 1
+	if i-1 != last {
+		for field := uint64(0); field < blacklistV2EntrySize; field++ {
+			state.SetState(contract, blacklistV2EntrySlot(i-1, field), state.GetState(contract, blacklistV2EntrySlot(last, field)))
+		}
+		moved := common.BytesToAddress(state.GetState(contract, blacklistV2EntrySlot(last, 0)).Bytes())
+		movedScope := common.BytesToAddress(state.GetState(contract, blacklistV2EntrySlot(last, 1)).Bytes())
+		state.SetState(contract, blacklistV2IndexKey(moved, movedScope), common.BigToHash(new(big.Int).SetUint64(i)))
+	}
+	for field := uint64(0); field < blacklistV2EntrySize; field++ {
+		state.SetState(contract, blacklistV2EntrySlot(last, field), common.Hash{})
+	}
+	state.SetState(contract, key, common.Hash{})
+	state.SetState(contract, blacklistV2LenSlot, common.BigToHash(new(big.Int).SetUint64(last)))
+	return nil
+}
+
+// decodeBlacklistEntryRequest decodes the direction and expiry logged along with a
+// blacklist entry request, checking the entry applies to a block after the given one.
+func decodeBlacklistEntryRequest(data []byte, scope common.Address, number uint64) (BlacklistEntry, error) {
+	if len(data) != 2*common.HashLength {
+		return BlacklistEntry{}, errInvalidBlacklistEntry
+	}
+	direction, expiry := new(big.Int).SetBytes(data[:common.HashLength]), new(big.Int).SetBytes(data[common.HashLength:])
+	if direction.Cmp(big.NewInt(int64(DirectionBoth))) > 0 || !expiry.IsUint64() {
+		return BlacklistEntry{}, errInvalidBlacklistEntry
+	}
+	entry := BlacklistEntry{Direction: blacklistDirection(direction.Uint64()), Expiry: expiry.Uint64(), Scope: scope}
+	if entry.Expiry != 0 && entry.Expiry <= number+1 {
+		return BlacklistEntry{}, errBlacklistEntryExpired
+	}
+	return entry, nil
+}
+
+// applyBlacklistV2 maintains the address list v2 contract: it's installed at the
+// fork block, and the entries requested or removed by the admin of the address
+// list contract in the transactions of the block are recorded. Code replacing it
+// is left in place and its logs ignored. The entries take effect from the next
+// block on, like the ones of the address list contract.
+func (d *Dpos) applyBlacklistV2(header *types.Header, state *state.StateDB, receipts []*types.Receipt) {
+	if !d.config.IsBlacklistV2(header.Number) {
+		return
+	}
+	number := header.Number.Uint64()
+	contract := systemcontract.AddressListV2ContractAddr
+
+	if state.GetCodeSize(contract) == 0 {
+		state.SetCode(contract, addressListV2Code)
+	}
+	if state.GetCodeHash(contract) != crypto.Keccak256Hash(addressListV2Code) {
+		return
+	}
+	admin := addressListAdmin(state)
+
+	updated := false
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if l.Address != contract || len(l.Topics) != 4 {
+				continue
+			}
+			requester := common.BytesToAddress(l.Topics[1].Bytes())
+			addr, scope := common.BytesToAddress(l.Topics[2].Bytes()), common.BytesToAddress(l.Topics[3].Bytes())
+
+			switch l.Topics[0] {
+			case blacklistEntryRequestedTopic:
+				entry, err := decodeBlacklistEntryRequest(l.Data, scope, number)
+				if err == nil && requester != admin {
+					err = errBlacklistAdmin
+				}
+				if err == nil && addr == admin {
+					err = errBlacklistAdminEntry
+				}
+				if err != nil {
+					log.Debug("Rejected blacklist entry", "addr", addr, "scope", scope, "requester", requester, "err", err)
+					continue
+				}
+				writeBlacklistV2Entry(state, addr, entry)
+				log.Debug("Blacklisted address", "addr", addr, "direction", entry.Direction, "expiry", entry.Expiry, "scope", scope)
+
+			case blacklistEntryRemovalRequestedTopic:
+				err := errBlacklistAdmin
+				if requester == admin {
+					err = removeBlacklistV2Entry(state, addr, scope)
+				}
+				if err != nil {
+					log.Debug("Rejected blacklist entry removal", "addr", addr, "scope", scope, "requester", requester, "err", err)
+					continue
+				}
+				log.Debug("Removed blacklist entry", "addr", addr, "scope", scope)
+
+			default:
+				continue
+			}
+			updated = true
+		}
+	}
+	if updated {
+		state.SetState(contract, blacklistV2LastUpdatedSlot, common.BigToHash(header.Number))
+	}
+}
@@ -0,0 +1,101 @@
+package dpos
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm/runtime"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestBlacklistV2(t *testing.T) {
+	statedb := newTestState()
+	var (
+		admin = common.HexToAddress("0xac887b0c4277cdceb741a93c6e8516acba880018")
+		user  = common.Address{0x01}
+		black = common.Address{0x02}
+		token = common.Address{0x0a}
+		sig   = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	)
+	// The admin of the address list contract is packed after two flags
+	statedb.SetState(systemcontract.AddressListContractAddr, common.Hash{}, common.BytesToHash(append(admin.Bytes(), 0x01, 0x01)))
+	if have := addressListAdmin(statedb); have != admin {
+		t.Fatalf("admin mismatch: have %x, want %x", have, admin)
+	}
+	engine := &Dpos{config: &params.DposConfig{Epoch: 10, BlacklistV2Block: big.NewInt(5)}}
+	engine.applyBlacklistV2(&types.Header{Number: big.NewInt(5)}, statedb, nil)
+
+	// Log the requests through the contract, only the ones of the admin are applied
+	abi := systemcontract.GetInteractiveABI()[systemcontract.AddressListV2ContractName]
+	request := func(from common.Address, method string, args ...interface{}) {
+		callContract(t, &runtime.Config{State: statedb, Origin: from}, systemcontract.AddressListV2ContractName, systemcontract.AddressListV2ContractAddr, method, args...)
+	}
+	request(admin, "setBlacklistEntry", black, uint8(DirectionFrom), uint64(20), common.Address{})
+	request(admin, "setBlacklistEntry", black, uint8(DirectionBoth), uint64(0), token)
+	request(admin, "setBlacklistEntry", user, uint8(DirectionTo), uint64(6), common.Address{}) // expired already
+	request(user, "setBlacklistEntry", user, uint8(DirectionBoth), uint64(0), common.Address{})
+	engine.applyBlacklistV2(&types.Header{Number: big.NewInt(5)}, statedb, []*types.Receipt{{Logs: statedb.Logs()}})
+
+	bl := make(blacklist)
+	readBlacklistV2(statedb, bl)
+	if len(bl) != 1 || len(bl[black]) != 2 {
+		t.Fatalf("blacklist mismatch: have %v", bl)
+	}
+	ret := callContract(t, &runtime.Config{State: statedb}, systemcontract.AddressListV2ContractName, systemcontract.AddressListV2ContractAddr, "entryAt", big.NewInt(1))
+	if entry, _ := abi.Unpack("entryAt", ret); entry[0].(common.Address) != black || entry[1].(common.Address) != token || entry[2].(uint8) != uint8(DirectionBoth) {
+		t.Fatalf("entry mismatch: have %v", entry)
+	}
+	// The expiring entry applies to all the checks until its expiry, the scoped one
+	// to the events of the token only
+	transfer := &types.Log{Address: token, Topics: []common.Hash{sig, user.Hash(), black.Hash()}}
+	rules := map[common.Hash]*EventCheckRule{sig: {EventSig: sig, Checks: map[int]common.AddressCheckType{1: common.CheckFrom, 2: common.CheckTo}}}
+
+	validator := &blacklistValidator{blacks: bl, rules: rules, number: 19}
+	if err := validator.CheckAddress(black, common.CheckFrom); !errors.Is(err, types.ErrAddressDenied) || err.(*types.AddressDeniedError).Expiry != 20 {
+		t.Fatalf("expiring entry error mismatch: have %v", err)
+	}
+	if err := validator.CheckAddress(black, common.CheckTo); err != nil {
+		t.Fatalf("recipient denied out of the scope: %v", err)
+	}
+	if err := validator.CheckLog(transfer); !errors.Is(err, types.ErrAddressDenied) || *err.(*types.AddressDeniedError).Scope != token {
+		t.Fatalf("scoped entry error mismatch: have %v", err)
+	}
+	validator.number = 20
+	if err := validator.CheckAddress(black, common.CheckFrom); err != nil {
+		t.Fatalf("expired entry denied: %v", err)
+	}
+	if err := validator.CheckLog(&types.Log{Address: common.Address{0x0b}, Topics: transfer.Topics}); err != nil {
+		t.Fatalf("log of another contract denied: %v", err)
+	}
+	// Removing an entry moves the last one in its place
+	statedb.Prepare(common.Hash{0x01}, 0)
+	request(admin, "removeBlacklistEntry", black, common.Address{})
+	engine.applyBlacklistV2(&types.Header{Number: big.NewInt(6)}, statedb, []*types.Receipt{{Logs: statedb.GetLogs(common.Hash{0x01}, common.Hash{})}})
+
+	bl = make(blacklist)
+	readBlacklistV2(statedb, bl)
+	if len(bl[black]) != 1 || bl[black][0].Scope != token {
+		t.Fatalf("blacklist after removal mismatch: have %v", bl)
+	}
+	if have := lastBlacklistV2UpdatedNumber(statedb); have != 6 {
+		t.Fatalf("last updated number mismatch: have %d, want 6", have)
+	}
+	// The upgraded contract is kept, and records the requests itself
+	upgraded := []byte{0x00}
+	statedb.SetCode(systemcontract.AddressListV2ContractAddr, upgraded)
+	engine.applyBlacklistV2(&types.Header{Number: big.NewInt(7)}, statedb, []*types.Receipt{{Logs: []*types.Log{{
+		Address: systemcontract.AddressListV2ContractAddr,
+		Topics:  []common.Hash{blacklistEntryRemovalRequestedTopic, admin.Hash(), black.Hash(), token.Hash()},
+	}}}})
+	if code := statedb.GetCode(systemcontract.AddressListV2ContractAddr); !bytes.Equal(code, upgraded) {
+		t.Fatalf("upgraded contract replaced: %x", code)
+	}
+	if have := lastBlacklistV2UpdatedNumber(statedb); have != 6 {
+		t.Fatalf("logs of the upgraded contract recorded at %d", have)
+	}
+}
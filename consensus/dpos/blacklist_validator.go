@@ -1,24 +1,62 @@
 package dpos
 
 import (
+	"sort"
+
 	"github.com/DxChainNetwork/dxc/common"
 	"github.com/DxChainNetwork/dxc/core/types"
 	"github.com/DxChainNetwork/dxc/log"
 )
 
 type EventCheckRule struct {
-	EventSig common.Hash
-	Checks   map[int]common.AddressCheckType
+	EventSig common.Hash                     `json:"eventSig"`
+	Checks   map[int]common.AddressCheckType `json:"checks"` // Address check types keyed by topic index
+}
+
+// BlacklistEntry is an address blacklisted in a direction. Since the blacklist v2
+// fork, an entry may expire at a block height, and may be scoped to the events of
+// a single contract, such as the transfers of a token.
+type BlacklistEntry struct {
+	Direction blacklistDirection `json:"direction"`
+	Expiry    uint64             `json:"expiry,omitempty"` // First block the entry doesn't apply to, zero if permanent
+	Scope     common.Address     `json:"scope"`            // Contract whose events are checked only, zero for all the checks
+}
+
+// active reports whether the entry applies to the given block.
+func (e *BlacklistEntry) active(number uint64) bool {
+	return e.Expiry == 0 || number < e.Expiry
 }
 
+// blacklist is the blacklisted addresses along with their entries.
+type blacklist map[common.Address][]BlacklistEntry
+
 type blacklistValidator struct {
-	blacks map[common.Address]blacklistDirection
+	blacks blacklist
 	rules  map[common.Hash]*EventCheckRule
+	number uint64 // Block validated, which the expiring entries are checked against
+}
+
+// CheckAddress returns an *AddressDeniedError if the address is blacklisted in the
+// checked direction.
+func (b *blacklistValidator) CheckAddress(address common.Address, cType common.AddressCheckType) error {
+	return b.check(address, cType, common.Address{})
 }
 
-func (b *blacklistValidator) IsAddressDenied(address common.Address, cType common.AddressCheckType) (hit bool) {
-	d, exist := b.blacks[address]
-	if exist {
+// check returns an *AddressDeniedError if the address is blacklisted in the checked
+// direction, by an entry for all the checks or scoped to the given emitter of a log.
+func (b *blacklistValidator) check(address common.Address, cType common.AddressCheckType, emitter common.Address) error {
+	entries, exist := b.blacks[address]
+	if !exist {
+		return nil
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.active(b.number) || (entry.Scope != (common.Address{}) && entry.Scope != emitter) {
+			continue
+		}
+		d := entry.Direction
+
+		var hit bool
 		switch cType {
 		case common.CheckFrom:
 			hit = d != DirectionTo // equals to : d == DirectionFrom || d == DirectionBoth
@@ -29,31 +67,49 @@ func (b *blacklistValidator) IsAddressDenied(address common.Address, cType commo
 		default:
 			log.Warn("blacklist, unsupported AddressCheckType", "type", cType)
 			// Unsupported value, not denied by default
-			hit = false
+			return nil
 		}
+		if !hit {
+			continue
+		}
+		log.Trace("Hit blacklist", "addr", address.String(), "direction", d, "checkType", cType, "expiry", entry.Expiry, "scope", entry.Scope)
+		denied := &types.AddressDeniedError{Address: address, Direction: d.String(), Check: cType.String(), Expiry: entry.Expiry}
+		if entry.Scope != (common.Address{}) {
+			scope := entry.Scope
+			denied.Scope = &scope
+		}
+		return denied
 	}
-	if hit {
-		log.Trace("Hit blacklist", "addr", address.String(), "direction", d, "checkType", cType)
-	}
-	return
+	return nil
 }
 
-func (b *blacklistValidator) IsLogDenied(evLog *types.Log) bool {
+// CheckLog returns an *AddressDeniedError if an address checked by the event rule
+// of the log is blacklisted.
+func (b *blacklistValidator) CheckLog(evLog *types.Log) error {
 	if nil == evLog || len(evLog.Topics) <= 1 {
-		return false
+		return nil
 	}
 	if rule, exist := b.rules[evLog.Topics[0]]; exist {
-		for idx, checkType := range rule.Checks {
+		// walk the checks in topic order, so that the same address is always reported
+		idxs := make([]int, 0, len(rule.Checks))
+		for idx := range rule.Checks {
+			idxs = append(idxs, idx)
+		}
+		sort.Ints(idxs)
+		for _, idx := range idxs {
+			checkType := rule.Checks[idx]
 			// do a basic check
 			if idx >= len(evLog.Topics) {
 				log.Error("check index in rule out to range", "sig", rule.EventSig.String(), "checkIdx", idx, "topicsLen", len(evLog.Topics))
 				continue
 			}
 			addr := common.BytesToAddress(evLog.Topics[idx].Bytes())
-			if b.IsAddressDenied(addr, checkType) {
-				return true
+			if err := b.check(addr, checkType, evLog.Address); err != nil {
+				denied := err.(*types.AddressDeniedError)
+				denied.EventSig, denied.CheckIndex = &rule.EventSig, idx
+				return denied
 			}
 		}
 	}
-	return false
+	return nil
 }
@@ -0,0 +1,44 @@
+package dpos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/types"
+)
+
+func TestBlacklistDenialDetails(t *testing.T) {
+	var (
+		black = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		other = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		sig   = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	)
+	validator := &blacklistValidator{
+		blacks: blacklist{black: {{Direction: DirectionTo}}},
+		rules: map[common.Hash]*EventCheckRule{
+			sig: {EventSig: sig, Checks: map[int]common.AddressCheckType{1: common.CheckFrom, 2: common.CheckTo}},
+		},
+	}
+	if err := validator.CheckAddress(black, common.CheckFrom); err != nil {
+		t.Fatalf("sender blacklisted to only denied: %v", err)
+	}
+	err := validator.CheckAddress(black, common.CheckTo)
+	if !errors.Is(err, types.ErrAddressDenied) {
+		t.Fatalf("recipient error mismatch: have %v, want %v", err, types.ErrAddressDenied)
+	}
+	if denied := err.(*types.AddressDeniedError); denied.Address != black || denied.Direction != "to" || denied.Check != "to" || denied.EventSig != nil {
+		t.Fatalf("recipient denial mismatch: %+v", denied)
+	}
+	// The blacklisted address is only checked as the recipient of the event
+	if err := validator.CheckLog(&types.Log{Topics: []common.Hash{sig, black.Hash(), other.Hash()}}); err != nil {
+		t.Fatalf("log from blacklisted to only address denied: %v", err)
+	}
+	err = validator.CheckLog(&types.Log{Topics: []common.Hash{sig, other.Hash(), black.Hash()}})
+	if !errors.Is(err, types.ErrAddressDenied) {
+		t.Fatalf("log error mismatch: have %v, want %v", err, types.ErrAddressDenied)
+	}
+	if denied := err.(*types.AddressDeniedError); denied.Address != black || denied.EventSig == nil || *denied.EventSig != sig || denied.CheckIndex != 2 {
+		t.Fatalf("log denial mismatch: %+v", denied)
+	}
+}
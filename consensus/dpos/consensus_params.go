@@ -0,0 +1,72 @@
+package dpos
+
+import (
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+// Storage layout of the consensus params contract.
+var (
+	paramsPeriodSlot    = common.BigToHash(common.Big0) // Seconds between blocks
+	paramsEpochSlot     = common.BigToHash(common.Big1) // Epoch length
+	paramsForkBlockSlot = common.BigToHash(common.Big2) // Block the values took effect at
+)
+
+// consensusParamsCode is the runtime code of the consensus params contract. It
+// implements the view methods of systemcontract.ConsensusParamsABI by reading the
+// slots maintained by the engine, so that the other system contracts can follow
+// the period schedule without hardcoding it. The epoch length is fixed by the
+// genesis, so currentEpoch always agrees with Base.currentEpoch:
+//
+//	function period() external view returns (uint256);
+//	function epoch() external view returns (uint256);
+//	function forkBlock() external view returns (uint256);
+//	function currentEpoch() external view returns (uint256); // block.number / epoch
+var consensusParamsCode = assembleContract(
+	contractMethod{"period()", func(c *contractCode) {
+		c.pushHash(paramsPeriodSlot).op(vm.SLOAD).returnWord()
+	}},
+	contractMethod{"epoch()", func(c *contractCode) {
+		c.pushHash(paramsEpochSlot).op(vm.SLOAD).returnWord()
+	}},
+	contractMethod{"forkBlock()", func(c *contractCode) {
+		c.pushHash(paramsForkBlockSlot).op(vm.SLOAD).returnWord()
+	}},
+	contractMethod{"currentEpoch()", func(c *contractCode) {
+		c.pushHash(paramsEpochSlot).op(vm.SLOAD, vm.NUMBER, vm.DIV).returnWord()
+	}},
+)
+
+// applyConsensusParams records the period and epoch length taking effect at the
+// header into the consensus params contract. Nothing is done at the blocks with
+// no scheduled change, so chains without any dpos fork are not affected.
+func (d *Dpos) applyConsensusParams(header *types.Header, state *state.StateDB) {
+	number := header.Number.Uint64()
+
+	scheduled := false
+	for _, fork := range d.config.Forks {
+		if fork.Block != nil && fork.Block.Uint64() == number {
+			scheduled = true
+			break
+		}
+	}
+	if !scheduled {
+		return
+	}
+	addr := systemcontract.ConsensusParamsContractAddr
+	if state.GetCodeHash(addr) != crypto.Keccak256Hash(consensusParamsCode) {
+		state.SetCode(addr, consensusParamsCode)
+	}
+	state.SetState(addr, paramsPeriodSlot, common.BigToHash(new(big.Int).SetUint64(d.config.PeriodAt(number))))
+	state.SetState(addr, paramsEpochSlot, common.BigToHash(new(big.Int).SetUint64(d.config.Epoch)))
+	state.SetState(addr, paramsForkBlockSlot, common.BigToHash(header.Number))
+
+	log.Info("Dpos consensus parameters changed", "number", number, "period", d.config.PeriodAt(number), "epoch", d.config.Epoch)
+}
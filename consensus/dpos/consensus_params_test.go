@@ -0,0 +1,39 @@
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm/runtime"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestConsensusParamsCode(t *testing.T) {
+	statedb := newTestState()
+
+	config := &params.DposConfig{Period: 6, Epoch: 100, Forks: []params.DposForkConfig{{Block: big.NewInt(1000), Period: 3}}}
+	engine := &Dpos{config: config}
+	engine.applyConsensusParams(&types.Header{Number: big.NewInt(1000)}, statedb)
+
+	cfg := &runtime.Config{State: statedb, BlockNumber: big.NewInt(1450)}
+	for method, want := range map[string]uint64{
+		"period":       3,
+		"epoch":        100,
+		"forkBlock":    1000,
+		"currentEpoch": 14,
+	} {
+		ret := callContract(t, cfg, systemcontract.ConsensusParamsContractName, systemcontract.ConsensusParamsContractAddr, method)
+		if have := new(big.Int).SetBytes(ret).Uint64(); have != want {
+			t.Errorf("%s: value mismatch: have %d, want %d", method, have, want)
+		}
+	}
+	// The epochs are still counted from genesis, as Base.currentEpoch does
+	if have := config.EpochNumber(1450); have != 1450/config.Epoch {
+		t.Errorf("epoch number mismatch: have %d, want %d", have, 1450/config.Epoch)
+	}
+	if !config.IsCheckpoint(1100) || config.IsCheckpoint(1150) {
+		t.Errorf("checkpoint mismatch after the period change")
+	}
+}
@@ -0,0 +1,157 @@
+package dpos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/crypto"
+)
+
+// revertLabel is the jump destination reverting the call, laid out by the method
+// dispatcher of every contract.
+const revertLabel = "revert"
+
+// contractCode assembles the runtime code of the system contracts whose code is
+// installed by the engine, resolving the jump destinations by label.
+type contractCode struct {
+	code   []byte
+	labels map[string]int // Position of the jump destinations
+	jumps  map[int]string // Position of the pushed jump destinations to patch
+}
+
+// contractMethod is a method of an assembled contract, the body of which runs with
+// the selector on the stack.
+type contractMethod struct {
+	signature string
+	body      func(c *contractCode)
+}
+
+// assembleContract lays out the selector dispatcher of the methods, followed by
+// their bodies. Unknown selectors revert.
+func assembleContract(methods ...contractMethod) []byte {
+	c := &contractCode{labels: make(map[string]int), jumps: make(map[int]string)}
+
+	// selector := calldata[0:4]
+	c.push(0).op(vm.CALLDATALOAD).push(0xe0).op(vm.SHR)
+	for _, method := range methods {
+		c.op(vm.DUP1).pushBytes(crypto.Keccak256([]byte(method.signature))[:4]).op(vm.EQ)
+		c.jumpi(method.signature)
+	}
+	c.label(revertLabel).push(0).op(vm.DUP1, vm.REVERT)
+
+	for _, method := range methods {
+		c.label(method.signature)
+		method.body(c)
+	}
+	return c.bytes()
+}
+
+// op appends the opcodes.
+func (c *contractCode) op(ops ...vm.OpCode) *contractCode {
+	for _, op := range ops {
+		c.code = append(c.code, byte(op))
+	}
+	return c
+}
+
+// pushBytes appends the shortest push of the big endian value.
+func (c *contractCode) pushBytes(value []byte) *contractCode {
+	for len(value) > 1 && value[0] == 0 {
+		value = value[1:]
+	}
+	if len(value) == 0 {
+		value = []byte{0}
+	}
+	if len(value) > common.HashLength {
+		panic(fmt.Sprintf("push of %d bytes", len(value)))
+	}
+	c.op(vm.PUSH1 + vm.OpCode(len(value)-1))
+	c.code = append(c.code, value...)
+	return c
+}
+
+// push appends the shortest push of the value.
+func (c *contractCode) push(value uint64) *contractCode {
+	return c.pushBytes(new(big.Int).SetUint64(value).Bytes())
+}
+
+// pushHash appends the push of the word.
+func (c *contractCode) pushHash(value common.Hash) *contractCode {
+	return c.pushBytes(value.Bytes())
+}
+
+// pushLabel appends the push of the position of the label.
+func (c *contractCode) pushLabel(label string) *contractCode {
+	c.op(vm.PUSH2)
+	c.jumps[len(c.code)] = label
+	c.code = append(c.code, 0, 0)
+	return c
+}
+
+// label appends a jump destination named after the label.
+func (c *contractCode) label(label string) *contractCode {
+	if _, ok := c.labels[label]; ok {
+		panic(fmt.Sprintf("duplicate label %q", label))
+	}
+	c.labels[label] = len(c.code)
+	return c.op(vm.JUMPDEST)
+}
+
+// jump appends a jump to the label.
+func (c *contractCode) jump(label string) *contractCode {
+	return c.pushLabel(label).op(vm.JUMP)
+}
+
+// jumpi appends a jump to the label, taken if the top of the stack is not zero.
+func (c *contractCode) jumpi(label string) *contractCode {
+	return c.pushLabel(label).op(vm.JUMPI)
+}
+
+// revertIf reverts if the top of the stack is not zero.
+func (c *contractCode) revertIf() *contractCode {
+	return c.jumpi(revertLabel)
+}
+
+// nonPayable reverts if the call transfers value.
+func (c *contractCode) nonPayable() *contractCode {
+	return c.op(vm.CALLVALUE).revertIf()
+}
+
+// arg pushes the word argument at the given position.
+func (c *contractCode) arg(i uint64) *contractCode {
+	return c.push(4 + 32*i).op(vm.CALLDATALOAD)
+}
+
+// addressArg pushes the address argument at the given position, reverting if it
+// is not a valid address.
+func (c *contractCode) addressArg(i uint64) *contractCode {
+	c.arg(i).op(vm.DUP1).push(0xa0).op(vm.SHR).revertIf()
+	return c
+}
+
+// mappingSlot replaces the key on top of the stack with the storage slot of its
+// entry in the mapping at the given position.
+func (c *contractCode) mappingSlot(position common.Hash) *contractCode {
+	c.push(0).op(vm.MSTORE).pushHash(position).push(0x20).op(vm.MSTORE)
+	return c.push(0x40).push(0).op(vm.SHA3)
+}
+
+// returnWord returns the word on top of the stack.
+func (c *contractCode) returnWord() *contractCode {
+	return c.push(0).op(vm.MSTORE).push(0x20).push(0).op(vm.RETURN)
+}
+
+// bytes resolves the jump destinations and returns the code.
+func (c *contractCode) bytes() []byte {
+	for pos, label := range c.jumps {
+		dest, ok := c.labels[label]
+		if !ok {
+			panic(fmt.Sprintf("unknown label %q", label))
+		}
+		binary.BigEndian.PutUint16(c.code[pos:], uint16(dest))
+	}
+	return c.code
+}
@@ -0,0 +1,95 @@
+package dpos
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/core/vm/runtime"
+	"github.com/DxChainNetwork/dxc/crypto"
+)
+
+func TestAssembleContract(t *testing.T) {
+	slot := common.BigToHash(big.NewInt(7))
+	code := assembleContract(
+		contractMethod{"get(address)", func(c *contractCode) {
+			c.addressArg(0).mappingSlot(slot).op(vm.SLOAD).returnWord()
+		}},
+		contractMethod{"set(address,uint256)", func(c *contractCode) {
+			// Skips the store if the value is zero
+			c.nonPayable()
+			c.arg(1).op(vm.DUP1, vm.ISZERO).jumpi("done")
+			c.addressArg(0).mappingSlot(slot).op(vm.SSTORE)
+			c.label("done").op(vm.STOP)
+		}},
+	)
+	var (
+		addr  = common.Address{0xc0}
+		owner = common.Address{0x01}
+	)
+	statedb := newTestState()
+	statedb.SetCode(addr, code)
+
+	call := func(value *big.Int, signature string, args ...[]byte) ([]byte, error) {
+		input := crypto.Keccak256([]byte(signature))[:4]
+		for _, arg := range args {
+			input = append(input, common.LeftPadBytes(arg, 32)...)
+		}
+		ret, _, err := runtime.Call(addr, input, &runtime.Config{State: statedb, Value: value})
+		return ret, err
+	}
+	if _, err := call(nil, "set(address,uint256)", owner.Bytes(), []byte{0x2a}); err != nil {
+		t.Fatalf("failed to set value: %v", err)
+	}
+	if _, err := call(nil, "set(address,uint256)", owner.Bytes(), nil); err != nil {
+		t.Fatalf("failed to skip zero value: %v", err)
+	}
+	ret, err := call(nil, "get(address)", owner.Bytes())
+	if err != nil {
+		t.Fatalf("failed to get value: %v", err)
+	}
+	if !bytes.Equal(ret, common.LeftPadBytes([]byte{0x2a}, 32)) {
+		t.Fatalf("value mismatch: have %x, want 2a", ret)
+	}
+	if have := statedb.GetState(addr, signingKeysMappingSlot(owner, slot)); have != common.BigToHash(big.NewInt(0x2a)) {
+		t.Fatalf("storage slot mismatch: have %x", have)
+	}
+	// Unknown selectors, value transfers to non payable methods and dirty addresses revert
+	if _, err := call(nil, "unknown()"); err != vm.ErrExecutionReverted {
+		t.Errorf("unknown selector error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	}
+	statedb.AddBalance(common.Address{}, big.NewInt(1))
+	if _, err := call(big.NewInt(1), "set(address,uint256)", owner.Bytes(), []byte{0x01}); err != vm.ErrExecutionReverted {
+		t.Errorf("value transfer error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	}
+	if _, err := call(nil, "get(address)", append([]byte{0x01}, make([]byte, 31)...)); err != vm.ErrExecutionReverted {
+		t.Errorf("dirty address error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	}
+}
+
+func TestContractCodePush(t *testing.T) {
+	tests := []struct {
+		push func(c *contractCode)
+		want []byte
+	}{
+		{func(c *contractCode) { c.push(0) }, []byte{byte(vm.PUSH1), 0x00}},
+		{func(c *contractCode) { c.push(0x1234) }, []byte{byte(vm.PUSH2), 0x12, 0x34}},
+		{func(c *contractCode) { c.pushHash(common.BigToHash(common.Big3)) }, []byte{byte(vm.PUSH1), 0x03}},
+		{func(c *contractCode) { c.pushBytes([]byte{0x00, 0x00, 0x01, 0x00}) }, []byte{byte(vm.PUSH2), 0x01, 0x00}},
+	}
+	for i, tt := range tests {
+		c := &contractCode{labels: make(map[string]int), jumps: make(map[int]string)}
+		tt.push(c)
+		if have := c.bytes(); !bytes.Equal(have, tt.want) {
+			t.Errorf("test %d: code mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+	// Jumps are resolved to the labels laid out after them
+	c := &contractCode{labels: make(map[string]int), jumps: make(map[int]string)}
+	c.jump("end").op(vm.STOP).label("end")
+	if have, want := c.bytes(), []byte{byte(vm.PUSH2), 0x00, 0x05, byte(vm.JUMP), byte(vm.STOP), byte(vm.JUMPDEST)}; !bytes.Equal(have, want) {
+		t.Errorf("jump mismatch: have %x, want %x", have, want)
+	}
+}
@@ -0,0 +1,224 @@
+package dpos
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/ethdb"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+const (
+	defaultDevPageLimit = 100  // Number of developers returned per page if not requested otherwise
+	maxDevPageLimit     = 1000 // Maximum number of developers returned per page
+
+	developerTxGas = 100000 // Gas limit of the transactions adding or removing a developer
+)
+
+var (
+	// errDevIndexDisabled is returned if the developers are listed while the
+	// developer index is not maintained.
+	errDevIndexDisabled = errors.New("dpos developer index is disabled")
+
+	// errAddressListUninitialized is returned if a developer transaction is built
+	// while the address list contract has no admin yet.
+	errAddressListUninitialized = errors.New("address list contract not initialized")
+
+	// errAlreadyDeveloper is returned if an allowlisted developer is added again.
+	errAlreadyDeveloper = errors.New("already in developer allowlist")
+
+	// errNotDeveloper is returned if a developer missing from the allowlist is removed.
+	errNotDeveloper = errors.New("not in developer allowlist")
+)
+
+// StartDevIndex starts maintaining the developer allowlist of the address list
+// contract from its DeveloperAdded and DeveloperRemoved events, until the engine
+// is closed. The contract mapping can't be enumerated from the state.
+func (d *Dpos) StartDevIndex(feed chainHeadSubscriber) {
+	d.devIndex = true
+	d.startLogIndex(d.newDevIndex(), feed)
+}
+
+// newDevIndex returns the developer index maintained from the AddressList events,
+// seeded with the developers allowlisted by the genesis allocation.
+func (d *Dpos) newDevIndex() *logIndex {
+	changes := &devChanges{devs: make(map[common.Address]bool)}
+	return &logIndex{
+		name:      "developer",
+		readHead:  rawdb.ReadDposDevIndexHead,
+		writeHead: rawdb.WriteDposDevIndexHead,
+		changes:   changes,
+		seed: func(genesis *types.Header) error {
+			return d.seedDevIndex(changes, genesis)
+		},
+	}
+}
+
+// seedDevIndex accumulates the developers allowlisted in the genesis state, which
+// emitted no events. As the mapping can't be enumerated, the accounts of the
+// genesis state are checked, whose addresses are known from the preimages stored
+// when committing the genesis.
+func (d *Dpos) seedDevIndex(changes *devChanges, genesis *types.Header) error {
+	statedb, err := d.stateFn(genesis.Root)
+	if err != nil {
+		return err
+	}
+	dump := statedb.RawDump(&state.DumpConfig{SkipCode: true, SkipStorage: true, OnlyWithAddresses: true})
+	for addr := range dump.Accounts {
+		if isDeveloper(statedb, addr) {
+			changes.devs[addr] = true
+		}
+	}
+	log.Debug("Seeded dpos developer index", "developers", len(changes.devs))
+	return nil
+}
+
+// devChanges accumulates the allowlist changes of a batch of blocks on top of the index.
+type devChanges struct {
+	devs map[common.Address]bool // Whether the developers are allowlisted after the batch
+}
+
+// apply accumulates the developers added and removed in the given block, reverting
+// them if the block left the canonical chain. The contract only emits the events
+// of effective changes, so reverting them in reverse order restores the allowlist.
+func (c *devChanges) apply(receipts types.Receipts, revert bool) {
+	events := systemcontract.GetInteractiveABI()[systemcontract.AddressListContractName].Events
+
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	for i := range logs {
+		l := logs[i]
+		if revert {
+			l = logs[len(logs)-1-i]
+		}
+		if l.Address != systemcontract.AddressListContractAddr || len(l.Topics) != 2 {
+			continue
+		}
+		var added bool
+		switch l.Topics[0] {
+		case events["DeveloperAdded"].ID:
+			added = true
+		case events["DeveloperRemoved"].ID:
+		default:
+			continue
+		}
+		c.devs[common.BytesToAddress(l.Topics[1].Bytes())] = added != revert
+	}
+}
+
+// flush writes the accumulated changes.
+func (c *devChanges) flush(batch ethdb.Batch) {
+	for dev, allowed := range c.devs {
+		rawdb.WriteDposDeveloper(batch, dev, allowed)
+	}
+	c.devs = make(map[common.Address]bool)
+}
+
+// DeveloperPageOptions selects a page of developers.
+type DeveloperPageOptions struct {
+	Cursor *common.Address `json:"cursor"` // The next cursor of the previous page, empty for the first one
+	Limit  int             `json:"limit"`  // Maximum number of developers in the page
+}
+
+// DeveloperPage is a page of the allowlisted developers read from the developer index.
+type DeveloperPage struct {
+	Number     hexutil.Uint64   `json:"number"` // Latest block indexed
+	Developers []common.Address `json:"developers"`
+	Next       *common.Address  `json:"next,omitempty"` // Cursor of the next page, empty on the last one
+}
+
+// ListDevelopers returns a page of the allowlisted developers, by ascending
+// address, from the given database maintained by the developer index.
+func ListDevelopers(db ethdb.Database, opts *DeveloperPageOptions) (*DeveloperPage, error) {
+	if opts == nil {
+		opts = new(DeveloperPageOptions)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultDevPageLimit
+	}
+	if limit > maxDevPageLimit {
+		limit = maxDevPageLimit
+	}
+	var start common.Address
+	if opts.Cursor != nil {
+		start = *opts.Cursor
+	}
+	number, _, _ := rawdb.ReadDposDevIndexHead(db)
+	page := &DeveloperPage{Number: hexutil.Uint64(number), Developers: []common.Address{}}
+
+	it := rawdb.IterateDposDevelopers(db, start)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) < common.AddressLength {
+			continue
+		}
+		dev := common.BytesToAddress(key[len(key)-common.AddressLength:])
+		if opts.Cursor != nil && dev == start {
+			continue
+		}
+		if len(page.Developers) == limit {
+			last := page.Developers[limit-1]
+			page.Next = &last
+			break
+		}
+		page.Developers = append(page.Developers, dev)
+	}
+	return page, it.Error()
+}
+
+// developersPage returns a page of the developers from the developer index.
+func (d *Dpos) developersPage(opts *DeveloperPageOptions) (*DeveloperPage, error) {
+	if !d.devIndex {
+		return nil, errDevIndexDisabled
+	}
+	return ListDevelopers(d.db, opts)
+}
+
+// isDeveloper reports whether the address is in the developer allowlist of the
+// address list contract, whether the verification is enabled or not.
+func isDeveloper(state consensus.StateReader, addr common.Address) bool {
+	return state.GetState(systemcontract.AddressListContractAddr, calcSlotOfDevMappingKey(addr)).Big().Sign() > 0
+}
+
+// BuildDeveloperTx assembles the unsigned transaction of the address list admin
+// adding the developer to the allowlist, or removing it, on top of the given state.
+// It returns the admin expected to sign it.
+func BuildDeveloperTx(statedb *state.StateDB, dev common.Address, add bool, gasPrice *big.Int) (*types.Transaction, common.Address, error) {
+	admin := addressListAdmin(statedb)
+	if admin == (common.Address{}) {
+		return nil, common.Address{}, errAddressListUninitialized
+	}
+	method := "addDeveloper"
+	switch allowed := isDeveloper(statedb, dev); {
+	case add && allowed:
+		return nil, common.Address{}, errAlreadyDeveloper
+	case !add && !allowed:
+		return nil, common.Address{}, errNotDeveloper
+	case !add:
+		method = "removeDeveloper"
+	}
+	data, err := systemcontract.GetInteractiveABI()[systemcontract.AddressListContractName].Pack(method, dev)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	to := systemcontract.AddressListContractAddr
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    statedb.GetNonce(admin),
+		GasPrice: gasPrice,
+		Gas:      developerTxGas,
+		To:       &to,
+		Data:     data,
+	})
+	return tx, admin, nil
+}
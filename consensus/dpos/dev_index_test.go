@@ -0,0 +1,111 @@
+package dpos
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestDeveloperIndex(t *testing.T) {
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+	if _, err := engine.developersPage(nil); err != errDevIndexDisabled {
+		t.Fatalf("error mismatch: have %v, want %v", err, errDevIndexDisabled)
+	}
+	engine.devIndex = true
+
+	var (
+		db     = engine.db
+		events = systemcontract.GetInteractiveABI()[systemcontract.AddressListContractName].Events
+		dev1   = common.HexToAddress("0x3001")
+		dev2   = common.HexToAddress("0x3002")
+		dev3   = common.HexToAddress("0x3003")
+		dev4   = common.HexToAddress("0x3004")
+	)
+	// The developers allowlisted by the genesis allocation are seeded, the ones of
+	// the genesis accounts being the only ones that can be found
+	genesisState := newTestState()
+	genesisState.SetNonce(dev4, 1)
+	genesisState.SetState(systemcontract.AddressListContractAddr, calcSlotOfDevMappingKey(dev4), common.BigToHash(common.Big1))
+	genesisState.IntermediateRoot(false)
+	engine.SetStateFn(func(common.Hash) (*state.StateDB, error) { return genesisState, nil })
+
+	devLog := func(event string, dev common.Address) *types.Log {
+		return &types.Log{
+			Address: systemcontract.AddressListContractAddr,
+			Topics:  []common.Hash{events[event].ID, dev.Hash()},
+		}
+	}
+	check := func(want ...common.Address) {
+		t.Helper()
+		if err := engine.updateLogIndex(engine.newDevIndex(), nil); err != nil {
+			t.Fatalf("failed to index developers: %v", err)
+		}
+		var have []common.Address
+		opts := &DeveloperPageOptions{Limit: 1}
+		for {
+			page, err := engine.developersPage(opts)
+			if err != nil {
+				t.Fatalf("failed to list developers: %v", err)
+			}
+			have = append(have, page.Developers...)
+			if page.Next == nil {
+				break
+			}
+			opts.Cursor = page.Next
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("developers mismatch: have %v, want %v", have, want)
+		}
+	}
+	genesis := insertTestBlock(db, nil, 0)
+	block1 := insertTestBlock(db, genesis, 0, devLog("DeveloperAdded", dev1), devLog("DeveloperAdded", dev2))
+	block2 := insertTestBlock(db, block1, 0, devLog("DeveloperRemoved", dev1), devLog("DeveloperAdded", dev3), devLog("DeveloperRemoved", dev3))
+	check(dev2, dev4)
+
+	// Reorg out the second block and back, the changes of the reorged out blocks
+	// are reverted in reverse order
+	insertTestBlock(db, block1, 1, devLog("DeveloperRemoved", dev2), devLog("DeveloperAdded", dev2), devLog("DeveloperAdded", dev3))
+	check(dev1, dev2, dev3, dev4)
+	rawdb.WriteCanonicalHash(db, block2.Hash(), 2)
+	rawdb.WriteHeadBlockHash(db, block2.Hash())
+	check(dev2, dev4)
+
+	// The admin transactions are checked against the allowlist in the state
+	statedb := newTestState()
+	if _, _, err := BuildDeveloperTx(statedb, dev1, true, big.NewInt(1)); err != errAddressListUninitialized {
+		t.Fatalf("error mismatch: have %v, want %v", err, errAddressListUninitialized)
+	}
+	admin := common.HexToAddress("0x4001")
+	var slot common.Hash
+	copy(slot[common.HashLength-2-common.AddressLength:], admin.Bytes())
+	slot[common.HashLength-1] = 0x01
+	statedb.SetState(systemcontract.AddressListContractAddr, common.Hash{}, slot)
+	statedb.SetState(systemcontract.AddressListContractAddr, calcSlotOfDevMappingKey(dev2), common.BigToHash(common.Big1))
+	statedb.SetNonce(admin, 7)
+
+	if _, _, err := BuildDeveloperTx(statedb, dev2, true, big.NewInt(1)); err != errAlreadyDeveloper {
+		t.Errorf("error mismatch: have %v, want %v", err, errAlreadyDeveloper)
+	}
+	if _, _, err := BuildDeveloperTx(statedb, dev1, false, big.NewInt(1)); err != errNotDeveloper {
+		t.Errorf("error mismatch: have %v, want %v", err, errNotDeveloper)
+	}
+	tx, sender, err := BuildDeveloperTx(statedb, dev2, false, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to build developer transaction: %v", err)
+	}
+	addressListABI := systemcontract.GetInteractiveABI()[systemcontract.AddressListContractName]
+	method, err := addressListABI.MethodById(tx.Data())
+	if err != nil || method.Name != "removeDeveloper" {
+		t.Errorf("method mismatch: have %v, want removeDeveloper (err %v)", method, err)
+	}
+	if sender != admin || tx.Nonce() != 7 || *tx.To() != systemcontract.AddressListContractAddr {
+		t.Errorf("transaction mismatch: sender %v, nonce %d, to %v", sender, tx.Nonce(), tx.To())
+	}
+}
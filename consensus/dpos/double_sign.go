@@ -0,0 +1,495 @@
+package dpos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/accounts"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/log"
+	"github.com/DxChainNetwork/dxc/rlp"
+)
+
+const (
+	inmemorySealedHeaders = 4096 // Number of recent sealed headers to keep in memory for double-sign detection
+	maxPendingEvidences   = 64   // Max offences waiting to be packed into a block
+)
+
+var (
+	// errInvalidEvidence is returned if a double-sign evidence doesn't prove that one
+	// validator sealed two different headers at the same height.
+	errInvalidEvidence = errors.New("invalid double sign evidence")
+
+	// errStaleEvidence is returned if a double-sign evidence is too old, or from the future.
+	errStaleEvidence = errors.New("stale double sign evidence")
+
+	// errDuplicateEvidence is returned if the offence has already been slashed.
+	errDuplicateEvidence = errors.New("duplicate double sign evidence")
+)
+
+// DoubleSignEvidence is the proof that a validator sealed two different headers at
+// the same height. Each header is carried in its DposRLP form, which is exactly the
+// data being signed, together with the 65 byte seal taken from the header extra-data.
+type DoubleSignEvidence struct {
+	HeaderA    hexutil.Bytes `json:"headerA"`
+	SignatureA hexutil.Bytes `json:"signatureA"`
+	HeaderB    hexutil.Bytes `json:"headerB"`
+	SignatureB hexutil.Bytes `json:"signatureB"`
+}
+
+// sigHeader is the decoded form of DposRLP, the field order must be kept the same as encodeSigHeader.
+type sigHeader struct {
+	ParentHash  common.Hash
+	UncleHash   common.Hash
+	Coinbase    common.Address
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	Bloom       types.Bloom
+	Difficulty  *big.Int
+	Number      *big.Int
+	GasLimit    uint64
+	GasUsed     uint64
+	Time        uint64
+	Extra       []byte
+	MixDigest   common.Hash
+	Nonce       types.BlockNonce
+}
+
+// sealKey identifies a sealing slot of a validator, an offence if it sealed two headers.
+type sealKey struct {
+	signer common.Address
+	number uint64
+}
+
+// NewDoubleSignEvidence builds the evidence from two sealed headers.
+func NewDoubleSignEvidence(a, b *types.Header) *DoubleSignEvidence {
+	return &DoubleSignEvidence{
+		HeaderA:    DposRLP(a),
+		SignatureA: common.CopyBytes(a.Extra[len(a.Extra)-extraSeal:]),
+		HeaderB:    DposRLP(b),
+		SignatureB: common.CopyBytes(b.Extra[len(b.Extra)-extraSeal:]),
+	}
+}
+
+// Hash returns the keccak256 hash of the evidence's RLP encoding.
+func (ev *DoubleSignEvidence) Hash() common.Hash {
+	blob, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return common.Hash{}
+	}
+	return crypto.Keccak256Hash(blob)
+}
+
+// Verify checks that both headers are at the same height, are different from each
+// other and are sealed by the same key on behalf of the same validator. It returns
+// the key, the validator claimed by the coinbase of the headers and the height.
+//
+// The key is only bound to the validator by the snapshot of the chain, see verifyEvidenceAt.
+func (ev *DoubleSignEvidence) Verify() (signer common.Address, offender common.Address, height uint64, err error) {
+	if bytes.Equal(ev.HeaderA, ev.HeaderB) {
+		return common.Address{}, common.Address{}, 0, errInvalidEvidence
+	}
+	signerA, a, err := recoverSigHeader(ev.HeaderA, ev.SignatureA)
+	if err != nil {
+		return common.Address{}, common.Address{}, 0, err
+	}
+	signerB, b, err := recoverSigHeader(ev.HeaderB, ev.SignatureB)
+	if err != nil {
+		return common.Address{}, common.Address{}, 0, err
+	}
+	if a.Number == nil || b.Number == nil || a.Number.Cmp(b.Number) != 0 || !a.Number.IsUint64() {
+		return common.Address{}, common.Address{}, 0, errInvalidEvidence
+	}
+	if signerA != signerB || a.Coinbase != b.Coinbase {
+		return common.Address{}, common.Address{}, 0, errInvalidEvidence
+	}
+	return signerA, a.Coinbase, a.Number.Uint64(), nil
+}
+
+// recoverSigHeader decodes a DposRLP blob and recovers the address that signed it.
+func recoverSigHeader(blob []byte, sig []byte) (common.Address, *sigHeader, error) {
+	if len(sig) != extraSeal {
+		return common.Address{}, nil, errMissingSignature
+	}
+	h := new(sigHeader)
+	if err := rlp.DecodeBytes(blob, h); err != nil {
+		return common.Address{}, nil, err
+	}
+	pubkey, err := crypto.Ecrecover(crypto.Keccak256(blob), sig)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, h, nil
+}
+
+// recordSealedHeader remembers the header sealed by signer, and queues a double-sign
+// evidence if the signer already sealed a different header at the same height.
+func (d *Dpos) recordSealedHeader(signer common.Address, header *types.Header) {
+	key := sealKey{signer: signer, number: header.Number.Uint64()}
+	if v, ok := d.sealedHeaders.Get(key); ok {
+		prev := v.(*types.Header)
+		if SealHash(prev) != SealHash(header) {
+			log.Warn("Double sign detected", "validator", signer, "number", key.number, "hashA", prev.Hash(), "hashB", header.Hash())
+			d.addEvidence(NewDoubleSignEvidence(prev, header))
+		}
+		return
+	}
+	d.sealedHeaders.Add(key, header)
+}
+
+// addEvidence adds a double-sign evidence to the local pool, waiting to be packed by
+// the local validator. A single evidence is kept for each offence, it returns the
+// hash of the one queued for the offence of the given evidence.
+func (d *Dpos) addEvidence(ev *DoubleSignEvidence) common.Hash {
+	_, offender, height, err := ev.Verify()
+	if err != nil {
+		return common.Hash{}
+	}
+	key := sealKey{signer: offender, number: height}
+
+	d.evLock.Lock()
+	defer d.evLock.Unlock()
+	if queued, exist := d.evidences[key]; exist {
+		return queued.Hash()
+	}
+	if len(d.evidences) < maxPendingEvidences {
+		d.evidences[key] = ev
+	}
+	return ev.Hash()
+}
+
+// pendingEvidences returns all queued double-sign evidences.
+func (d *Dpos) pendingEvidences() []*DoubleSignEvidence {
+	d.evLock.Lock()
+	defer d.evLock.Unlock()
+
+	evs := make([]*DoubleSignEvidence, 0, len(d.evidences))
+	for _, ev := range d.evidences {
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// dropEvidence removes an evidence from the local pool.
+func (d *Dpos) dropEvidence(ev *DoubleSignEvidence) {
+	d.evLock.Lock()
+	defer d.evLock.Unlock()
+
+	hash := ev.Hash()
+	for key, queued := range d.evidences {
+		if queued.Hash() == hash {
+			delete(d.evidences, key)
+		}
+	}
+}
+
+// evidenceSlot returns the storage slot used to record a slashed offence.
+func evidenceSlot(offender common.Address, height uint64) common.Hash {
+	return crypto.Keccak256Hash(offender.Bytes(), new(big.Int).SetUint64(height).Bytes())
+}
+
+// evidenceInRange returns whether an offence at the given height can be slashed by
+// the block with the given number, i.e. it's from one of the blocks of the last epoch.
+func (d *Dpos) evidenceInRange(height uint64, number uint64) bool {
+	return height < number && number-height <= d.config.Epoch
+}
+
+// verifyEvidenceAt checks whether the evidence can be used to slash the offender at the given header.
+func (d *Dpos) verifyEvidenceAt(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, ev *DoubleSignEvidence) (common.Address, uint64, error) {
+	signer, offender, height, err := ev.Verify()
+	if err != nil {
+		if err != errInvalidEvidence {
+			err = fmt.Errorf("%w: %v", errInvalidEvidence, err)
+		}
+		return common.Address{}, 0, err
+	}
+	number := header.Number.Uint64()
+	if !d.evidenceInRange(height, number) {
+		return common.Address{}, 0, errStaleEvidence
+	}
+	snap, err := d.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return common.Address{}, 0, err
+	}
+	// The headers may be sealed with the signing key of the offender
+	if snap.validatorOf(signer) != offender {
+		return common.Address{}, 0, errInvalidEvidence
+	}
+	if _, ok := snap.Validators[offender]; !ok {
+		return common.Address{}, 0, fmt.Errorf("%w: %v", errInvalidEvidence, errUnauthorizedValidator)
+	}
+	if state.GetState(systemcontract.DoubleSignEvidenceToAddr, evidenceSlot(offender, height)) != (common.Hash{}) {
+		return common.Address{}, 0, errDuplicateEvidence
+	}
+	return offender, height, nil
+}
+
+// recordEvidence marks the offence as slashed at the given block number.
+func recordEvidence(state vm.StateDB, offender common.Address, height uint64, number *big.Int) {
+	// An account without nonce, balance and code will be deleted as an empty one,
+	// so make sure the account holding the records is not empty.
+	if state.GetNonce(systemcontract.DoubleSignEvidenceToAddr) == 0 {
+		state.SetNonce(systemcontract.DoubleSignEvidenceToAddr, 1)
+	}
+	state.SetState(systemcontract.DoubleSignEvidenceToAddr, evidenceSlot(offender, height), common.BigToHash(number))
+}
+
+// applyEvidence records the offence and slashes the offender.
+func (d *Dpos) applyEvidence(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, offender common.Address, height uint64) error {
+	recordEvidence(state, offender, height, header.Number)
+	return d.slashValidator(offender, chain, header, state)
+}
+
+// slashValidator punishes the validator as many times as needed to reach the next
+// multiple of MAX_PUNISH_COUNT, so the SystemRewards contract kicks it out and
+// burns its reward of the current epoch.
+func (d *Dpos) slashValidator(validator common.Address, chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB) error {
+	sysRewardsABI := d.abi[systemcontract.SystemRewardsContractName]
+	ret, err := d.commonCallContract(header, state, sysRewardsABI, systemcontract.SystemRewardsContractAddr, "MAX_PUNISH_COUNT", 1)
+	if err != nil {
+		return err
+	}
+	maxCount, ok := ret[0].(*big.Int)
+	if !ok {
+		return errors.New("invalid MAX_PUNISH_COUNT")
+	}
+	ret, err = d.commonCallContract(header, state, sysRewardsABI, systemcontract.SystemRewardsContractAddr, "currentEpoch", 1)
+	if err != nil {
+		return err
+	}
+	epoch, ok := ret[0].(*big.Int)
+	if !ok {
+		return errors.New("invalid currentEpoch")
+	}
+	punish, err := systemcontract.NewSystemRewards().PunishInfo(state, header, newChainContext(chain, d), d.chainConfig, validator, epoch)
+	if err != nil {
+		return err
+	}
+	times := slashTimes(maxCount, punish.Count)
+	for i := uint64(0); i < times; i++ {
+		if err := d.punishValidator(validator, chain, header, state); err != nil {
+			return err
+		}
+	}
+	log.Info("Slashed double sign validator", "validator", validator, "number", header.Number, "punished", times)
+	return nil
+}
+
+// slashTimes returns the number of punishments raising the punish count to the next
+// multiple of the max count. A zero max count disables the punishment.
+func slashTimes(maxCount *big.Int, count *big.Int) uint64 {
+	if maxCount.Sign() <= 0 {
+		return 0
+	}
+	return new(big.Int).Sub(maxCount, new(big.Int).Mod(count, maxCount)).Uint64()
+}
+
+// executeEvidence packs a double-sign evidence into a system transaction and applies it.
+func (d *Dpos) executeEvidence(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, ev *DoubleSignEvidence, totalTxIndex int) (*types.Transaction, *types.Receipt, common.Address, error) {
+	offender, height, err := d.verifyEvidenceAt(chain, header, state, ev)
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	data, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	nonce := state.GetNonce(d.signingKey)
+	tx := types.NewTransaction(nonce, systemcontract.DoubleSignEvidenceToAddr, new(big.Int), header.GasLimit, new(big.Int), data)
+	tx, err = d.signTxFn(accounts.Account{Address: d.signingKey}, tx, d.chainConfig.ChainID)
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	state.SetNonce(d.signingKey, nonce+1)
+
+	receipt, err := d.applyEvidenceTx(chain, header, state, offender, height, totalTxIndex, tx.Hash(), common.Hash{})
+	if err != nil {
+		return nil, nil, common.Address{}, err
+	}
+	return tx, receipt, offender, nil
+}
+
+// packEvidence executes a pending double-sign evidence on top of the state of the
+// block being assembled. A failed evidence leaves the state untouched and is dropped
+// from the pool if it can never be packed.
+func (d *Dpos) packEvidence(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, ev *DoubleSignEvidence, totalTxIndex int) (*types.Transaction, *types.Receipt, common.Address, error) {
+	// The system calls finalise the state, dropping its journal, so the changes of
+	// a failed slashing can't be reverted to a snapshot. Try it on a copy first.
+	err := d.tryEvidence(chain, header, state.Copy(), ev)
+	if err != nil {
+		// The evidence is invalid, stale or already used, never pack it again
+		if errors.Is(err, errInvalidEvidence) || errors.Is(err, errStaleEvidence) || errors.Is(err, errDuplicateEvidence) {
+			log.Debug("Drop double sign evidence", "hash", ev.Hash(), "err", err)
+			d.dropEvidence(ev)
+		} else {
+			log.Warn("Failed to pack double sign evidence", "hash", ev.Hash(), "err", err)
+		}
+		return nil, nil, common.Address{}, err
+	}
+	return d.executeEvidence(chain, header, state, ev, totalTxIndex)
+}
+
+// tryEvidence verifies the evidence and slashes the offender on the given state.
+func (d *Dpos) tryEvidence(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, ev *DoubleSignEvidence) error {
+	offender, height, err := d.verifyEvidenceAt(chain, header, state, ev)
+	if err != nil {
+		return err
+	}
+	return d.applyEvidence(chain, header, state, offender, height)
+}
+
+// replayEvidence verifies and applies a double-sign evidence system transaction of an imported block.
+func (d *Dpos) replayEvidence(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, totalTxIndex int, tx *types.Transaction) (*types.Receipt, common.Address, error) {
+	sender, err := types.Sender(d.signer, tx)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	if !d.isSystemSender(sender, header) {
+		return nil, common.Address{}, errors.New("invalid sender for double sign evidence transaction")
+	}
+	ev := new(DoubleSignEvidence)
+	if err := rlp.DecodeBytes(tx.Data(), ev); err != nil {
+		return nil, common.Address{}, err
+	}
+	offender, height, err := d.verifyEvidenceAt(chain, header, state, ev)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	nonce := state.GetNonce(sender)
+	state.SetNonce(sender, nonce+1)
+
+	receipt, err := d.applyEvidenceTx(chain, header, state, offender, height, totalTxIndex, tx.Hash(), header.Hash())
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return receipt, offender, nil
+}
+
+func (d *Dpos) applyEvidenceTx(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, offender common.Address, height uint64, totalTxIndex int, txHash, bHash common.Hash) (*types.Receipt, error) {
+	state.Prepare(txHash, totalTxIndex)
+	if err := d.applyEvidence(chain, header, state, offender, height); err != nil {
+		return nil, err
+	}
+	receipt := types.NewReceipt([]byte{}, false, header.GasUsed)
+	receipt.Logs = state.GetLogs(txHash, bHash)
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	receipt.TxHash = txHash
+	receipt.BlockHash = bHash
+	receipt.BlockNumber = header.Number
+	receipt.TransactionIndex = uint(state.TxIndex())
+
+	return receipt, nil
+}
+
+// splitSystemTxs separates the system governance transactions from the double-sign evidence transactions.
+func splitSystemTxs(systemTxs []*types.Transaction) (govTxs []*types.Transaction, evidenceTxs []*types.Transaction) {
+	for _, tx := range systemTxs {
+		if to := tx.To(); to != nil && *to == systemcontract.DoubleSignEvidenceToAddr {
+			evidenceTxs = append(evidenceTxs, tx)
+		} else {
+			govTxs = append(govTxs, tx)
+		}
+	}
+	return
+}
+
+// applyEvidenceSysTx applies a double-sign evidence system transaction using a given evm,
+// it's the counterpart of replayEvidence for tracing.
+func (d *Dpos) applyEvidenceSysTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender common.Address, tx *types.Transaction) (ret []byte, vmerr error, err error) {
+	ev := new(DoubleSignEvidence)
+	if err = rlp.DecodeBytes(tx.Data(), ev); err != nil {
+		return
+	}
+	signer, offender, height, err := ev.Verify()
+	if err != nil {
+		return
+	}
+	if d.chain == nil {
+		err = errUnknownBlock
+		return
+	}
+	number := evm.Context.BlockNumber.Uint64()
+	if !d.evidenceInRange(height, number) {
+		err = errStaleEvidence
+		return
+	}
+	snap, err := d.snapshot(d.chain, number-1, evm.Context.GetHash(number-1), nil)
+	if err != nil {
+		return
+	}
+	if snap.validatorOf(signer) != offender {
+		err = errInvalidEvidence
+		return
+	}
+	evm.Context.ExtraValidator = nil
+	nonce := evm.StateDB.GetNonce(sender)
+	evm.StateDB.SetNonce(sender, nonce+1)
+
+	state.Prepare(tx.Hash(), txIndex)
+	evm.TxContext = vm.TxContext{
+		Origin:   sender,
+		GasPrice: new(big.Int),
+	}
+	recordEvidence(evm.StateDB, offender, height, evm.Context.BlockNumber)
+
+	sysRewardsABI := d.abi[systemcontract.SystemRewardsContractName]
+	call := func(method string, args ...interface{}) ([]interface{}, error) {
+		data, err := sysRewardsABI.Pack(method, args...)
+		if err != nil {
+			return nil, err
+		}
+		ret, _, err := evm.Call(vm.AccountRef(sender), systemcontract.SystemRewardsContractAddr, data, tx.Gas(), new(big.Int))
+		if err != nil {
+			return nil, err
+		}
+		return sysRewardsABI.Unpack(method, ret)
+	}
+	out, vmerr := call("MAX_PUNISH_COUNT")
+	if vmerr != nil {
+		return
+	}
+	maxCount, ok := out[0].(*big.Int)
+	if !ok {
+		vmerr = errors.New("invalid MAX_PUNISH_COUNT")
+		return
+	}
+	if out, vmerr = call("currentEpoch"); vmerr != nil {
+		return
+	}
+	epoch, ok := out[0].(*big.Int)
+	if !ok {
+		vmerr = errors.New("invalid currentEpoch")
+		return
+	}
+	data, vmerr := sysRewardsABI.Pack("punishInfo", offender, epoch)
+	if vmerr != nil {
+		return
+	}
+	if ret, _, vmerr = evm.Call(vm.AccountRef(sender), systemcontract.SystemRewardsContractAddr, data, tx.Gas(), new(big.Int)); vmerr != nil {
+		return
+	}
+	punish := new(systemcontract.Punish)
+	if vmerr = sysRewardsABI.UnpackIntoInterface(punish, "punishInfo", ret); vmerr != nil {
+		return
+	}
+	times := slashTimes(maxCount, punish.Count)
+	for i := uint64(0); i < times; i++ {
+		if _, vmerr = call("punish", offender); vmerr != nil {
+			return
+		}
+	}
+	state.Finalise(true)
+	return
+}
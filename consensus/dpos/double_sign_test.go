@@ -0,0 +1,237 @@
+package dpos
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/accounts"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+// sealEvidenceHeader seals a header of the validator at the given height with the key.
+func sealEvidenceHeader(t *testing.T, key *ecdsa.PrivateKey, validator common.Address, number int64, time uint64) *types.Header {
+	header := &types.Header{
+		Coinbase:   validator,
+		Number:     big.NewInt(number),
+		Difficulty: diffInTurn,
+		Time:       time,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign header: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	return header
+}
+
+func TestDoubleSignEvidence(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	seal := func(number int64, time uint64) *types.Header {
+		return sealEvidenceHeader(t, key, signer, number, time)
+	}
+	a, b := seal(10, 100), seal(10, 101)
+
+	sealer, offender, height, err := NewDoubleSignEvidence(a, b).Verify()
+	if err != nil {
+		t.Fatalf("failed to verify evidence: %v", err)
+	}
+	if sealer != signer || offender != signer || height != 10 {
+		t.Fatalf("offender mismatch: have %x/%x/%d, want %x/%d", sealer, offender, height, signer, 10)
+	}
+	// The same header twice is not an offence
+	if _, _, _, err := NewDoubleSignEvidence(a, a).Verify(); err != errInvalidEvidence {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidEvidence)
+	}
+	// Different heights are not an offence
+	if _, _, _, err := NewDoubleSignEvidence(a, seal(11, 100)).Verify(); err != errInvalidEvidence {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidEvidence)
+	}
+	// Headers sealed on behalf of different validators are not an offence
+	if _, _, _, err := NewDoubleSignEvidence(a, sealEvidenceHeader(t, key, common.Address{0x01}, 10, 101)).Verify(); err != errInvalidEvidence {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidEvidence)
+	}
+	// Tampered signature must not recover the offender
+	ev := NewDoubleSignEvidence(a, b)
+	ev.SignatureB = bytes.Repeat([]byte{0x01}, extraSeal)
+	if sealer, _, _, err := ev.Verify(); err == nil && sealer == signer {
+		t.Fatalf("tampered evidence verified")
+	}
+}
+
+// newEvidenceTestEngine creates an engine whose snapshot at the parent of the
+// returned block 12 binds the validator to the signing key.
+func newEvidenceTestEngine(validator, signingKey common.Address) (*Dpos, *types.Header) {
+	config := *params.TestChainConfig
+	config.BerlinBlock, config.LondonBlock = nil, nil
+	config.Dpos = &params.DposConfig{Epoch: 100}
+	engine := New(&config, rawdb.NewMemoryDatabase())
+
+	parent := common.Hash{0x0b}
+	snap := newSnapshot(engine.config, engine.signatures, 11, parent, []common.Address{validator}, nil)
+	snap.setSigners([]common.Address{validator}, []common.Address{signingKey})
+	engine.recents.Add(parent, snap)
+
+	return engine, &types.Header{Number: big.NewInt(12), ParentHash: parent, Coinbase: common.Address{0xcc}, GasLimit: 8000000, Difficulty: diffInTurn}
+}
+
+// newPunishCounterCode returns a SystemRewards stub counting the punishments in
+// its slot 0. It answers any other call with 32 followed by empty punish records,
+// which decodes as the result of all the getters used to slash.
+func newPunishCounterCode(engine *Dpos) []byte {
+	punish := engine.abi[systemcontract.SystemRewardsContractName].Methods["punish"].ID
+	code := append([]byte{0x63}, punish...)
+	return append(code, common.FromHex("0x60003560e01c14602957"+
+		"6020600052608060405260a060605260c0608052610100"+"6000f3"+
+		"5b60016000540160005500")...)
+}
+
+func TestSlashKeyedValidator(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		signingKey = crypto.PubkeyToAddress(key.PublicKey)
+		validator  = common.HexToAddress("0x1001")
+	)
+	engine, header := newEvidenceTestEngine(validator, signingKey)
+	statedb := newTestState()
+	statedb.SetCode(systemcontract.SystemRewardsContractAddr, newPunishCounterCode(engine))
+
+	// The headers sealed with the signing key on behalf of the validator slash it
+	ev := NewDoubleSignEvidence(sealEvidenceHeader(t, key, validator, 10, 100), sealEvidenceHeader(t, key, validator, 10, 101))
+	offender, height, err := engine.verifyEvidenceAt(nil, header, statedb, ev)
+	if err != nil {
+		t.Fatalf("failed to verify keyed evidence: %v", err)
+	}
+	if offender != validator || height != 10 {
+		t.Fatalf("offender mismatch: have %x/%d, want %x/%d", offender, height, validator, 10)
+	}
+	if err := engine.applyEvidence(nil, header, statedb, offender, height); err != nil {
+		t.Fatalf("failed to slash validator: %v", err)
+	}
+	if have := statedb.GetState(systemcontract.SystemRewardsContractAddr, common.Hash{}).Big(); have.Uint64() != 32 {
+		t.Errorf("punish count mismatch: have %v, want 32", have)
+	}
+	if _, _, err := engine.verifyEvidenceAt(nil, header, statedb, ev); err != errDuplicateEvidence {
+		t.Errorf("error mismatch: have %v, want %v", err, errDuplicateEvidence)
+	}
+	// The key sealing on behalf of itself is not the validator bound to it
+	other := NewDoubleSignEvidence(sealEvidenceHeader(t, key, signingKey, 10, 100), sealEvidenceHeader(t, key, signingKey, 10, 101))
+	if _, _, err := engine.verifyEvidenceAt(nil, header, statedb, other); err != errInvalidEvidence {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidEvidence)
+	}
+}
+
+func TestPackEvidenceRevert(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		signingKey = crypto.PubkeyToAddress(key.PublicKey)
+		validator  = common.HexToAddress("0x1001")
+		packer     = common.HexToAddress("0x2001")
+	)
+	engine, header := newEvidenceTestEngine(validator, signingKey)
+	engine.signingKey = packer
+	engine.signTxFn = func(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		return tx, nil
+	}
+	statedb := newTestState()
+
+	ev := NewDoubleSignEvidence(sealEvidenceHeader(t, key, validator, 10, 100), sealEvidenceHeader(t, key, validator, 10, 101))
+	engine.addEvidence(ev)
+
+	// Slashing fails without the SystemRewards contract, the evidence is kept for
+	// the next block and the state is left untouched
+	if _, _, _, err := engine.packEvidence(nil, header, statedb, ev, 0); err == nil {
+		t.Fatalf("evidence packed without SystemRewards contract")
+	}
+	if nonce := statedb.GetNonce(packer); nonce != 0 {
+		t.Errorf("packer nonce not reverted: %d", nonce)
+	}
+	if record := statedb.GetState(systemcontract.DoubleSignEvidenceToAddr, evidenceSlot(validator, 10)); record != (common.Hash{}) {
+		t.Errorf("offence record not reverted: %x", record)
+	}
+	if pending := engine.pendingEvidences(); len(pending) != 1 {
+		t.Fatalf("evidence dropped on a transient error")
+	}
+	statedb.SetCode(systemcontract.SystemRewardsContractAddr, newPunishCounterCode(engine))
+	if _, _, offender, err := engine.packEvidence(nil, header, statedb, ev, 0); err != nil || offender != validator {
+		t.Fatalf("failed to pack evidence: %x/%v", offender, err)
+	}
+	if nonce := statedb.GetNonce(packer); nonce != 1 {
+		t.Errorf("packer nonce mismatch: have %d, want 1", nonce)
+	}
+	// The used evidence is dropped
+	if _, _, _, err := engine.packEvidence(nil, header, statedb, ev, 1); err != errDuplicateEvidence {
+		t.Fatalf("error mismatch: have %v, want %v", err, errDuplicateEvidence)
+	}
+	if pending := engine.pendingEvidences(); len(pending) != 0 {
+		t.Errorf("used evidence kept")
+	}
+}
+
+func TestEvidencePool(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	validator := common.HexToAddress("0x1001")
+	engine, _ := newEvidenceTestEngine(validator, crypto.PubkeyToAddress(key.PublicKey))
+
+	// A single evidence is queued for each offence
+	ev := NewDoubleSignEvidence(sealEvidenceHeader(t, key, validator, 10, 100), sealEvidenceHeader(t, key, validator, 10, 101))
+	if hash := engine.addEvidence(ev); hash != ev.Hash() {
+		t.Fatalf("hash mismatch: have %x, want %x", hash, ev.Hash())
+	}
+	dup := NewDoubleSignEvidence(sealEvidenceHeader(t, key, validator, 10, 100), sealEvidenceHeader(t, key, validator, 10, 102))
+	if hash := engine.addEvidence(dup); hash != ev.Hash() {
+		t.Errorf("duplicate offence hash mismatch: have %x, want %x", hash, ev.Hash())
+	}
+	// Invalid evidences are not queued
+	invalid := NewDoubleSignEvidence(sealEvidenceHeader(t, key, validator, 10, 100), sealEvidenceHeader(t, key, validator, 11, 100))
+	if hash := engine.addEvidence(invalid); hash != (common.Hash{}) {
+		t.Errorf("invalid evidence queued: %x", hash)
+	}
+	for height := int64(11); height < 11+2*maxPendingEvidences; height++ {
+		engine.addEvidence(NewDoubleSignEvidence(sealEvidenceHeader(t, key, validator, height, 100), sealEvidenceHeader(t, key, validator, height, 101)))
+	}
+	if pending := engine.pendingEvidences(); len(pending) != maxPendingEvidences {
+		t.Fatalf("pool size mismatch: have %d, want %d", len(pending), maxPendingEvidences)
+	}
+	engine.dropEvidence(ev)
+	if pending := engine.pendingEvidences(); len(pending) != maxPendingEvidences-1 {
+		t.Errorf("pool size mismatch after drop: have %d, want %d", len(pending), maxPendingEvidences-1)
+	}
+}
+
+func TestSlashTimes(t *testing.T) {
+	tests := []struct {
+		maxCount, count int64
+		want            uint64
+	}{
+		{32, 0, 32},
+		{32, 5, 27},
+		{32, 32, 32},
+		{0, 5, 0}, // Punishment disabled
+	}
+	for i, tt := range tests {
+		if have := slashTimes(big.NewInt(tt.maxCount), big.NewInt(tt.count)); have != tt.want {
+			t.Errorf("test %d: times mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+	engine := &Dpos{config: &params.DposConfig{Epoch: 100}}
+	for _, tt := range []struct {
+		height, number uint64
+		want           bool
+	}{
+		{10, 10, false}, {10, 11, true}, {10, 110, true}, {10, 111, false}, {11, 10, false},
+	} {
+		if have := engine.evidenceInRange(tt.height, tt.number); have != tt.want {
+			t.Errorf("range of %d at %d mismatch: have %v, want %v", tt.height, tt.number, have, tt.want)
+		}
+	}
+}
@@ -26,7 +26,9 @@ import (
 	"math"
 	"math/big"
 	"math/rand"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DxChainNetwork/dxc/accounts"
@@ -40,6 +42,7 @@ import (
 	"github.com/DxChainNetwork/dxc/core/types"
 	"github.com/DxChainNetwork/dxc/crypto"
 	"github.com/DxChainNetwork/dxc/ethdb"
+	"github.com/DxChainNetwork/dxc/event"
 	"github.com/DxChainNetwork/dxc/log"
 	"github.com/DxChainNetwork/dxc/metrics"
 	"github.com/DxChainNetwork/dxc/params"
@@ -59,6 +62,8 @@ const (
 	maxValidators = 99                     // Max validators allowed sealing.
 
 	inmemoryBlacklist = 21 // Number of recent blacklist snapshots to keep in memory
+
+	blacklistReloadInterval = 1024 // Number of blocks after which the blacklist and rules are fully reloaded to check the cache
 )
 
 type blacklistDirection uint
@@ -69,6 +74,36 @@ const (
 	DirectionBoth
 )
 
+// String implements fmt.Stringer.
+func (d blacklistDirection) String() string {
+	switch d {
+	case DirectionFrom:
+		return "from"
+	case DirectionTo:
+		return "to"
+	case DirectionBoth:
+		return "both"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d blacklistDirection) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *blacklistDirection) UnmarshalText(input []byte) error {
+	for _, dir := range []blacklistDirection{DirectionFrom, DirectionTo, DirectionBoth} {
+		if dir.String() == string(input) {
+			*d = dir
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown blacklist direction %q", input)
+}
+
 // Dpos delegated proof-of-stake protocol constants.
 var (
 	epochLength = uint64(14400) // Default number of blocks after which to checkpoint and reset the pending votes
@@ -158,6 +193,13 @@ var (
 var (
 	getblacklistTimer = metrics.NewRegisteredTimer("dpos/blacklist/get", nil)
 	getRulesTimer     = metrics.NewRegisteredTimer("dpos/eventcheckrules/get", nil)
+
+	blacklistIncrementalMeter = metrics.NewRegisteredMeter("dpos/blacklist/incremental", nil) // Blacklists updated from the events of the parent block
+	blacklistReloadMeter      = metrics.NewRegisteredMeter("dpos/blacklist/reload", nil)      // Blacklists fully reloaded from the contracts
+	blacklistMismatchMeter    = metrics.NewRegisteredMeter("dpos/blacklist/mismatch", nil)    // Incremental blacklists differing from the reloaded ones
+	rulesIncrementalMeter     = metrics.NewRegisteredMeter("dpos/eventcheckrules/incremental", nil)
+	rulesReloadMeter          = metrics.NewRegisteredMeter("dpos/eventcheckrules/reload", nil)
+	rulesMismatchMeter        = metrics.NewRegisteredMeter("dpos/eventcheckrules/mismatch", nil)
 )
 
 // StateFn gets state by the state root hash.
@@ -209,12 +251,37 @@ type Dpos struct {
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
+	sealedHeaders *lru.ARCCache                   // Recently sealed headers of each validator, to detect double sign
+	evidences     map[sealKey]*DoubleSignEvidence // Double sign evidences waiting to be packed, by offence
+	evLock        sync.Mutex                      // Protects the evidences pool
+
+	attestations *lru.Cache   // Attestations collected for recent blocks, keyed by block hash
+	attLock      sync.Mutex   // Protects the attestations pool
+	finalized    atomic.Value // Latest block finalized by attestations
+	finalLock    sync.Mutex   // Protects the finalized block marker
+
+	validatorSetFeed event.Feed              // Feed of the validator set changes at checkpoints
+	proposalFeed     event.Feed              // Feed of the executed governance proposals
+	punishFeed       event.Feed              // Feed of the validator punishments
+	scope            event.SubscriptionScope // Tracks the subscriptions of the feeds above
+	blockEvents      *lru.Cache              // Events of recent blocks waiting to become canonical, keyed by hash or seal hash if mined locally
+	eventsHead       struct {                // Latest block whose events were posted, only accessed by the events loop
+		number uint64
+		hash   common.Hash
+	}
+
+	rewardEpochs *lru.Cache // Sealed block counts of recent epochs, keyed by the hash of their last block
+	rewardCache  *lru.Cache // Rewards of accounts in closed epochs
+	voteIndex    bool       // Whether the votes are indexed from the NodeVotes events
+	devIndex     bool       // Whether the developer allowlist is indexed from the AddressList events
+
 	signer types.Signer // the signer instance to recover tx sender
 
-	validator common.Address // Ethereum address of the signing key
-	signFn    ValidatorFn    // Validator function to authorize hashes with
-	signTxFn  SignTxFn
-	lock      sync.RWMutex // Protects the validator fields
+	validator  common.Address // Ethereum address of the staking account
+	signingKey common.Address // Ethereum address of the signing key, the staking account unless bound to another key
+	signFn     ValidatorFn    // Validator function to authorize hashes with
+	signTxFn   SignTxFn
+	lock       sync.RWMutex // Protects the validator fields
 
 	stateFn StateFn // Function to get state by state root
 
@@ -234,11 +301,23 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database) *Dpos {
 	if conf.Epoch == 0 {
 		conf.Epoch = epochLength
 	}
+	// Relocate or replace the system contracts as configured
+	if err := systemcontract.ApplyConfig(conf.SystemContracts); err != nil {
+		log.Crit("Invalid dpos system contracts config", "err", err)
+	}
+	if err := checkUpgradeMigrations(chainConfig.SystemContractUpgrades); err != nil {
+		log.Crit("Invalid system contract upgrades", "err", err)
+	}
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
 	blacklists, _ := lru.New(inmemoryBlacklist)
 	rules, _ := lru.New(inmemoryBlacklist)
+	sealedHeaders, _ := lru.NewARC(inmemorySealedHeaders)
+	attestations, _ := lru.New(inmemoryAttestations)
+	blockEvents, _ := lru.New(inmemoryEvents)
+	rewardEpochs, _ := lru.New(inmemoryRewardEpochs)
+	rewardCache, _ := lru.New(inmemoryRewardHistory)
 
 	return &Dpos{
 		chainConfig:     chainConfig,
@@ -249,6 +328,12 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database) *Dpos {
 		blacklists:      blacklists,
 		eventCheckRules: rules,
 		proposals:       make(map[common.Address]bool),
+		sealedHeaders:   sealedHeaders,
+		evidences:       make(map[sealKey]*DoubleSignEvidence),
+		attestations:    attestations,
+		blockEvents:     blockEvents,
+		rewardEpochs:    rewardEpochs,
+		rewardCache:     rewardCache,
 		abi:             systemcontract.GetInteractiveABI(),
 		signer:          types.LatestSignerForChainID(chainConfig.ChainID),
 	}
@@ -318,7 +403,7 @@ func (d *Dpos) verifyHeader(chain consensus.ChainHeaderReader, header *types.Hea
 		return errMissingSignature
 	}
 	// check extra data
-	isEpoch := number%d.config.Epoch == 0
+	isEpoch := d.config.IsCheckpoint(number)
 
 	// Ensure that the extra-data contains a validator list on checkpoint, but none otherwise
 	validatorsBytes := len(header.Extra) - extraVanity - extraSeal
@@ -326,7 +411,7 @@ func (d *Dpos) verifyHeader(chain consensus.ChainHeaderReader, header *types.Hea
 		return errExtraValidators
 	}
 	// Ensure that the validator bytes length is valid
-	if isEpoch && validatorsBytes%common.AddressLength != 0 {
+	if isEpoch && validatorsBytes%d.checkpointEntryLength(number) != 0 {
 		return errExtraValidators
 	}
 
@@ -375,8 +460,11 @@ func (d *Dpos) verifyCascadingFields(chain consensus.ChainHeaderReader, header *
 	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
 		return consensus.ErrUnknownAncestor
 	}
+	if err := d.verifyFinality(chain, header, parents); err != nil {
+		return err
+	}
 
-	if parent.Time+d.config.Period > header.Time {
+	if parent.Time+d.config.PeriodAt(number) > header.Time {
 		return ErrInvalidTimestamp
 	}
 
@@ -414,8 +502,9 @@ func (d *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 			snap = s.(*Snapshot)
 			break
 		}
-		// If an on-disk checkpoint snapshot can be found, use that
-		if number%checkpointInterval == 0 {
+		// If an on-disk checkpoint snapshot can be found, use that. Besides the periodic
+		// ones, epoch checkpoint snapshots are stored when trusted by light clients.
+		if number%checkpointInterval == 0 || d.config.IsCheckpoint(number) {
 			if s, err := loadSnapshot(d.config, d.signatures, d.db, hash); err == nil {
 				log.Trace("Loaded voting snapshot from disk", "number", number, "hash", hash)
 				snap = s
@@ -426,16 +515,14 @@ func (d *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 		// at a checkpoint block without a parent (light client CHT), or we have piled
 		// up more headers than allowed to be reorged (chain reinit from a freezer),
 		// consider the checkpoint trusted and snapshot it.
-		if number == 0 || (number%d.config.Epoch == 0 && (len(headers) > params.FullImmutabilityThreshold || chain.GetHeaderByNumber(number-1) == nil)) {
+		if number == 0 || (d.config.IsCheckpoint(number) && (len(headers) > params.FullImmutabilityThreshold || chain.GetHeaderByNumber(number-1) == nil)) {
 			checkpoint := chain.GetHeaderByNumber(number)
 			if checkpoint != nil {
 				hash := checkpoint.Hash()
 
-				validators := make([]common.Address, (len(checkpoint.Extra)-extraVanity-extraSeal)/common.AddressLength)
-				for i := 0; i < len(validators); i++ {
-					copy(validators[i][:], checkpoint.Extra[extraVanity+i*common.AddressLength:])
-				}
-				snap = newSnapshot(d.config, d.signatures, number, hash, validators)
+				validators, weights, signers := parseCheckpointValidators(checkpoint, isWeightedCheckpoint(d.config, number), isKeyedCheckpoint(d.config, number))
+				snap = newSnapshot(d.config, d.signatures, number, hash, validators, weights)
+				snap.setSigners(validators, signers)
 				if err := snap.store(d.db); err != nil {
 					return nil, err
 				}
@@ -470,6 +557,11 @@ func (d *Dpos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 	if err != nil {
 		return nil, err
 	}
+	for _, change := range snap.changes {
+		d.recordValidatorSetChange(change)
+	}
+	snap.changes = nil
+
 	d.recents.Add(snap.Hash, snap)
 
 	// If we've generated a new checkpoint snapshot, save to disk
@@ -518,16 +610,19 @@ func (d *Dpos) verifySeal(chain consensus.ChainHeaderReader, header *types.Heade
 	if err != nil {
 		return err
 	}
-	if signer != header.Coinbase {
+	// The validator may seal with a signing key bound to it instead of its own account
+	validator := snap.validatorOf(signer)
+	if validator != header.Coinbase {
 		return errInvalidCoinbase
 	}
 
-	if _, ok := snap.Validators[signer]; !ok {
+	if _, ok := snap.Validators[validator]; !ok {
 		return errUnauthorizedValidator
 	}
+	d.recordSealedHeader(validator, header)
 
 	for seen, recent := range snap.Recents {
-		if recent == signer {
+		if recent == validator {
 			// Validator is among recents, only fail if the current block doesn't shift it out
 			if limit := uint64(len(snap.Validators)/2 + 1); seen > number-limit {
 				return errRecentlySigned
@@ -537,7 +632,7 @@ func (d *Dpos) verifySeal(chain consensus.ChainHeaderReader, header *types.Heade
 
 	// Ensure that the difficulty corresponds to the turn-ness of the signer
 	if !d.fakeDiff {
-		inturn := snap.inturn(header.Number.Uint64(), signer)
+		inturn := snap.inturn(header.Number.Uint64(), validator)
 		if inturn && header.Difficulty.Cmp(diffInTurn) != 0 {
 			return errWrongDifficulty
 		}
@@ -581,14 +676,20 @@ func (d *Dpos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 		return err
 	}
 
-	if number%d.config.Epoch == 0 {
+	if d.config.IsCheckpoint(number) {
 		newValidators, err := d.getCurEpochValidators(chain, header, statedb)
 		if err != nil {
 			return err
 		}
-
-		for _, validator := range newValidators {
-			header.Extra = append(header.Extra, validator.Bytes()...)
+		var weights []uint64
+		if isWeightedCheckpoint(d.config, number) {
+			if weights, err = d.getCurEpochWeights(chain, header, statedb, newValidators); err != nil {
+				return err
+			}
+		}
+		header.Extra = append(header.Extra, encodeCheckpointValidators(newValidators, weights)...)
+		if isKeyedCheckpoint(d.config, number) {
+			header.Extra = append(header.Extra, encodeCheckpointSigners(checkpointSigners(statedb, newValidators))...)
 		}
 	}
 	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
@@ -597,7 +698,7 @@ func (d *Dpos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 	header.MixDigest = common.Hash{}
 
 	// Ensure the timestamp has the correct delay
-	header.Time = parent.Time + d.config.Period
+	header.Time = parent.Time + d.config.PeriodAt(number)
 	if header.Time < uint64(time.Now().Unix()) {
 		header.Time = uint64(time.Now().Unix())
 	}
@@ -614,11 +715,20 @@ func (d *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 			return err
 		}
 	}
+	d.applyConsensusParams(header, state)
+	if err := d.applySystemContractUpgrades(header, state); err != nil {
+		return err
+	}
 
+	events := new(blockEvents)
 	if header.Difficulty.Cmp(diffInTurn) != 0 {
-		if err := d.tryPunishValidator(chain, header, state); err != nil {
+		punished, ok, err := d.tryPunishValidator(chain, header, state)
+		if err != nil {
 			return err
 		}
+		if ok {
+			events.punish(d.config.EpochNumber(header.Number.Uint64()), punished, PunishMissedBlock)
+		}
 	}
 
 	// avoid nil pointer
@@ -630,6 +740,10 @@ func (d *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 		rs := make([]*types.Receipt, 0)
 		receipts = &rs
 	}
+	if err := d.applySigningKeys(chain, header, state); err != nil {
+		return err
+	}
+	d.applyBlacklistV2(header, state, *receipts)
 
 	// deposit block reward
 	if err := d.trySendBlockReward(chain, header, state); err != nil {
@@ -637,32 +751,58 @@ func (d *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 	}
 
 	// do epoch thing at the end, because it will update active validators
-	if header.Number.Uint64()%d.config.Epoch == 0 {
+	if d.config.IsCheckpoint(header.Number.Uint64()) {
 
 		newEpochValidators, err := d.getCurEpochValidators(chain, header, state)
 		if err != nil {
 			return err
 		}
-		log.Info("New Epoch", "header", header.Number.Uint64(), "epoch", header.Number.Uint64()/d.config.Epoch)
-
-		validatorsBytes := make([]byte, len(newEpochValidators)*common.AddressLength)
-		for i, validator := range newEpochValidators {
-			copy(validatorsBytes[i*common.AddressLength:], validator.Bytes())
+		log.Info("New Epoch", "header", header.Number.Uint64(), "epoch", d.config.EpochNumber(header.Number.Uint64()))
+
+		// the weights and signing keys are taken from the parent state, the same as when the header was prepared
+		var (
+			weighted = isWeightedCheckpoint(d.config, header.Number.Uint64())
+			keyed    = isKeyedCheckpoint(d.config, header.Number.Uint64())
+			weights  []uint64
+			signers  []common.Address
+		)
+		if weighted || keyed {
+			parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+			if parent == nil {
+				return consensus.ErrUnknownAncestor
+			}
+			parentState, err := d.stateFn(parent.Root)
+			if err != nil {
+				return err
+			}
+			if weighted {
+				if weights, err = d.getCurEpochWeights(chain, header, parentState, newEpochValidators); err != nil {
+					return err
+				}
+			}
+			if keyed {
+				signers = checkpointSigners(parentState, newEpochValidators)
+			}
 		}
+		validatorsBytes := append(encodeCheckpointValidators(newEpochValidators, weights), encodeCheckpointSigners(signers)...)
 
 		extraSuffix := len(header.Extra) - extraSeal
 		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], validatorsBytes) {
 			return errInvalidExtraValidators
 		}
+		if err := d.collectEpochEvents(chain, header, state, newEpochValidators, events); err != nil {
+			return err
+		}
 	}
 
 	//handle system governance Proposal
 	if chain.Config().IsRedCoast(header.Number) {
+		govTxs, evidenceTxs := splitSystemTxs(systemTxs)
 		proposalCount, err := d.getPassedProposalCount(chain, header, state)
 		if err != nil {
 			return err
 		}
-		if proposalCount != uint32(len(systemTxs)) {
+		if proposalCount != uint32(len(govTxs)) {
 			return errInvalidSysGovCount
 		}
 		// Due to the logics of the finish operation of contract `governance`, when finishing a proposal which
@@ -675,13 +815,14 @@ func (d *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 				return err
 			}
 			// execute the system governance Proposal
-			tx := systemTxs[int(i)]
+			tx := govTxs[int(i)]
 			receipt, err := d.replayProposal(chain, header, state, prop, len(*txs), tx)
 			if err != nil {
 				return err
 			}
 			*txs = append(*txs, tx)
 			*receipts = append(*receipts, receipt)
+			events.proposal(prop, tx, receipt)
 			// set
 			pIds = append(pIds, prop.Id)
 		}
@@ -692,12 +833,24 @@ func (d *Dpos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 				return err
 			}
 		}
+
+		// slash double sign validators
+		for _, tx := range evidenceTxs {
+			receipt, offender, err := d.replayEvidence(chain, header, state, len(*txs), tx)
+			if err != nil {
+				return err
+			}
+			events.punish(d.config.EpochNumber(header.Number.Uint64()), offender, PunishDoubleSign)
+			*txs = append(*txs, tx)
+			*receipts = append(*receipts, receipt)
+		}
 	}
 
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 
+	d.recordBlockEvents(header, events)
 	return nil
 }
 
@@ -715,14 +868,26 @@ func (d *Dpos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 			panic(err)
 		}
 	}
+	d.applyConsensusParams(header, state)
+	if err := d.applySystemContractUpgrades(header, state); err != nil {
+		panic(err)
+	}
 
 	// punish validator if necessary
+	events := new(blockEvents)
 	if header.Difficulty.Cmp(diffInTurn) != 0 {
-		err := d.tryPunishValidator(chain, header, state)
+		punished, ok, err := d.tryPunishValidator(chain, header, state)
 		if err != nil {
 			panic(err)
 		}
+		if ok {
+			events.punish(d.config.EpochNumber(header.Number.Uint64()), punished, PunishMissedBlock)
+		}
 	}
+	if err := d.applySigningKeys(chain, header, state); err != nil {
+		panic(err)
+	}
+	d.applyBlacklistV2(header, state, receipts)
 
 	// deposit block reward
 	if err := d.trySendBlockReward(chain, header, state); err != nil {
@@ -730,13 +895,17 @@ func (d *Dpos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 	}
 
 	// do  something at the epoch end
-	if header.Number.Uint64()%d.config.Epoch == 0 {
+	if d.config.IsCheckpoint(header.Number.Uint64()) {
 
 		newEpochValidators, err := d.getCurEpochValidators(chain, header, state)
 		if err != nil {
 			panic(err)
 		}
-		log.Info("New Epoch", "header", header.Number.Uint64(), "epoch", header.Number.Uint64()/d.config.Epoch, "count", len(newEpochValidators), "newEpochValidators", newEpochValidators)
+		log.Info("New Epoch", "header", header.Number.Uint64(), "epoch", d.config.EpochNumber(header.Number.Uint64()), "count", len(newEpochValidators), "newEpochValidators", newEpochValidators)
+
+		if err := d.collectEpochEvents(chain, header, state, newEpochValidators, events); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	//handle system governance Proposal
@@ -767,6 +936,7 @@ func (d *Dpos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 			}
 			txs = append(txs, tx)
 			receipts = append(receipts, receipt)
+			events.proposal(prop, tx, receipt)
 			// set
 			pIds = append(pIds, prop.Id)
 		}
@@ -777,12 +947,25 @@ func (d *Dpos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 				return nil, nil, err
 			}
 		}
+
+		// slash double sign validators
+		for _, ev := range d.pendingEvidences() {
+			tx, receipt, offender, err := d.packEvidence(chain, header, state, ev, len(txs))
+			if err != nil {
+				continue
+			}
+			events.punish(d.config.EpochNumber(header.Number.Uint64()), offender, PunishDoubleSign)
+			txs = append(txs, tx)
+			receipts = append(receipts, receipt)
+		}
 	}
 
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 
+	d.stashMinedEvents(header, events)
+
 	// Assemble and return the final block for sealing
 	return types.NewBlock(header, txs, nil, receipts, new(trie.Trie)), receipts, nil
 }
@@ -792,7 +975,7 @@ func (d *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *typ
 		return nil
 	}
 
-	blockRewardEpoch := new(big.Int).Div(header.Number, new(big.Int).SetUint64(d.config.Epoch))
+	blockRewardEpoch := new(big.Int).SetUint64(d.config.EpochNumber(header.Number.Uint64()))
 
 	s := systemcontract.NewSystemRewards()
 	// get Block Reward
@@ -803,7 +986,7 @@ func (d *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *typ
 	}
 
 	totalReward := new(big.Int).Add(epochInfo.BlockReward, state.GetBalance(consensus.FeeRecoder))
-	rewardToFoundation := new(big.Int).Div(new(big.Int).Mul(totalReward, big.NewInt(5)), big.NewInt(100))
+	rewardToFoundation := new(big.Int).Div(new(big.Int).Mul(totalReward, big.NewInt(foundationRewardPercent)), big.NewInt(100))
 	rewardToMiner := new(big.Int).Sub(totalReward, rewardToFoundation)
 
 	state.AddBalance(foundationAddress, rewardToFoundation)
@@ -829,15 +1012,16 @@ func (d *Dpos) trySendBlockReward(chain consensus.ChainHeaderReader, header *typ
 	return nil
 }
 
-func (d *Dpos) tryPunishValidator(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB) error {
+// tryPunishValidator punishes the in-turn validator of the block if it didn't seal
+// the block, returning the validator punished if any.
+func (d *Dpos) tryPunishValidator(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB) (common.Address, bool, error) {
 	number := header.Number.Uint64()
 	snap, err := d.snapshot(chain, number-1, header.ParentHash, nil)
 	if err != nil {
-		return err
+		return common.Address{}, false, err
 	}
 
-	validators := snap.validators()
-	outTurnValidator := validators[number%uint64(len(validators))]
+	outTurnValidator := snap.inturnValidator(number)
 	// check sigend recently or not
 	signedRecently := false
 	for _, recent := range snap.Recents {
@@ -847,10 +1031,13 @@ func (d *Dpos) tryPunishValidator(chain consensus.ChainHeaderReader, header *typ
 		}
 	}
 	if !signedRecently {
-		return d.punishValidator(outTurnValidator, chain, header, state)
+		if err := d.punishValidator(outTurnValidator, chain, header, state); err != nil {
+			return common.Address{}, false, err
+		}
+		return outTurnValidator, true, nil
 	}
 
-	return nil
+	return common.Address{}, false, nil
 }
 
 // punishValidator punish validator when not mining in turn
@@ -906,10 +1093,15 @@ func (d *Dpos) initializeSystemContracts(chain consensus.ChainHeaderReader, head
 		return err
 	}
 
-	genesisValidators := snap.validators()
-	if len(genesisValidators) != 1 {
-		return errInvalidValidatorsLength
+	initials, err := d.initialValidators(snap.validators())
+	if err != nil {
+		return err
+	}
+	genesisValidators := make([]common.Address, len(initials))
+	for i, val := range initials {
+		genesisValidators[i] = val.Address
 	}
+	first := initials[0]
 
 	initMigrateAddrs, initMigrateBals := systemcontract.InitMigrateAddrBalance()
 
@@ -925,7 +1117,7 @@ func (d *Dpos) initializeSystemContracts(chain consensus.ChainHeaderReader, head
 			return d.abi[systemcontract.SystemRewardsContractName].Pack(method, systemcontract.ValidatorsContractAddr, systemcontract.NodeVotesContractAddr)
 		}},
 		{systemcontract.ValidatorsContractAddr, func() ([]byte, error) {
-			return d.abi[systemcontract.ValidatorsContractName].Pack(method, systemcontract.ValidatorProposalsContractAddr, systemcontract.SystemRewardsContractAddr, systemcontract.NodeVotesContractAddr, genesisValidators[0], systemcontract.InitDeposit, systemcontract.InitRate, systemcontract.InitName, systemcontract.InitDetails)
+			return d.abi[systemcontract.ValidatorsContractName].Pack(method, systemcontract.ValidatorProposalsContractAddr, systemcontract.SystemRewardsContractAddr, systemcontract.NodeVotesContractAddr, first.Address, first.Deposit, first.Rate, first.Name, first.Details)
 		}},
 		{systemcontract.NodeVotesContractAddr, func() ([]byte, error) {
 			return d.abi[systemcontract.NodeVotesContractName].Pack(method, systemcontract.ValidatorsContractAddr, systemcontract.SystemRewardsContractAddr)
@@ -949,14 +1141,14 @@ func (d *Dpos) initializeSystemContracts(chain consensus.ChainHeaderReader, head
 		if err != nil {
 			return err
 		}
-		nonce := state.GetNonce(genesisValidators[0])
+		nonce := state.GetNonce(first.Address)
 
 		var msg types.Message
 
 		if contract.addr == systemcontract.ValidatorsContractAddr {
-			msg = vmcaller.NewLegacyMessage(genesisValidators[0], &contract.addr, nonce, systemcontract.InitDeposit, math.MaxUint64, new(big.Int), data, true)
+			msg = vmcaller.NewLegacyMessage(first.Address, &contract.addr, nonce, first.Deposit, math.MaxUint64, new(big.Int), data, true)
 		} else {
-			msg = vmcaller.NewLegacyMessage(genesisValidators[0], &contract.addr, nonce, new(big.Int), math.MaxUint64, new(big.Int), data, true)
+			msg = vmcaller.NewLegacyMessage(first.Address, &contract.addr, nonce, new(big.Int), math.MaxUint64, new(big.Int), data, true)
 		}
 
 		if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, d), d.chainConfig); err != nil {
@@ -965,7 +1157,7 @@ func (d *Dpos) initializeSystemContracts(chain consensus.ChainHeaderReader, head
 		}
 	}
 
-	return nil
+	return d.registerGenesisValidators(chain, header, state, initials)
 }
 
 // get current epoch validators after try elect
@@ -1002,10 +1194,18 @@ func (d *Dpos) getCurEpochValidators(chain consensus.ChainHeaderReader, header *
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 func (d *Dpos) Authorize(validator common.Address, signFn ValidatorFn, signTxFn SignTxFn) {
+	d.AuthorizeSigningKey(validator, validator, signFn, signTxFn)
+}
+
+// AuthorizeSigningKey injects the private key bound to the validator into the
+// consensus engine to mint new blocks with, the validator account itself holding
+// the stake doesn't need to be unlocked.
+func (d *Dpos) AuthorizeSigningKey(validator common.Address, key common.Address, signFn ValidatorFn, signTxFn SignTxFn) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
 	d.validator = validator
+	d.signingKey = key
 	d.signFn = signFn
 	d.signTxFn = signTxFn
 }
@@ -1021,13 +1221,13 @@ func (d *Dpos) Seal(chain consensus.ChainHeaderReader, block *types.Block, resul
 		return errUnknownBlock
 	}
 	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
-	if d.config.Period == 0 && len(block.Transactions()) == 0 {
+	if d.config.PeriodAt(number) == 0 && len(block.Transactions()) == 0 {
 		log.Info("Sealing paused, waiting for transactions")
 		return nil
 	}
 	// Don't hold the val fields for the entire sealing procedure
 	d.lock.RLock()
-	val, signFn := d.validator, d.signFn
+	val, key, signFn := d.validator, d.signingKey, d.signFn
 	d.lock.RUnlock()
 
 	// Bail out if we're unauthorized to sign a block
@@ -1038,6 +1238,10 @@ func (d *Dpos) Seal(chain consensus.ChainHeaderReader, block *types.Block, resul
 	if _, authorized := snap.Validators[val]; !authorized {
 		return errUnauthorizedValidator
 	}
+	if signer := snap.signerOf(val); signer != key {
+		log.Warn("Local signing key not in effect", "validator", val, "key", key, "want", signer)
+		return errUnauthorizedValidator
+	}
 	// If we're amongst the recent validators, wait for the next block
 	for seen, recent := range snap.Recents {
 		if recent == val {
@@ -1054,12 +1258,19 @@ func (d *Dpos) Seal(chain consensus.ChainHeaderReader, block *types.Block, resul
 	if header.Difficulty.Cmp(diffNoTurn) == 0 {
 		// It's not our turn explicitly to sign, delay it a bit
 		wiggle := time.Duration(len(snap.Validators)/2+1) * wiggleTime
+		if snap.Weights != nil {
+			// With the weighted schedule, validators whose own turn comes sooner
+			// back up the missing one first.
+			if turns := snap.nextTurn(number, val); turns < uint64(len(snap.Validators)/2+1) {
+				wiggle = time.Duration(turns) * wiggleTime
+			}
+		}
 		delay += time.Duration(rand.Int63n(int64(wiggle)))
 
 		log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
 	}
 	// Sign all the things!
-	sighash, err := signFn(accounts.Account{Address: val}, accounts.MimetypeDpos, DposRLP(header))
+	sighash, err := signFn(accounts.Account{Address: key}, accounts.MimetypeDpos, DposRLP(header))
 	if err != nil {
 		return err
 	}
@@ -1087,6 +1298,8 @@ func (d *Dpos) Seal(chain consensus.ChainHeaderReader, block *types.Block, resul
 // that a new block should have:
 // * DIFF_NOTURN(2) if BLOCK_NUMBER % validator_COUNT != validator_INDEX
 // * DIFF_INTURN(1) if BLOCK_NUMBER % validator_COUNT == validator_INDEX
+// With the weighted schedule, the validator index is taken from the slot sequence
+// derived from the weights of the current epoch instead.
 func (d *Dpos) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
 	snap, err := d.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
 	if err != nil {
@@ -1109,6 +1322,7 @@ func (d *Dpos) SealHash(header *types.Header) common.Hash {
 
 // Close implements consensus.Engine. It's a noop for dpos as there are no background threads.
 func (d *Dpos) Close() error {
+	d.scope.Close()
 	return nil
 }
 
@@ -1178,32 +1392,86 @@ func (d *Dpos) IsSysTransaction(sender common.Address, tx *types.Transaction, he
 	}
 
 	to := tx.To()
-	if sender == header.Coinbase && *to == systemcontract.SysGovToAddr && tx.GasPrice().Sign() == 0 {
+	if !d.isSystemSender(sender, header) {
+		return false, nil
+	}
+	if *to == systemcontract.SysGovToAddr && tx.GasPrice().Sign() == 0 {
+		return true, nil
+	}
+	if *to == systemcontract.DoubleSignEvidenceToAddr && tx.GasPrice().Sign() == 0 {
 		return true, nil
 	}
 	// Make sure the miner can NOT call the system contract through a normal transaction.
-	if sender == header.Coinbase && *to == systemcontract.SysGovContractAddr {
+	if *to == systemcontract.SysGovContractAddr {
 		return true, nil
 	}
 	return false, nil
 }
 
-// CanCreate determines where a given address can create a new contract.
+// isSystemSender returns whether the sender is allowed to send the system transactions
+// of the sealed header, which is its validator or, once the signing keys are enabled,
+// the key it's sealed with.
+func (d *Dpos) isSystemSender(sender common.Address, header *types.Header) bool {
+	if sender == header.Coinbase {
+		return true
+	}
+	if !d.config.IsSigningKeys(header.Number) {
+		return false
+	}
+	signer, err := ecrecover(header, d.signatures)
+	return err == nil && signer == sender
+}
+
+// CanCreate determines whether the caller can create a new contract within a
+// transaction sent by the origin. Depending on the verification mode, either the
+// caller, the origin, or the caller or the developer that directly deployed it
+// must be in the developer allowlist.
 //
 // This will query the system Developers contract, by DIRECTLY to get the target slot value of the contract,
 // it means that it's strongly relative to the layout of the Developers contract's state variables
-func (d *Dpos) CanCreate(state consensus.StateReader, addr common.Address, height *big.Int) bool {
+func (d *Dpos) CanCreate(state consensus.StateReader, origin, caller common.Address, height *big.Int) bool {
 	if d.chainConfig.IsRedCoast(height) && d.config.EnableDevVerification {
 		if isDeveloperVerificationEnabled(state) {
-			slot := calcSlotOfDevMappingKey(addr)
-			valueHash := state.GetState(systemcontract.AddressListContractAddr, slot)
-			// none zero value means true
-			return valueHash.Big().Sign() > 0
+			switch d.config.DevVerificationModeAt(height) {
+			case params.DevVerificationOrigin:
+				return isDeveloper(state, origin)
+			case params.DevVerificationFactory:
+				if isDeveloper(state, caller) {
+					return true
+				}
+				deployer := factoryDeployer(state, caller)
+				return deployer != (common.Address{}) && isDeveloper(state, deployer)
+			default:
+				return isDeveloper(state, caller)
+			}
 		}
 	}
 	return true
 }
 
+// ContractCreated records the developer a contract is directly deployed by in the
+// factory verification mode, so that the contract inherits its allowlisting for
+// the contracts it creates in turn. The contracts created by such a factory don't
+// inherit it. Removing the developer from the allowlist revokes the factories
+// deployed by it.
+//
+// The record is charged to the creation like a storage write.
+func (d *Dpos) ContractCreated(state consensus.StateReadWriter, caller, contract common.Address, height *big.Int) uint64 {
+	if !d.chainConfig.IsRedCoast(height) || !d.config.EnableDevVerification {
+		return 0
+	}
+	if d.config.DevVerificationModeAt(height) != params.DevVerificationFactory || !isDeveloper(state, caller) {
+		return 0
+	}
+	// An account without nonce, balance and code will be deleted as an empty one,
+	// so make sure the account holding the deployers is not empty.
+	if state.GetNonce(systemcontract.FactoryDeployersAddr) == 0 {
+		state.SetNonce(systemcontract.FactoryDeployersAddr, 1)
+	}
+	state.SetState(systemcontract.FactoryDeployersAddr, factoryDeployerSlot(contract), caller.Hash())
+	return params.SstoreSetGasEIP2200
+}
+
 // ValidateTx do a consensus-related validation on the given transaction at the given header and state.
 // the parentState must be the state of the header's parent block.
 func (d *Dpos) ValidateTx(sender common.Address, tx *types.Transaction, header *types.Header, parentState *state.StateDB) error {
@@ -1214,54 +1482,61 @@ func (d *Dpos) ValidateTx(sender common.Address, tx *types.Transaction, header *
 		if err != nil {
 			return err
 		}
-		if d, exist := m[sender]; exist && (d != DirectionTo) {
-			log.Trace("Hit blacklist", "tx", tx.Hash().String(), "addr", sender.String(), "direction", d)
-			return types.ErrAddressDenied
+		validator := &blacklistValidator{blacks: m, number: header.Number.Uint64()}
+		if err := validator.CheckAddress(sender, common.CheckFrom); err != nil {
+			log.Trace("Hit blacklist", "tx", tx.Hash().String(), "addr", sender.String(), "err", err)
+			return err
 		}
 		if to := tx.To(); to != nil {
-			if d, exist := m[*to]; exist && (d != DirectionFrom) {
-				log.Trace("Hit blacklist", "tx", tx.Hash().String(), "addr", to.String(), "direction", d)
-				return types.ErrAddressDenied
+			if err := validator.CheckAddress(*to, common.CheckTo); err != nil {
+				log.Trace("Hit blacklist", "tx", tx.Hash().String(), "addr", to.String(), "err", err)
+				return err
 			}
 		}
 	}
 	return nil
 }
 
-func (d *Dpos) getBlacklist(header *types.Header, parentState *state.StateDB) (map[common.Address]blacklistDirection, error) {
+// getBlacklist returns the blacklist in effect for the header, which is cached by
+// the parent hash. The entries of the address list contract are permanent and apply
+// to all the checks, the ones of the v2 contract are added after the fork.
+func (d *Dpos) getBlacklist(header *types.Header, parentState *state.StateDB) (blacklist, error) {
 	defer func(start time.Time) {
 		getblacklistTimer.UpdateSince(start)
 	}(time.Now())
 
 	if v, ok := d.blacklists.Get(header.ParentHash); ok {
-		return v.(map[common.Address]blacklistDirection), nil
+		return v.(*blacklistSnapshot).merged, nil
 	}
 
 	d.blLock.Lock()
 	defer d.blLock.Unlock()
 	if v, ok := d.blacklists.Get(header.ParentHash); ok {
-		return v.(map[common.Address]blacklistDirection), nil
-	}
-
-	// if the last updates is long ago, we don't need to get blacklist from the contract.
-	if d.chainConfig.SophonBlock != nil && header.Number.Cmp(d.chainConfig.SophonBlock) > 0 {
-		num := header.Number.Uint64()
-		lastUpdated := lastBlacklistUpdatedNumber(parentState)
-		if num >= 2 && num > lastUpdated+1 {
-			parent := d.chain.GetHeader(header.ParentHash, num-1)
-			if parent != nil {
-				if v, ok := d.blacklists.Get(parent.ParentHash); ok {
-					m := v.(map[common.Address]blacklistDirection)
-					d.blacklists.Add(header.ParentHash, m)
-					return m, nil
-				}
-			} else {
-				log.Error("Unexpected error when getBlacklist, can not get parent from chain", "number", num, "blockHash", header.Hash(), "parentHash", header.ParentHash)
+		return v.(*blacklistSnapshot).merged, nil
+	}
+
+	// If the blacklist of the parent is cached, apply the updates of the parent block
+	// to it instead of getting the whole blacklist from the contract, except for the
+	// periodic full reload checking the cache is consistent.
+	var incremental *blacklistSnapshot
+	num := header.Number.Uint64()
+	if d.chainConfig.SophonBlock != nil && header.Number.Cmp(d.chainConfig.SophonBlock) > 0 && num >= 2 {
+		parent := d.chain.GetHeader(header.ParentHash, num-1)
+		if parent != nil {
+			if v, ok := d.blacklists.Get(parent.ParentHash); ok {
+				incremental = d.updateBlacklist(v.(*blacklistSnapshot), header, parentState)
 			}
+		} else {
+			log.Error("Unexpected error when getBlacklist, can not get parent from chain", "number", num, "blockHash", header.Hash(), "parentHash", header.ParentHash)
+		}
+		if incremental != nil && num%blacklistReloadInterval != 0 {
+			d.blacklists.Add(header.ParentHash, incremental)
+			return incremental.merged, nil
 		}
 	}
 
 	// can't get blacklist from cache, try to call the contract
+	blacklistReloadMeter.Mark(1)
 	alABI := d.abi[systemcontract.AddressListContractName]
 	get := func(method string) ([]common.Address, error) {
 		ret, err := d.commonCallContract(header, parentState, alABI, systemcontract.AddressListContractAddr, method, 1)
@@ -1285,19 +1560,28 @@ func (d *Dpos) getBlacklist(header *types.Header, parentState *state.StateDB) (m
 		return nil, err
 	}
 
-	m := make(map[common.Address]blacklistDirection)
+	directions := make(map[common.Address]blacklistDirection)
 	for _, from := range froms {
-		m[from] = DirectionFrom
+		directions[from] = DirectionFrom
 	}
 	for _, to := range tos {
-		if _, exist := m[to]; exist {
-			m[to] = DirectionBoth
+		if _, exist := directions[to]; exist {
+			directions[to] = DirectionBoth
 		} else {
-			m[to] = DirectionTo
+			directions[to] = DirectionTo
 		}
 	}
-	d.blacklists.Add(header.ParentHash, m)
-	return m, nil
+	v2 := make(blacklist)
+	if d.config.IsBlacklistV2(header.Number) {
+		readBlacklistV2(parentState, v2)
+	}
+	snap := newBlacklistSnapshot(directions, v2)
+	if incremental != nil && !incremental.equal(snap) {
+		blacklistMismatchMeter.Mark(1)
+		log.Warn("Inconsistent blacklist cache, reloaded", "number", num, "parentHash", header.ParentHash)
+	}
+	d.blacklists.Add(header.ParentHash, snap)
+	return snap.merged, nil
 }
 
 func (d *Dpos) CreateEvmExtraValidator(header *types.Header, parentState *state.StateDB) types.EvmExtraValidator {
@@ -1315,6 +1599,7 @@ func (d *Dpos) CreateEvmExtraValidator(header *types.Header, parentState *state.
 		return &blacklistValidator{
 			blacks: blacks,
 			rules:  rules,
+			number: header.Number.Uint64(),
 		}
 	}
 	return nil
@@ -1335,23 +1620,28 @@ func (d *Dpos) getEventCheckRules(header *types.Header, parentState *state.State
 		return v.(map[common.Hash]*EventCheckRule), nil
 	}
 
-	// if the last updates is long ago, we don't need to get blacklist from the contract.
+	// If the rules of the parent are cached, apply the updates of the parent block to
+	// them instead of getting all the rules from the contract, except for the periodic
+	// full reload checking the cache is consistent.
+	var incremental map[common.Hash]*EventCheckRule
 	num := header.Number.Uint64()
-	lastUpdated := lastRulesUpdatedNumber(parentState)
-	if num >= 2 && num > lastUpdated+1 {
+	if num >= 2 {
 		parent := d.chain.GetHeader(header.ParentHash, num-1)
 		if parent != nil {
 			if v, ok := d.eventCheckRules.Get(parent.ParentHash); ok {
-				m := v.(map[common.Hash]*EventCheckRule)
-				d.eventCheckRules.Add(header.ParentHash, m)
-				return m, nil
+				incremental = d.updateEventCheckRules(v.(map[common.Hash]*EventCheckRule), header, parentState)
 			}
 		} else {
 			log.Error("Unexpected error when getEventCheckRules, can not get parent from chain", "number", num, "blockHash", header.Hash(), "parentHash", header.ParentHash)
 		}
+		if incremental != nil && num%blacklistReloadInterval != 0 {
+			d.eventCheckRules.Add(header.ParentHash, incremental)
+			return incremental, nil
+		}
 	}
 
 	// can't get blacklist from cache, try to call the contract
+	rulesReloadMeter.Mark(1)
 	alABI := d.abi[systemcontract.AddressListContractName]
 	method := "getRuleByIndex"
 	get := func(i uint32) (common.Hash, int, common.AddressCheckType, error) {
@@ -1389,6 +1679,10 @@ func (d *Dpos) getEventCheckRules(header *types.Header, parentState *state.State
 		rule.Checks[idx] = ct
 	}
 
+	if incremental != nil && !reflect.DeepEqual(incremental, rules) {
+		rulesMismatchMeter.Mark(1)
+		log.Warn("Inconsistent event check rules cache, reloaded", "number", num, "parentHash", header.ParentHash)
+	}
 	d.eventCheckRules.Add(header.ParentHash, rules)
 	return rules, nil
 }
@@ -1456,6 +1750,23 @@ func calcSlotOfDevMappingKey(addr common.Address) common.Hash {
 	return crypto.Keccak256Hash(addr.Hash().Bytes(), p)
 }
 
+// The developers the factories inherit the allowlisting of are not kept by the
+// AddressList contract, whose storage is laid out by its state variables above,
+// but by the engine in the storage of the dedicated FactoryDeployersAddr account:
+//
+//    slot: the factory address, left padded to 32 bytes
+//    value: the developer address, left padded to 32 bytes, zero if none
+func factoryDeployerSlot(factory common.Address) common.Hash {
+	return factory.Hash()
+}
+
+// factoryDeployer returns the developer the factory inherits the allowlisting of,
+// zero if it wasn't directly deployed by an allowlisted developer in the factory
+// mode.
+func factoryDeployer(state consensus.StateReader, factory common.Address) common.Address {
+	return common.BytesToAddress(state.GetState(systemcontract.FactoryDeployersAddr, factoryDeployerSlot(factory)).Bytes())
+}
+
 func lastBlacklistUpdatedNumber(state consensus.StateReader) uint64 {
 	value := state.GetState(systemcontract.AddressListContractAddr, systemcontract.BlackLastUpdatedNumberPosition)
 	return value.Big().Uint64()
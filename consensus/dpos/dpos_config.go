@@ -4,6 +4,11 @@ import (
 	"github.com/DxChainNetwork/dxc/common"
 )
 
+const (
+	// Percentage of the block rewards and fees going to the foundation
+	foundationRewardPercent = 5
+)
+
 var (
 	foundationAddress = common.HexToAddress("0x731cb03Ab9609e76c0C012aBeB234D2227323781")
 )
@@ -13,12 +13,38 @@ import (
 	"github.com/DxChainNetwork/dxc/core/state"
 	"github.com/DxChainNetwork/dxc/core/types"
 	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/crypto"
 	"github.com/DxChainNetwork/dxc/log"
 	"github.com/DxChainNetwork/dxc/rlp"
 	"math"
 	"math/big"
 )
 
+// System governance proposal actions. The state changing actions, from set code on,
+// are supported since the governance actions fork.
+const (
+	ProposalActionEvmCall       = 0 // Call the To contract from the From address with the proposal value and data
+	ProposalActionErase         = 1 // Erase the code and storage of the To address
+	ProposalActionSetCode       = 2 // Replace the code of the To address with the proposal data
+	ProposalActionSetStorage    = 3 // Set storage slots of the To address, the data is a list of 64 bytes slot-value pairs
+	ProposalActionAdjustBalance = 4 // Mint (data empty or 0x00) or burn (data 0x01) the proposal value at the To address
+	ProposalActionSetParam      = 5 // Set the whitelisted chain parameter named by the data to the proposal value, within its bounds
+)
+
+// Topics of the logs emitted by the state changing governance actions, from the
+// address of the governance contract.
+var (
+	codeSetTopic       = crypto.Keccak256Hash([]byte("CodeSet(address,bytes32)"))
+	storageSetTopic    = crypto.Keccak256Hash([]byte("StorageSet(address,bytes32,bytes32)"))
+	balanceMintedTopic = crypto.Keccak256Hash([]byte("BalanceMinted(address,uint256)"))
+	balanceBurnedTopic = crypto.Keccak256Hash([]byte("BalanceBurned(address,uint256)"))
+	paramSetTopic      = crypto.Keccak256Hash([]byte("ParamSet(string,uint256)"))
+)
+
+// errUnsupportedAction is returned if a proposal action isn't known, or not yet
+// activated at the block executing it.
+var errUnsupportedAction = errors.New("unsupported action")
+
 // Proposal is the system governance proposal info.
 type Proposal struct {
 	Id     *big.Int
@@ -123,19 +149,14 @@ func (d *Dpos) executeProposal(chain consensus.ChainHeaderReader, header *types.
 		return nil, nil, err
 	}
 	//make system governance transaction
-	nonce := state.GetNonce(d.validator)
-	amout := prop.Value
-	if d.chainConfig.IsSophon(header.Number) {
-		// fix bug
-		amout = new(big.Int)
-	}
-	tx := types.NewTransaction(nonce, systemcontract.SysGovToAddr, amout, header.GasLimit, new(big.Int), propRLP)
-	tx, err = d.signTxFn(accounts.Account{Address: d.validator}, tx, chain.Config().ChainID)
+	nonce := state.GetNonce(d.signingKey)
+	tx := types.NewTransaction(nonce, systemcontract.SysGovToAddr, d.proposalTxValue(header, prop), header.GasLimit, new(big.Int), propRLP)
+	tx, err = d.signTxFn(accounts.Account{Address: d.signingKey}, tx, chain.Config().ChainID)
 	if err != nil {
 		return nil, nil, err
 	}
 	//add nonce for validator
-	state.SetNonce(d.validator, nonce+1)
+	state.SetNonce(d.signingKey, nonce+1)
 	receipt := d.executeProposalMsg(chain, header, state, prop, totalTxIndex, tx.Hash(), common.Hash{})
 
 	return tx, receipt, nil
@@ -146,7 +167,7 @@ func (d *Dpos) replayProposal(chain consensus.ChainHeaderReader, header *types.H
 	if err != nil {
 		return nil, err
 	}
-	if sender != header.Coinbase {
+	if !d.isSystemSender(sender, header) {
 		return nil, errors.New("invalid sender for system governance transaction")
 	}
 	propRLP, err := rlp.EncodeToBytes(prop)
@@ -156,6 +177,16 @@ func (d *Dpos) replayProposal(chain consensus.ChainHeaderReader, header *types.H
 	if !bytes.Equal(propRLP, tx.Data()) {
 		return nil, fmt.Errorf("data missmatch, proposalID: %s, rlp: %s, txHash:%s, txData:%s", prop.Id.String(), hexutil.Encode(propRLP), tx.Hash().String(), hexutil.Encode(tx.Data()))
 	}
+	// The transactions of the state changing actions must be exactly the ones the
+	// validator makes, as the value of some actions is an actual amount of coins.
+	if isStateAction(prop.Action) && d.config.IsGovernanceActions(header.Number) {
+		if to := tx.To(); to == nil || *to != systemcontract.SysGovToAddr {
+			return nil, fmt.Errorf("invalid recipient for system governance transaction, proposalID: %s, txHash: %s", prop.Id.String(), tx.Hash().String())
+		}
+		if tx.Value().Cmp(d.proposalTxValue(header, prop)) != 0 {
+			return nil, fmt.Errorf("value missmatch, proposalID: %s, value: %s, txHash: %s, txValue: %s", prop.Id.String(), prop.Value.String(), tx.Hash().String(), tx.Value().String())
+		}
+	}
 	//make system governance transaction
 	nonce := state.GetNonce(sender)
 	//add nonce for validator
@@ -166,19 +197,43 @@ func (d *Dpos) replayProposal(chain consensus.ChainHeaderReader, header *types.H
 }
 
 func (d *Dpos) executeProposalMsg(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) *types.Receipt {
-	var receipt *types.Receipt
+	receipt, _, _ := d.applyProposalMsg(chain, header, state, prop, totalTxIndex, txHash, bHash, vm.Config{})
+	return receipt
+}
+
+// applyProposalMsg executes the system governance proposal with the given evm config,
+// returning the receipt along with the return data and the error of the execution.
+func (d *Dpos) applyProposalMsg(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash, vmConfig vm.Config) (*types.Receipt, []byte, error) {
+	var (
+		receipt *types.Receipt
+		ret     []byte
+		err     error
+	)
 	action := prop.Action.Uint64()
 	switch action {
 	case 0:
 		// evm action.
-		receipt = d.executeEvmCallProposal(chain, header, state, prop, totalTxIndex, txHash, bHash)
+		receipt, ret, err = d.executeEvmCallProposal(chain, header, state, prop, totalTxIndex, txHash, bHash, vmConfig)
 	case 1:
 		// delete code action
 		ok := state.Erase(prop.To)
 		receipt = types.NewReceipt([]byte{}, ok != true, header.GasUsed)
+		if !ok {
+			err = errors.New("erase failed")
+		}
 		log.Info("executeProposalMsg", "action", "erase", "id", prop.Id.String(), "to", prop.To, "txHash", txHash.String(), "success", ok)
 	default:
+		if isStateAction(prop.Action) && d.config.IsGovernanceActions(header.Number) {
+			state.Prepare(txHash, totalTxIndex)
+			err = d.applyStateAction(header.Number, state, prop)
+			receipt = types.NewReceipt([]byte{}, err != nil, header.GasUsed)
+			receipt.Logs = state.GetLogs(txHash, bHash)
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			log.Info("executeProposalMsg", "action", action, "id", prop.Id.String(), "to", prop.To, "value", prop.Value.String(), "data", hexutil.Encode(prop.Data), "txHash", txHash.String(), "err", err)
+			break
+		}
 		receipt = types.NewReceipt([]byte{}, true, header.GasUsed)
+		err = errUnsupportedAction
 		log.Warn("executeProposalMsg failed, unsupported action", "action", action, "id", prop.Id.String(), "from", prop.From, "to", prop.To, "value", prop.Value.String(), "data", hexutil.Encode(prop.Data), "txHash", txHash.String())
 	}
 
@@ -187,15 +242,15 @@ func (d *Dpos) executeProposalMsg(chain consensus.ChainHeaderReader, header *typ
 	receipt.BlockNumber = header.Number
 	receipt.TransactionIndex = uint(state.TxIndex())
 
-	return receipt
+	return receipt, ret, err
 }
 
-// the returned value should not nil.
-func (d *Dpos) executeEvmCallProposal(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) *types.Receipt {
+// the returned receipt should not nil.
+func (d *Dpos) executeEvmCallProposal(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash, vmConfig vm.Config) (*types.Receipt, []byte, error) {
 	// actually run the governance message
 	msg := vmcaller.NewLegacyMessage(prop.From, &prop.To, 0, prop.Value, header.GasLimit, new(big.Int), prop.Data, false)
 	state.Prepare(txHash, totalTxIndex)
-	_, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, d), d.chainConfig)
+	ret, err := vmcaller.ExecuteMsgWithConfig(msg, state, header, newChainContext(chain, d), d.chainConfig, vmConfig)
 
 	// governance message will not actually consumes gas
 	receipt := types.NewReceipt([]byte{}, err != nil, header.GasUsed)
@@ -205,7 +260,7 @@ func (d *Dpos) executeEvmCallProposal(chain consensus.ChainHeaderReader, header
 
 	log.Info("executeProposalMsg", "action", "evmCall", "id", prop.Id.String(), "from", prop.From, "to", prop.To, "value", prop.Value.String(), "data", hexutil.Encode(prop.Data), "txHash", txHash.String(), "err", err)
 
-	return receipt
+	return receipt, ret, err
 }
 
 // Methods for debug trace
@@ -213,6 +268,9 @@ func (d *Dpos) executeEvmCallProposal(chain consensus.ChainHeaderReader, header
 // ApplySysTx applies a system-transaction using a given evm,
 // the main purpose of this method is for tracing a system-transaction.
 func (d *Dpos) ApplySysTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender common.Address, tx *types.Transaction) (ret []byte, vmerr error, err error) {
+	if to := tx.To(); to != nil && *to == systemcontract.DoubleSignEvidenceToAddr {
+		return d.applyEvidenceSysTx(evm, state, txIndex, sender, tx)
+	}
 	var prop = &Proposal{}
 	if err = rlp.DecodeBytes(tx.Data(), prop); err != nil {
 		return
@@ -239,7 +297,122 @@ func (d *Dpos) ApplySysTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender
 		// delete code action
 		_ = state.Erase(prop.To)
 	default:
-		vmerr = errors.New("unsupported action")
+		if isStateAction(prop.Action) && d.config.IsGovernanceActions(evm.Context.BlockNumber) {
+			state.Prepare(tx.Hash(), txIndex)
+			vmerr = d.applyStateAction(evm.Context.BlockNumber, state, prop)
+			break
+		}
+		vmerr = errUnsupportedAction
 	}
 	return
 }
+
+// proposalTxValue returns the value of the system governance transaction executing
+// the proposal.
+func (d *Dpos) proposalTxValue(header *types.Header, prop *Proposal) *big.Int {
+	if d.chainConfig.IsSophon(header.Number) {
+		// fix bug
+		return new(big.Int)
+	}
+	return prop.Value
+}
+
+// isStateAction returns whether the action is a state changing governance action.
+func isStateAction(action *big.Int) bool {
+	return action.IsUint64() && action.Uint64() >= ProposalActionSetCode && action.Uint64() <= ProposalActionSetParam
+}
+
+// stateActionWrites returns the account and the storage slots the state changing
+// governance action writes to if it succeeds.
+func (d *Dpos) stateActionWrites(prop *Proposal) (common.Address, []common.Hash) {
+	switch prop.Action.Uint64() {
+	case ProposalActionSetStorage:
+		var slots []common.Hash
+		for i := 0; i+2*common.HashLength <= len(prop.Data); i += 2 * common.HashLength {
+			slots = append(slots, common.BytesToHash(prop.Data[i:i+common.HashLength]))
+		}
+		return prop.To, slots
+
+	case ProposalActionSetParam:
+		if param, ok := d.config.GovernableParam(string(prop.Data)); ok {
+			return param.Address, []common.Hash{param.Slot}
+		}
+	}
+	return prop.To, nil
+}
+
+// applyStateAction applies a state changing governance action and emits its logs.
+// The proposal is checked in whole before any change, a failed action leaves the
+// state untouched.
+func (d *Dpos) applyStateAction(number *big.Int, state *state.StateDB, prop *Proposal) error {
+	emit := func(data []byte, topics ...common.Hash) {
+		state.AddLog(&types.Log{
+			Address:     systemcontract.SysGovContractAddr,
+			Topics:      topics,
+			Data:        data,
+			BlockNumber: number.Uint64(),
+		})
+	}
+	value := common.BigToHash(prop.Value)
+
+	switch prop.Action.Uint64() {
+	case ProposalActionSetCode:
+		state.SetCode(prop.To, prop.Data)
+		emit(crypto.Keccak256(prop.Data), codeSetTopic, prop.To.Hash())
+
+	case ProposalActionSetStorage:
+		if len(prop.Data) == 0 || len(prop.Data)%(2*common.HashLength) != 0 {
+			return errors.New("invalid storage slots")
+		}
+		// The storage of an empty account would be dropped along with the account
+		if state.GetCodeSize(prop.To) == 0 {
+			return errors.New("storage target has no code")
+		}
+		for i := 0; i < len(prop.Data); i += 2 * common.HashLength {
+			slot := common.BytesToHash(prop.Data[i : i+common.HashLength])
+			value := common.BytesToHash(prop.Data[i+common.HashLength : i+2*common.HashLength])
+			state.SetState(prop.To, slot, value)
+			emit(value.Bytes(), storageSetTopic, prop.To.Hash(), slot)
+		}
+
+	case ProposalActionAdjustBalance:
+		if prop.Value.Sign() < 0 || prop.Value.BitLen() > 256 {
+			return errors.New("invalid amount")
+		}
+		switch {
+		case len(prop.Data) == 0 || bytes.Equal(prop.Data, []byte{0x00}):
+			state.AddBalance(prop.To, prop.Value)
+			emit(value.Bytes(), balanceMintedTopic, prop.To.Hash())
+		case bytes.Equal(prop.Data, []byte{0x01}):
+			if state.GetBalance(prop.To).Cmp(prop.Value) < 0 {
+				return errors.New("insufficient balance to burn")
+			}
+			state.SubBalance(prop.To, prop.Value)
+			emit(value.Bytes(), balanceBurnedTopic, prop.To.Hash())
+		default:
+			return errors.New("invalid balance adjustment direction")
+		}
+
+	case ProposalActionSetParam:
+		param, ok := d.config.GovernableParam(string(prop.Data))
+		if !ok {
+			return fmt.Errorf("chain parameter %q not governable", string(prop.Data))
+		}
+		if prop.Value.Sign() < 0 || prop.Value.BitLen() > 256 {
+			return errors.New("invalid parameter value")
+		}
+		if err := param.CheckValue(prop.Value); err != nil {
+			return err
+		}
+		if state.GetCodeSize(param.Address) == 0 {
+			return fmt.Errorf("chain parameter %q holder has no code", param.Name)
+		}
+		state.SetState(param.Address, param.Slot, value)
+		emit(value.Bytes(), paramSetTopic, crypto.Keccak256Hash(prop.Data))
+
+	default:
+		return errUnsupportedAction
+	}
+	state.Finalise(true)
+	return nil
+}
@@ -0,0 +1,77 @@
+package dpos
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestStateGovernanceActions(t *testing.T) {
+	statedb := newTestState()
+
+	var (
+		target = common.HexToAddress("0x1001")
+		param  = params.DposGovernableParam{Name: "minDeposit", Address: common.HexToAddress("0x1002"), Slot: common.Hash{0x05}, Max: big.NewInt(100)}
+	)
+	engine := &Dpos{
+		chainConfig: params.TestChainConfig,
+		config:      &params.DposConfig{GovernanceActionsBlock: big.NewInt(10), GovernableParams: []params.DposGovernableParam{param}},
+	}
+	execute := func(number int64, action int64, value int64, data []byte) *types.Receipt {
+		prop := &Proposal{Id: big.NewInt(1), Action: big.NewInt(action), To: target, Value: big.NewInt(value), Data: data}
+		receipt, _, _ := engine.applyProposalMsg(nil, &types.Header{Number: big.NewInt(number)}, statedb, prop, 0, common.Hash{0x01}, common.Hash{}, vm.Config{})
+		return receipt
+	}
+	// The state changing actions are unsupported before the fork
+	if receipt := execute(9, ProposalActionSetCode, 0, []byte{0x60, 0x01}); receipt.Status != types.ReceiptStatusFailed {
+		t.Fatalf("set code succeeded before the fork")
+	}
+	if receipt := execute(10, ProposalActionSetCode, 0, []byte{0x60, 0x01}); receipt.Status != types.ReceiptStatusSuccessful || len(receipt.Logs) != 1 {
+		t.Fatalf("set code failed")
+	}
+	if code := statedb.GetCode(target); !bytes.Equal(code, []byte{0x60, 0x01}) {
+		t.Errorf("code mismatch: have %x", code)
+	}
+	slots := append(common.Hash{0x01}.Bytes(), common.Hash{0xaa}.Bytes()...)
+	if receipt := execute(10, ProposalActionSetStorage, 0, slots); receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("set storage failed")
+	}
+	if value := statedb.GetState(target, common.Hash{0x01}); value != (common.Hash{0xaa}) {
+		t.Errorf("storage mismatch: have %x", value)
+	}
+	if receipt := execute(10, ProposalActionSetStorage, 0, slots[:40]); receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("malformed storage slots accepted")
+	}
+	execute(10, ProposalActionAdjustBalance, 100, nil)
+	execute(10, ProposalActionAdjustBalance, 30, []byte{0x01})
+	if balance := statedb.GetBalance(target); balance.Cmp(big.NewInt(70)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 70", balance)
+	}
+	if receipt := execute(10, ProposalActionAdjustBalance, 100, []byte{0x01}); receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("burnt more than the balance")
+	}
+	statedb.SetCode(param.Address, []byte{0x00})
+	if receipt := execute(10, ProposalActionSetParam, 42, []byte("minDeposit")); receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("set param failed")
+	}
+	if value := statedb.GetState(param.Address, param.Slot); value != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("param mismatch: have %x", value)
+	}
+	if receipt := execute(10, ProposalActionSetParam, 42, []byte("maxDeposit")); receipt.Status != types.ReceiptStatusFailed {
+		t.Errorf("non-whitelisted param updated")
+	}
+	// Parameters can't be set out of their bounds, nor zeroed by default
+	for _, value := range []int64{0, 101} {
+		if receipt := execute(10, ProposalActionSetParam, value, []byte("minDeposit")); receipt.Status != types.ReceiptStatusFailed {
+			t.Errorf("param set out of its bounds to %d", value)
+		}
+	}
+	if value := statedb.GetState(param.Address, param.Slot); value != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("param mismatch after rejected updates: have %x", value)
+	}
+}
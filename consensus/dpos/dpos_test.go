@@ -1,11 +1,57 @@
 package dpos
 
 import (
+	"math/big"
+	"testing"
+
 	"github.com/DxChainNetwork/dxc/common"
 	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
-	"testing"
+	"github.com/DxChainNetwork/dxc/core"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/core/vm/runtime"
+	"github.com/DxChainNetwork/dxc/ethdb"
+	"github.com/DxChainNetwork/dxc/params"
 )
 
+// newTestState creates an empty state backed by an in-memory database.
+func newTestState() *state.StateDB {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	return statedb
+}
+
+// callContract calls the method of the named system contract deployed at the
+// address in the given runtime configuration, failing the test if the call fails.
+func callContract(t *testing.T, cfg *runtime.Config, name string, addr common.Address, method string, args ...interface{}) []byte {
+	t.Helper()
+	input, err := systemcontract.GetInteractiveABI()[name].Pack(method, args...)
+	if err != nil {
+		t.Fatalf("%s: failed to pack input: %v", method, err)
+	}
+	ret, _, err := runtime.Call(addr, input, cfg)
+	if err != nil {
+		t.Fatalf("%s: call failed: %v", method, err)
+	}
+	return ret
+}
+
+// insertTestBlock writes a canonical head block with the given logs on top of
+// the parent, or a genesis block if there is none.
+func insertTestBlock(db ethdb.Database, parent *types.Header, extra byte, logs ...*types.Log) *types.Header {
+	header := &types.Header{Number: big.NewInt(0), Extra: []byte{extra}}
+	if parent != nil {
+		header.Number = new(big.Int).Add(parent.Number, common.Big1)
+		header.ParentHash = parent.Hash()
+	}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+	rawdb.WriteReceipts(db, header.Hash(), header.Number.Uint64(), types.Receipts{{Logs: logs}})
+	rawdb.WriteHeadBlockHash(db, header.Hash())
+	return header
+}
+
 func TestCalcSlotOfDevMappingKey(t *testing.T) {
 	addr := common.HexToAddress("0x5b38da6a701c568545dcfcb03fcb875f56beddc4")
 	slot := calcSlotOfDevMappingKey(addr)
@@ -18,3 +64,97 @@ func TestMigrateAddrBalances(t *testing.T) {
 	t.Log(addrs)
 	t.Log(bals)
 }
+
+func TestDevVerificationModes(t *testing.T) {
+	var (
+		dev      = common.HexToAddress("0x5001")
+		stranger = common.HexToAddress("0x5002")
+
+		// The factory creates an empty contract when called and stores its address,
+		// zero if the creation failed
+		factoryCode = common.FromHex("0x600b80600b6000396000f3" + "600060006000f060005500")
+	)
+	for _, mode := range []string{params.DevVerificationCaller, params.DevVerificationOrigin, params.DevVerificationFactory} {
+		engine := New(&params.ChainConfig{
+			ChainID:       big.NewInt(1),
+			RedCoastBlock: big.NewInt(0),
+			Dpos:          &params.DposConfig{Epoch: 10, EnableDevVerification: true, DevVerificationMode: mode, DevVerificationModeBlock: big.NewInt(0)},
+		}, rawdb.NewMemoryDatabase())
+
+		statedb := newTestState()
+		statedb.SetState(systemcontract.AddressListContractAddr, common.Hash{}, common.BytesToHash([]byte{0x01, 0x01}))
+		statedb.SetState(systemcontract.AddressListContractAddr, calcSlotOfDevMappingKey(dev), common.BigToHash(common.Big1))
+
+		// call runs the code of the contract in a transaction sent by the origin
+		call := func(origin, caller common.Address, to *common.Address, code []byte) (common.Address, error) {
+			blockContext := vm.BlockContext{
+				CanTransfer: core.CanTransfer,
+				Transfer:    core.Transfer,
+				BlockNumber: big.NewInt(1),
+				Difficulty:  new(big.Int),
+				GasLimit:    10000000,
+				CanCreate: func(db vm.StateDB, origin, caller common.Address, height *big.Int) bool {
+					return engine.CanCreate(db, origin, caller, height)
+				},
+				ContractCreated: func(db vm.StateDB, caller, contract common.Address, height *big.Int) uint64 {
+					return engine.ContractCreated(db, caller, contract, height)
+				},
+			}
+			evm := vm.NewEVM(blockContext, vm.TxContext{Origin: origin, GasPrice: new(big.Int)}, statedb, params.TestChainConfig, vm.Config{})
+			if to == nil {
+				_, addr, _, err := evm.Create(vm.AccountRef(caller), code, 1000000, new(big.Int))
+				return addr, err
+			}
+			_, _, err := evm.Call(vm.AccountRef(caller), *to, nil, 1000000, new(big.Int))
+			return common.BytesToAddress(statedb.GetState(*to, common.Hash{}).Bytes()), err
+		}
+		if _, err := call(stranger, stranger, nil, factoryCode); err != vm.ErrUnauthorizedDeveloper {
+			t.Fatalf("mode %s: error mismatch: have %v, want %v", mode, err, vm.ErrUnauthorizedDeveloper)
+		}
+		factory, err := call(dev, dev, nil, factoryCode)
+		if err != nil {
+			t.Fatalf("mode %s: failed to deploy factory: %v", mode, err)
+		}
+		// Only the factory mode lets anyone deploy through the factory of a developer,
+		// only the origin mode lets the developer deploy through any factory
+		created, _ := call(stranger, stranger, &factory, nil)
+		if have, want := created != (common.Address{}), mode == params.DevVerificationFactory; have != want {
+			t.Errorf("mode %s: creation through factory by stranger mismatch: have %t, want %t", mode, have, want)
+		}
+		created, _ = call(dev, dev, &factory, nil)
+		if have, want := created != (common.Address{}), mode != params.DevVerificationCaller; have != want {
+			t.Errorf("mode %s: creation through factory by developer mismatch: have %t, want %t", mode, have, want)
+		}
+		if mode != params.DevVerificationFactory {
+			if deployer := factoryDeployer(statedb, factory); deployer != (common.Address{}) {
+				t.Errorf("mode %s: factory deployer recorded: %v", mode, deployer)
+			}
+			continue
+		}
+		// The contracts created by the factory don't inherit the developer
+		if deployer := factoryDeployer(statedb, created); deployer != (common.Address{}) {
+			t.Errorf("nested factory deployer recorded: %v", deployer)
+		}
+		// The records are charged to the creations writing them
+		if cost := engine.ContractCreated(statedb, dev, common.HexToAddress("0x6001"), big.NewInt(1)); cost != params.SstoreSetGasEIP2200 {
+			t.Errorf("record cost mismatch: have %d, want %d", cost, params.SstoreSetGasEIP2200)
+		}
+		if cost := engine.ContractCreated(statedb, factory, common.HexToAddress("0x6002"), big.NewInt(1)); cost != 0 {
+			t.Errorf("unrecorded creation charged %d", cost)
+		}
+		nested, err := call(stranger, factory, nil, factoryCode)
+		if err != nil {
+			t.Fatalf("failed to deploy nested factory: %v", err)
+		}
+		if created, _ := call(stranger, stranger, &nested, nil); created != (common.Address{}) {
+			t.Errorf("creation through nested factory allowed")
+		}
+		if nonce := statedb.GetNonce(systemcontract.FactoryDeployersAddr); nonce != 1 {
+			t.Errorf("factory deployers holder may be deleted as empty, nonce %d", nonce)
+		}
+		statedb.SetState(systemcontract.AddressListContractAddr, calcSlotOfDevMappingKey(dev), common.Hash{})
+		if created, _ := call(stranger, stranger, &factory, nil); created != (common.Address{}) {
+			t.Errorf("creation through factory of removed developer allowed")
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package dpos
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/log"
+	"github.com/DxChainNetwork/dxc/rlp"
+)
+
+// EpochIndex is the staking state of the system contracts at an epoch checkpoint,
+// kept to answer the historical queries once the state is pruned.
+type EpochIndex struct {
+	Epoch        uint64
+	Info         systemcontract.EpochInfo // Block reward and stake of the epoch
+	TotalDeposit *big.Int
+	TotalVotes   *big.Int
+	Validators   []common.Address // Validators elected for the epoch
+	Effective    []common.Address // Effective validators, the candidates of the next election
+	Records      []EpochIndexRecord
+
+	// Block production of the previous epoch, closed by the checkpoint
+	Sealers []EpochIndexSealer `rlp:"optional"`
+	Seconds uint64             `rlp:"optional"` // Time elapsed during the previous epoch
+}
+
+// EpochIndexRecord is the staking state of a validator at an epoch checkpoint.
+type EpochIndexRecord struct {
+	Address   common.Address
+	Validator systemcontract.Validator
+	Reward    systemcontract.Reward // Rewards earned in the previous epoch
+}
+
+// EpochIndexSealer is the number of blocks a validator sealed in an epoch.
+type EpochIndexSealer struct {
+	Address common.Address
+	Blocks  uint64
+}
+
+// record returns the staking state of the given validator, if indexed.
+func (idx *EpochIndex) record(addr common.Address) (*EpochIndexRecord, bool) {
+	if idx == nil {
+		return nil, false
+	}
+	for i := range idx.Records {
+		if idx.Records[i].Address == addr {
+			return &idx.Records[i], true
+		}
+	}
+	return nil, false
+}
+
+const epochIndexRetries = 3 // Number of attempts to index a checkpoint whose state is available
+
+// epochIndexer follows the canonical chain to index its epoch checkpoints.
+type epochIndexer struct {
+	next    uint64              // Next checkpoint to index
+	retries map[common.Hash]int // Failed attempts of the checkpoints with available state
+}
+
+// StartEpochIndex starts indexing the staking state of the system contracts at
+// every epoch checkpoint becoming canonical, until the engine is closed. The
+// recent checkpoints whose state is still available are backfilled first, and
+// the ones replaced by a reorg are indexed again.
+func (d *Dpos) StartEpochIndex(chain consensus.ChainHeaderReader, feed chainHeadSubscriber) {
+	events := make(chan core.ChainHeadEvent, 16)
+	sub := d.scope.Track(feed.SubscribeChainHeadEvent(events))
+
+	go func() {
+		defer sub.Unsubscribe()
+
+		indexer := &epochIndexer{retries: make(map[common.Hash]int)}
+		if head := chain.CurrentHeader(); head != nil {
+			indexer.next = d.config.EpochStart(head.Number.Uint64()) + d.config.Epoch
+		}
+		d.updateEpochIndex(chain, indexer)
+		for {
+			select {
+			case <-events:
+				d.updateEpochIndex(chain, indexer)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// indexable returns whether the canonical checkpoint still has to be indexed: it's
+// neither indexed nor given up, and its state is available.
+func (d *Dpos) indexable(indexer *epochIndexer, checkpoint *types.Header) bool {
+	if len(rawdb.ReadDposEpochIndex(d.db, checkpoint.Number.Uint64(), checkpoint.Root)) > 0 {
+		return false
+	}
+	if indexer.retries[checkpoint.Hash()] >= epochIndexRetries {
+		return false
+	}
+	_, err := d.stateFn(checkpoint.Root)
+	return err == nil
+}
+
+// updateEpochIndex indexes the canonical checkpoints up to the head. It first steps
+// back over the checkpoints left to index, either replaced by a reorg or not yet
+// backfilled. A checkpoint failing is retried at the next head while its state is
+// available, and given up after a few attempts.
+func (d *Dpos) updateEpochIndex(chain consensus.ChainHeaderReader, indexer *epochIndexer) {
+	head := chain.CurrentHeader()
+	if head == nil {
+		return
+	}
+	for indexer.next > 1 {
+		prev := d.config.EpochStart(indexer.next - 1)
+		checkpoint := chain.GetHeaderByNumber(prev)
+		if prev == 0 || checkpoint == nil || !d.indexable(indexer, checkpoint) {
+			break
+		}
+		indexer.next = prev
+	}
+	for indexer.next <= head.Number.Uint64() {
+		checkpoint := chain.GetHeaderByNumber(indexer.next)
+		if checkpoint == nil {
+			return
+		}
+		if err := d.indexEpoch(chain, checkpoint); err != nil {
+			if _, serr := d.stateFn(checkpoint.Root); serr == nil {
+				if indexer.retries[checkpoint.Hash()]++; indexer.retries[checkpoint.Hash()] < epochIndexRetries {
+					log.Warn("Failed to index dpos epoch, retrying", "number", checkpoint.Number, "err", err)
+					return
+				}
+			}
+			log.Warn("Failed to index dpos epoch", "number", checkpoint.Number, "err", err)
+		} else {
+			delete(indexer.retries, checkpoint.Hash())
+		}
+		indexer.next += d.config.Epoch
+	}
+}
+
+// effectiveValidators returns all the effective validators.
+func (d *Dpos) effectiveValidators(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB) ([]common.Address, error) {
+	validators := systemcontract.NewValidators()
+	count, err := validators.EffictiveValsLength(statedb, header, newChainContext(chain, d), d.chainConfig)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		size = big.NewInt(50)
+		vals []common.Address
+	)
+	for page := int64(1); new(big.Int).Mul(big.NewInt(page-1), size).Cmp(count) < 0; page++ {
+		list, err := validators.GetEffictiveValidatorsWithPage(statedb, header, newChainContext(chain, d), d.chainConfig, big.NewInt(page), size)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, list...)
+	}
+	return vals, nil
+}
+
+// collectEpochIndex reads the staking state of the system contracts at the epoch
+// checkpoint.
+func (d *Dpos) collectEpochIndex(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB) (*EpochIndex, error) {
+	var (
+		chainContext  = newChainContext(chain, d)
+		validators    = systemcontract.NewValidators()
+		systemRewards = systemcontract.NewSystemRewards()
+		epoch         = d.config.EpochNumber(header.Number.Uint64())
+		err           error
+	)
+	index := &EpochIndex{Epoch: epoch}
+	info, err := systemRewards.GetEpochInfo(statedb, header, chainContext, d.chainConfig, new(big.Int).SetUint64(epoch))
+	if err != nil {
+		return nil, err
+	}
+	index.Info = *info
+	if index.TotalDeposit, err = validators.TotalDeposit(statedb, header, chainContext, d.chainConfig); err != nil {
+		return nil, err
+	}
+	if index.TotalVotes, err = systemcontract.NewNodeVotes().TotalVotes(statedb, header, chainContext, d.chainConfig); err != nil {
+		return nil, err
+	}
+	if index.Validators, err = validators.GetCurrentEpochValidators(statedb, header, chainContext, d.chainConfig); err != nil {
+		return nil, err
+	}
+	if index.Effective, err = d.effectiveValidators(chain, header, statedb); err != nil {
+		return nil, err
+	}
+	seen := make(map[common.Address]bool)
+	for _, addr := range append(append([]common.Address{}, index.Validators...), index.Effective...) {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+
+		val, err := validators.GetValidator(statedb, header, chainContext, d.chainConfig, addr)
+		if err != nil {
+			return nil, err
+		}
+		record := EpochIndexRecord{Address: addr, Validator: *val, Reward: systemcontract.Reward{ValidatorReward: new(big.Int), DelegatorsReward: new(big.Int)}}
+		if epoch > 0 {
+			reward, err := systemRewards.GetValRewardInfoByEpoch(statedb, header, chainContext, d.chainConfig, addr, new(big.Int).SetUint64(epoch-1))
+			if err != nil {
+				return nil, err
+			}
+			record.Reward = *reward
+		}
+		index.Records = append(index.Records, record)
+	}
+	if epoch > 0 {
+		if err := d.collectEpochSeal(chain, header, index); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// collectEpochSeal counts the blocks sealed by each validator in the epoch closed
+// by the checkpoint.
+func (d *Dpos) collectEpochSeal(chain consensus.ChainHeaderReader, checkpoint *types.Header, index *EpochIndex) error {
+	last := chain.GetHeader(checkpoint.ParentHash, checkpoint.Number.Uint64()-1)
+	if last == nil {
+		return errUnknownBlock
+	}
+	first, _ := d.epochBlocks(index.Epoch - 1)
+	budget := uint64(math.MaxUint64)
+	seal, err := d.sealEpoch(chain, first, last, &budget)
+	if err != nil {
+		return err
+	}
+	for validator, blocks := range seal.sealers {
+		index.Sealers = append(index.Sealers, EpochIndexSealer{Address: validator, Blocks: blocks})
+	}
+	sort.Slice(index.Sealers, func(i, j int) bool {
+		return bytes.Compare(index.Sealers[i].Address[:], index.Sealers[j].Address[:]) < 0
+	})
+	index.Seconds = seal.seconds
+	return nil
+}
+
+// indexEpoch stores the staking state at the epoch checkpoint, keyed by its state
+// root so that the side chains don't clash with the canonical one.
+func (d *Dpos) indexEpoch(chain consensus.ChainHeaderReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	if len(rawdb.ReadDposEpochIndex(d.db, number, header.Root)) > 0 {
+		return nil
+	}
+	statedb, err := d.stateFn(header.Root)
+	if err != nil {
+		return err
+	}
+	index, err := d.collectEpochIndex(chain, header, statedb)
+	if err != nil {
+		return err
+	}
+	blob, err := rlp.EncodeToBytes(index)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteDposEpochIndex(d.db, number, header.Root, blob)
+	log.Debug("Indexed dpos epoch", "epoch", index.Epoch, "number", number, "validators", len(index.Records), "size", len(blob))
+	return nil
+}
+
+// readEpochIndex retrieves the staking state indexed at the checkpoint of the
+// canonical epoch the given block belongs to.
+func (d *Dpos) readEpochIndex(chain consensus.ChainHeaderReader, number uint64) *EpochIndex {
+	checkpoint := chain.GetHeaderByNumber(d.config.EpochStart(number))
+	if checkpoint == nil {
+		return nil
+	}
+	blob := rawdb.ReadDposEpochIndex(d.db, checkpoint.Number.Uint64(), checkpoint.Root)
+	if len(blob) == 0 {
+		return nil
+	}
+	index := new(EpochIndex)
+	if err := rlp.DecodeBytes(blob, index); err != nil {
+		log.Error("Invalid dpos epoch index", "number", checkpoint.Number, "err", err)
+		return nil
+	}
+	return index
+}
@@ -0,0 +1,121 @@
+package dpos
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+	"github.com/DxChainNetwork/dxc/rlp"
+)
+
+// testHeaderChain is a canonical chain of headers indexed by number.
+type testHeaderChain []*types.Header
+
+func (c testHeaderChain) Config() *params.ChainConfig               { return params.TestChainConfig }
+func (c testHeaderChain) CurrentHeader() *types.Header              { return c[len(c)-1] }
+func (c testHeaderChain) GetHeaderByHash(common.Hash) *types.Header { return nil }
+func (c testHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.GetHeaderByNumber(number)
+}
+func (c testHeaderChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c)) {
+		return nil
+	}
+	return c[number]
+}
+
+func TestEpochIndex(t *testing.T) {
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+
+	chain := make(testHeaderChain, 25)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i)), Root: common.Hash{byte(i)}}
+	}
+	val := common.HexToAddress("0x1001")
+	index := &EpochIndex{
+		Epoch:        1,
+		Info:         systemcontract.EpochInfo{BlockReward: big.NewInt(1), Tvl: big.NewInt(2), ValidatorCount: big.NewInt(1), EffictiveValCount: big.NewInt(1)},
+		TotalDeposit: big.NewInt(100),
+		TotalVotes:   big.NewInt(200),
+		Validators:   []common.Address{val},
+		Records: []EpochIndexRecord{{
+			Address:   val,
+			Validator: systemcontract.Validator{Status: 4, Deposit: big.NewInt(100), Rate: 30, Name: "v", Votes: big.NewInt(200), UnstakeLockingEndBlock: new(big.Int), RateSettLockingEndBlock: new(big.Int)},
+			Reward:    systemcontract.Reward{ValidatorReward: big.NewInt(7), DelegatorsReward: big.NewInt(3), Rate: 30},
+		}},
+	}
+	blob, err := rlp.EncodeToBytes(index)
+	if err != nil {
+		t.Fatalf("failed to encode index: %v", err)
+	}
+	rawdb.WriteDposEpochIndex(engine.db, 10, chain[10].Root, blob)
+
+	// Every block of the epoch is answered by the checkpoint's index
+	for _, number := range []uint64{10, 15, 19} {
+		have := engine.readEpochIndex(chain, number)
+		if have == nil || have.Epoch != 1 || have.TotalVotes.Cmp(big.NewInt(200)) != 0 {
+			t.Fatalf("block %d: index mismatch: have %+v", number, have)
+		}
+		record, ok := have.record(val)
+		if !ok || record.Validator.Deposit.Cmp(big.NewInt(100)) != 0 || record.Reward.ValidatorReward.Cmp(big.NewInt(7)) != 0 {
+			t.Fatalf("block %d: record mismatch: have %+v", number, record)
+		}
+	}
+	if have := engine.readEpochIndex(chain, 20); have != nil {
+		t.Errorf("unindexed epoch answered: %+v", have)
+	}
+	// A reorged checkpoint doesn't match the index anymore
+	chain[10] = &types.Header{Number: big.NewInt(10), Root: common.Hash{0xff}}
+	if have := engine.readEpochIndex(chain, 15); have != nil {
+		t.Errorf("side chain index answered: %+v", have)
+	}
+}
+
+func TestUpdateEpochIndex(t *testing.T) {
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+
+	chain := make(testHeaderChain, 36)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i)), Root: common.Hash{byte(i)}, Difficulty: diffInTurn}
+	}
+	rawdb.WriteDposEpochIndex(engine.db, 10, chain[10].Root, []byte{0xc0})
+	rawdb.WriteDposEpochIndex(engine.db, 20, chain[20].Root, []byte{0xc0})
+
+	// Only the recent states are available, and lack the system contracts
+	available := map[common.Hash]bool{chain[30].Root: true}
+	engine.SetStateFn(func(root common.Hash) (*state.StateDB, error) {
+		if !available[root] {
+			return nil, errors.New("missing trie node")
+		}
+		return newTestState(), nil
+	})
+	// The unindexed checkpoint with available state is backfilled, and retried
+	indexer := &epochIndexer{next: 40, retries: make(map[common.Hash]int)}
+	for i := 1; i < epochIndexRetries; i++ {
+		engine.updateEpochIndex(chain, indexer)
+		if indexer.next != 30 || indexer.retries[chain[30].Hash()] != i {
+			t.Fatalf("attempt %d: indexer mismatch: have %d/%d, want 30/%d", i, indexer.next, indexer.retries[chain[30].Hash()], i)
+		}
+	}
+	// Until given up
+	engine.updateEpochIndex(chain, indexer)
+	engine.updateEpochIndex(chain, indexer)
+	if indexer.next != 40 {
+		t.Fatalf("failing checkpoint not given up: next %d", indexer.next)
+	}
+	// The checkpoints replaced by a reorg are indexed again
+	chain[20] = &types.Header{Number: big.NewInt(20), Root: common.Hash{0x20, 0x01}, Difficulty: diffInTurn}
+	chain[30] = &types.Header{Number: big.NewInt(30), Root: common.Hash{0x30, 0x01}, ParentHash: common.Hash{0x01}, Difficulty: diffInTurn}
+	available[chain[20].Root], available[chain[30].Root] = true, true
+
+	engine.updateEpochIndex(chain, indexer)
+	if indexer.next != 20 {
+		t.Errorf("reorged checkpoint not indexed again: next %d", indexer.next)
+	}
+}
@@ -0,0 +1,169 @@
+package dpos
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/vmcaller"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+)
+
+// EpochPreview is the predicted outcome of the next epoch transition, computed by
+// running the election against a copy of the head state.
+type EpochPreview struct {
+	Number     uint64 `json:"number"`     // Head block the preview is computed from
+	Election   uint64 `json:"election"`   // Block running the election, the last one of the epoch
+	Checkpoint uint64 `json:"checkpoint"` // First block of the next epoch, carrying the elected validators
+	Epoch      uint64 `json:"epoch"`      // Next epoch
+
+	Validators []common.Address `json:"validators"` // Predicted validators of the next epoch
+	Added      []common.Address `json:"added"`
+	Removed    []common.Address `json:"removed"`
+	Kickouts   []common.Address `json:"kickouts"` // Validators kicked out during the current epoch
+
+	// Random is set if there are more candidates than seats, the elected ones are
+	// then drawn from block hashes which aren't known yet.
+	Random bool `json:"random"`
+
+	EpochInfo *systemcontract.EpochInfo `json:"epochInfo"` // Block reward and stake of the next epoch
+	Rewards   []*ValidatorRewardPreview `json:"rewards"`
+	Proposals []*PendingProposal        `json:"proposals"`
+}
+
+// ValidatorRewardPreview is the reward a validator earned in the current epoch so
+// far, distributed at the transition, along with its rate in the next epoch.
+type ValidatorRewardPreview struct {
+	Validator        common.Address `json:"validator"`
+	ValidatorReward  *hexutil.Big   `json:"validatorReward"`
+	DelegatorsReward *hexutil.Big   `json:"delegatorsReward"`
+	Rate             uint8          `json:"rate"`
+	Elected          bool           `json:"elected"`
+	NextRate         uint8          `json:"nextRate,omitempty"`
+}
+
+// PendingProposal is a passed system governance proposal to be executed by the
+// next block, along with the outcome of its simulation.
+type PendingProposal struct {
+	*GovernanceProposal
+	Simulation *GovernanceSimulation `json:"simulation"`
+}
+
+// electionBlock returns the block electing the validators of the epoch following
+// the given block, and the checkpoint the elected validators take over at.
+func (d *Dpos) electionBlock(number uint64) (uint64, uint64) {
+	checkpoint, _ := d.epochBlocks(d.config.EpochNumber(number) + 1)
+	return checkpoint - 1, checkpoint
+}
+
+// PreviewNextEpoch runs the election of the next epoch on a copy of the head state
+// and reports its outcome. The given state is left untouched.
+func (d *Dpos) PreviewNextEpoch(chain consensus.ChainHeaderReader, head *types.Header, headState *state.StateDB) (*EpochPreview, error) {
+	number := head.Number.Uint64()
+	election, checkpoint := d.electionBlock(number)
+
+	preview := &EpochPreview{
+		Number:     number,
+		Election:   election,
+		Checkpoint: checkpoint,
+		Epoch:      d.config.EpochNumber(checkpoint),
+		Rewards:    []*ValidatorRewardPreview{},
+		Proposals:  []*PendingProposal{},
+	}
+	var (
+		chainContext  = newChainContext(chain, d)
+		systemRewards = systemcontract.NewSystemRewards()
+		epoch         = new(big.Int).SetUint64(d.config.EpochNumber(number))
+		next          = new(big.Int).SetUint64(preview.Epoch)
+	)
+	current, err := d.getCurEpochValidators(chain, head, headState.Copy())
+	if err != nil {
+		return nil, err
+	}
+	if preview.Kickouts, err = systemRewards.KickoutInfo(headState.Copy(), head, chainContext, d.chainConfig, epoch); err != nil {
+		return nil, err
+	}
+	// The election is triggered by the block reward of the last block of the epoch,
+	// unless the head is that block already.
+	header, post := head, headState.Copy()
+	if number < election {
+		header = &types.Header{
+			ParentHash: head.Hash(),
+			Number:     new(big.Int).SetUint64(election),
+			GasLimit:   head.GasLimit,
+			Time:       head.Time + (election-number)*d.config.PeriodAt(election),
+			Difficulty: new(big.Int).Set(diffInTurn),
+			Coinbase:   head.Coinbase,
+		}
+		data, err := d.abi[systemcontract.ValidatorsContractName].Pack("tryElect")
+		if err != nil {
+			return nil, err
+		}
+		msg := vmcaller.NewLegacyMessage(systemcontract.SystemRewardsContractAddr, &systemcontract.ValidatorsContractAddr, 0, new(big.Int), math.MaxUint64, new(big.Int), data, false)
+		if _, err := vmcaller.ExecuteMsg(msg, post, header, chainContext, d.chainConfig); err != nil {
+			return nil, err
+		}
+	}
+	if preview.Validators, err = d.getCurEpochValidators(chain, header, post); err != nil {
+		return nil, err
+	}
+	prev := make(map[common.Address]struct{}, len(current))
+	for _, val := range current {
+		prev[val] = struct{}{}
+	}
+	change := newValidatorSetChange(preview.Epoch, prev, preview.Validators)
+	preview.Added, preview.Removed = change.Added, change.Removed
+
+	if preview.EpochInfo, err = systemRewards.GetEpochInfo(post, header, chainContext, d.chainConfig, next); err != nil {
+		return nil, err
+	}
+	preview.Random = preview.EpochInfo.EffictiveValCount.Cmp(preview.EpochInfo.ValidatorCount) > 0
+
+	// The rewards of the current epoch are distributed to the validators sealing it
+	elected := make(map[common.Address]bool)
+	for _, val := range preview.Validators {
+		elected[val] = true
+	}
+	for _, val := range current {
+		reward, err := systemRewards.GetValRewardInfoByEpoch(headState.Copy(), head, chainContext, d.chainConfig, val, epoch)
+		if err != nil {
+			return nil, err
+		}
+		preview.Rewards = append(preview.Rewards, &ValidatorRewardPreview{
+			Validator:        val,
+			ValidatorReward:  (*hexutil.Big)(reward.ValidatorReward),
+			DelegatorsReward: (*hexutil.Big)(reward.DelegatorsReward),
+			Rate:             reward.Rate,
+			Elected:          elected[val],
+		})
+	}
+	for _, reward := range preview.Rewards {
+		if !reward.Elected {
+			continue
+		}
+		nextReward, err := systemRewards.GetValRewardInfoByEpoch(post, header, chainContext, d.chainConfig, reward.Validator, next)
+		if err != nil {
+			return nil, err
+		}
+		reward.NextRate = nextReward.Rate
+	}
+	// The passed governance proposals are executed by the next block
+	if d.chainConfig.IsRedCoast(new(big.Int).Add(head.Number, common.Big1)) {
+		props, err := d.passedProposals(chain, head, headState.Copy())
+		if err != nil {
+			return nil, err
+		}
+		for _, prop := range props {
+			sim, err := d.simulateProposal(chain, head, headState, prop)
+			if err != nil {
+				return nil, err
+			}
+			preview.Proposals = append(preview.Proposals, &PendingProposal{GovernanceProposal: newGovernanceProposal(prop), Simulation: sim})
+		}
+	}
+	return preview, nil
+}
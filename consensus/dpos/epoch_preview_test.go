@@ -0,0 +1,159 @@
+package dpos
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/vmcaller"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestPreviewElectionBlock(t *testing.T) {
+	engine := &Dpos{config: &params.DposConfig{Epoch: 10}}
+
+	tests := []struct {
+		number, election, checkpoint uint64
+	}{
+		{0, 9, 10},
+		{9, 9, 10},
+		{10, 19, 20},
+		{15, 19, 20},
+	}
+	for _, tt := range tests {
+		election, checkpoint := engine.electionBlock(tt.number)
+		if election != tt.election || checkpoint != tt.checkpoint {
+			t.Errorf("block %d: election mismatch: have %d/%d, want %d/%d", tt.number, election, checkpoint, tt.election, tt.checkpoint)
+		}
+	}
+}
+
+// newPreviewTestEngine creates an engine with the genesis system contracts of the
+// given validators initialized in the returned state. The epoch length matches the
+// one the contracts are built with.
+func newPreviewTestEngine(t *testing.T, validators []common.Address) (*Dpos, testHeaderChain, *state.StateDB) {
+	t.Helper()
+	config := *params.TestChainConfig
+	config.BerlinBlock, config.LondonBlock, config.RedCoastBlock = nil, nil, nil
+	config.Dpos = &params.DposConfig{Epoch: 14400, Period: 3}
+	engine := New(&config, rawdb.NewMemoryDatabase())
+
+	genesis := &types.Header{Number: big.NewInt(0), GasLimit: 8000000}
+	engine.recents.Add(genesis.Hash(), newSnapshot(engine.config, engine.signatures, 0, genesis.Hash(), validators, nil))
+
+	alloc, err := SystemContractsAlloc(config.Dpos)
+	if err != nil {
+		t.Fatalf("failed to build system contracts alloc: %v", err)
+	}
+	statedb := newTestState()
+	for addr, account := range alloc {
+		statedb.SetCode(addr, account.Code)
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+		statedb.SetBalance(addr, account.Balance)
+	}
+	for _, val := range validators {
+		statedb.SetBalance(val, systemcontract.InitDeposit)
+	}
+	header := &types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash(), Coinbase: validators[0], GasLimit: 8000000, Difficulty: diffInTurn}
+	chain := testHeaderChain{genesis}
+	if err := engine.initializeSystemContracts(chain, header, statedb); err != nil {
+		t.Fatalf("failed to initialize system contracts: %v", err)
+	}
+	return engine, chain, statedb
+}
+
+// previewTestCall executes a call of the named system contract on the state at
+// the given header, failing the test if it reverts.
+func previewTestCall(t *testing.T, engine *Dpos, chain testHeaderChain, header *types.Header, statedb *state.StateDB, from, to common.Address, value *big.Int, contract, method string, args ...interface{}) {
+	t.Helper()
+	data, err := engine.abi[contract].Pack(method, args...)
+	if err != nil {
+		t.Fatalf("%s: failed to pack input: %v", method, err)
+	}
+	msg := vmcaller.NewLegacyMessage(from, &to, statedb.GetNonce(from), value, math.MaxUint64, new(big.Int), data, false)
+	if _, err := vmcaller.ExecuteMsg(msg, statedb, header, newChainContext(chain, engine), engine.chainConfig); err != nil {
+		t.Fatalf("%s: call failed: %v", method, err)
+	}
+}
+
+func TestPreviewNextEpoch(t *testing.T) {
+	var (
+		a = common.HexToAddress("0x1001")
+		b = common.HexToAddress("0x1002")
+		c = common.HexToAddress("0x1003")
+		d = common.HexToAddress("0x1004")
+
+		voter = common.HexToAddress("0x2001")
+	)
+	engine, chain, statedb := newPreviewTestEngine(t, []common.Address{a, b, c})
+
+	head := &types.Header{Number: big.NewInt(100), ParentHash: common.Hash{0x01}, Coinbase: a, GasLimit: 8000000, Time: 300, Difficulty: diffInTurn}
+
+	// Add a candidate the way a passed validator proposal does
+	statedb.AddBalance(systemcontract.ValidatorProposalsContractAddr, systemcontract.InitDeposit)
+	previewTestCall(t, engine, chain, head, statedb, systemcontract.ValidatorProposalsContractAddr, systemcontract.ValidatorsContractAddr, systemcontract.InitDeposit,
+		systemcontract.ValidatorsContractName, "addValidatorFromProposal", d, systemcontract.InitDeposit, systemcontract.InitRate, "candidate", "")
+
+	// Unstaking a validator not sealing the epoch drops it from the candidates
+	// right away, the last candidate taking its place.
+	previewTestCall(t, engine, chain, head, statedb, b, systemcontract.ValidatorsContractAddr, new(big.Int), systemcontract.ValidatorsContractName, "unstake")
+
+	// Votes don't reorder the candidates while there are seats for all of them
+	statedb.AddBalance(voter, big.NewInt(params.Ether))
+	previewTestCall(t, engine, chain, head, statedb, voter, systemcontract.NodeVotesContractAddr, big.NewInt(params.Ether), systemcontract.NodeVotesContractName, "vote", c)
+
+	root := statedb.IntermediateRoot(false)
+	preview, err := engine.PreviewNextEpoch(chain, head, statedb)
+	if err != nil {
+		t.Fatalf("failed to preview next epoch: %v", err)
+	}
+	if statedb.IntermediateRoot(false) != root {
+		t.Errorf("preview modified the head state")
+	}
+	if preview.Election != 14399 || preview.Checkpoint != 14400 || preview.Epoch != 1 {
+		t.Errorf("election mismatch: have %d/%d/%d, want 14399/14400/1", preview.Election, preview.Checkpoint, preview.Epoch)
+	}
+	want := []common.Address{a, d, c}
+	if !reflect.DeepEqual(preview.Validators, want) {
+		t.Errorf("validators mismatch: have %x, want %x", preview.Validators, want)
+	}
+	if added := []common.Address{d, c}; !reflect.DeepEqual(preview.Added, added) {
+		t.Errorf("added validators mismatch: have %x, want %x", preview.Added, added)
+	}
+	if len(preview.Removed) != 0 {
+		t.Errorf("removed validators mismatch: have %x, want none", preview.Removed)
+	}
+	if preview.Random {
+		t.Errorf("election with free seats reported random")
+	}
+	if len(preview.Rewards) != 1 || preview.Rewards[0].Validator != a || !preview.Rewards[0].Elected {
+		t.Errorf("rewards mismatch: have %v", preview.Rewards)
+	}
+
+	// A head at the election block already carries the elected validators
+	election := &types.Header{Number: big.NewInt(14399), ParentHash: common.Hash{0x02}, Coinbase: a, GasLimit: 8000000, Time: 43197, Difficulty: diffInTurn}
+	previewTestCall(t, engine, chain, election, statedb, systemcontract.SystemRewardsContractAddr, systemcontract.ValidatorsContractAddr, new(big.Int), systemcontract.ValidatorsContractName, "tryElect")
+
+	preview, err = engine.PreviewNextEpoch(chain, election, statedb)
+	if err != nil {
+		t.Fatalf("failed to preview next epoch at the election block: %v", err)
+	}
+	if preview.Election != 14399 || preview.Epoch != 1 {
+		t.Errorf("election mismatch: have %d/%d, want 14399/1", preview.Election, preview.Epoch)
+	}
+	if !reflect.DeepEqual(preview.Validators, want) {
+		t.Errorf("validators mismatch: have %x, want %x", preview.Validators, want)
+	}
+	// Both sets are the elected one by then
+	if len(preview.Added) != 0 || len(preview.Removed) != 0 {
+		t.Errorf("set change mismatch: have +%x -%x, want none", preview.Added, preview.Removed)
+	}
+}
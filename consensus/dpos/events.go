@@ -0,0 +1,285 @@
+package dpos
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/event"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+const (
+	inmemoryEvents = 256 // Number of recent blocks to keep the events of until they become canonical
+)
+
+// Kinds of validator punishments reported by PunishmentEvent.
+const (
+	PunishMissedBlock = "missedBlock" // The in-turn validator didn't seal its block
+	PunishDoubleSign  = "doubleSign"  // The validator was slashed by a double sign evidence
+	PunishKickout     = "kickout"     // The validator was kicked out of the validator set
+)
+
+// ValidatorSetChangeEvent is posted when a checkpoint block changes the validator set.
+type ValidatorSetChangeEvent struct {
+	Number     uint64           `json:"number"`
+	Hash       common.Hash      `json:"hash"`
+	Epoch      uint64           `json:"epoch"`
+	Validators []common.Address `json:"validators"`
+	Added      []common.Address `json:"added"`
+	Removed    []common.Address `json:"removed"`
+	Reorged    bool             `json:"reorged,omitempty"` // Whether the block was reorged out of the canonical chain
+}
+
+// ProposalEvent is posted when a passed governance proposal is executed by a block.
+type ProposalEvent struct {
+	Number  uint64         `json:"number"`
+	Hash    common.Hash    `json:"hash"`
+	TxHash  common.Hash    `json:"txHash"`
+	Id      *hexutil.Big   `json:"id"`
+	Action  *hexutil.Big   `json:"action"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value"`
+	Data    hexutil.Bytes  `json:"data"`
+	Success bool           `json:"success"`
+	Reorged bool           `json:"reorged,omitempty"` // Whether the block was reorged out of the canonical chain
+}
+
+// PunishmentEvent is posted when a validator is punished by a block.
+type PunishmentEvent struct {
+	Number    uint64         `json:"number"`
+	Hash      common.Hash    `json:"hash"`
+	Epoch     uint64         `json:"epoch"`
+	Validator common.Address `json:"validator"`
+	Kind      string         `json:"kind"`
+	Reorged   bool           `json:"reorged,omitempty"` // Whether the block was reorged out of the canonical chain
+}
+
+// blockEvents collects the events caused by a block while it's being finalized.
+// They are only posted once the block becomes canonical, as blocks are finalized
+// whether they are valid or not, on side chains and again when traced.
+type blockEvents struct {
+	validatorSet *ValidatorSetChangeEvent
+	proposals    []*ProposalEvent
+	punishments  []*PunishmentEvent
+}
+
+func (e *blockEvents) empty() bool {
+	return e.validatorSet == nil && len(e.proposals) == 0 && len(e.punishments) == 0
+}
+
+// newValidatorSetChange diffs the validator set before a checkpoint against the one
+// carried by the checkpoint header.
+func newValidatorSetChange(epoch uint64, prev map[common.Address]struct{}, validators []common.Address) *ValidatorSetChangeEvent {
+	ev := &ValidatorSetChangeEvent{
+		Epoch:      epoch,
+		Validators: validators,
+		Added:      []common.Address{},
+		Removed:    []common.Address{},
+	}
+	next := make(map[common.Address]struct{}, len(validators))
+	for _, validator := range validators {
+		next[validator] = struct{}{}
+		if _, ok := prev[validator]; !ok {
+			ev.Added = append(ev.Added, validator)
+		}
+	}
+	for validator := range prev {
+		if _, ok := next[validator]; !ok {
+			ev.Removed = append(ev.Removed, validator)
+		}
+	}
+	sort.Sort(validatorsAscending(ev.Removed))
+	return ev
+}
+
+// changed reports whether the validator set is actually changed.
+func (ev *ValidatorSetChangeEvent) changed() bool {
+	return len(ev.Added) > 0 || len(ev.Removed) > 0
+}
+
+func (e *blockEvents) punish(epoch uint64, validator common.Address, kind string) {
+	e.punishments = append(e.punishments, &PunishmentEvent{Epoch: epoch, Validator: validator, Kind: kind})
+}
+
+func (e *blockEvents) proposal(prop *Proposal, tx *types.Transaction, receipt *types.Receipt) {
+	e.proposals = append(e.proposals, &ProposalEvent{
+		TxHash:  tx.Hash(),
+		Id:      (*hexutil.Big)(prop.Id),
+		Action:  (*hexutil.Big)(prop.Action),
+		From:    prop.From,
+		To:      prop.To,
+		Value:   (*hexutil.Big)(prop.Value),
+		Data:    prop.Data,
+		Success: receipt.Status == types.ReceiptStatusSuccessful,
+	})
+}
+
+// collectEpochEvents records the validator set change and the validators kicked out
+// during the last epoch at a checkpoint block.
+func (d *Dpos) collectEpochEvents(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, validators []common.Address, events *blockEvents) error {
+	number := header.Number.Uint64()
+	epoch := d.config.EpochNumber(number)
+
+	snap, err := d.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if ev := newValidatorSetChange(epoch, snap.Validators, validators); ev.changed() {
+		events.validatorSet = ev
+	}
+	if epoch == 0 {
+		return nil
+	}
+	kicked, err := systemcontract.NewSystemRewards().KickoutInfo(state, header, newChainContext(chain, d), d.chainConfig, new(big.Int).SetUint64(epoch-1))
+	if err != nil {
+		return err
+	}
+	for _, validator := range kicked {
+		events.punish(epoch-1, validator, PunishKickout)
+	}
+	return nil
+}
+
+// recordBlockEvents keeps the events of a finalized block until the block becomes
+// canonical. The header must be complete, as its hash identifies the block.
+func (d *Dpos) recordBlockEvents(header *types.Header, events *blockEvents) {
+	if !events.empty() {
+		d.blockEvents.Add(header.Hash(), events)
+	}
+}
+
+// recordValidatorSetChange keeps the validator set change of a checkpoint applied
+// onto a snapshot, unless the events of the block were recorded when finalizing it.
+func (d *Dpos) recordValidatorSetChange(change *ValidatorSetChangeEvent) {
+	d.blockEvents.ContainsOrAdd(change.Hash, &blockEvents{validatorSet: change})
+}
+
+// stashMinedEvents keeps the events of a locally assembled block until the block
+// is sealed and becomes canonical, as such blocks are never finalized again. The
+// events are keyed by the seal hash, the hash of the block being unknown yet.
+func (d *Dpos) stashMinedEvents(header *types.Header, events *blockEvents) {
+	if !events.empty() {
+		d.blockEvents.Add(SealHash(header), events)
+	}
+}
+
+// StartBlockEvents starts posting the events of the blocks becoming canonical, and
+// again flagged as reorged for the blocks leaving the canonical chain, until the
+// engine is closed.
+func (d *Dpos) StartBlockEvents(feed chainHeadSubscriber) {
+	events := make(chan core.ChainHeadEvent, 16)
+	sub := d.scope.Track(feed.SubscribeChainHeadEvent(events))
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-events:
+				d.updateBlockEvents()
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// updateBlockEvents posts the events of the blocks between the last posted head and
+// the canonical head, first unwinding the posted blocks which were reorged out.
+func (d *Dpos) updateBlockEvents() {
+	head := rawdb.ReadHeadBlockHash(d.db)
+	headNumber := rawdb.ReadHeaderNumber(d.db, head)
+	if headNumber == nil {
+		return
+	}
+	number, hash := d.eventsHead.number, d.eventsHead.hash
+	if hash == (common.Hash{}) {
+		// Nothing posted yet, start following the chain from its head
+		d.eventsHead.number, d.eventsHead.hash = *headNumber, head
+		return
+	}
+	for hash != rawdb.ReadCanonicalHash(d.db, number) {
+		header := rawdb.ReadHeader(d.db, hash, number)
+		if header == nil || number == 0 {
+			break
+		}
+		d.postBlockEvents(header, true)
+		number, hash = number-1, header.ParentHash
+	}
+	// The events of the blocks too far behind are not kept anymore
+	if *headNumber > number+inmemoryEvents {
+		number = *headNumber - inmemoryEvents
+		hash = rawdb.ReadCanonicalHash(d.db, number)
+	}
+	for number < *headNumber {
+		next := rawdb.ReadCanonicalHash(d.db, number+1)
+		header := rawdb.ReadHeader(d.db, next, number+1)
+		if header == nil || header.ParentHash != hash {
+			// Reorged meanwhile, the next update unwinds it
+			break
+		}
+		d.postBlockEvents(header, false)
+		number, hash = number+1, next
+	}
+	d.eventsHead.number, d.eventsHead.hash = number, hash
+}
+
+// postBlockEvents sends the recorded events of the block to the subscribers,
+// flagged as reorged if the block left the canonical chain.
+func (d *Dpos) postBlockEvents(header *types.Header, reorged bool) {
+	number, hash := header.Number.Uint64(), header.Hash()
+
+	v, ok := d.blockEvents.Get(hash)
+	if !ok {
+		if len(header.Extra) < extraSeal {
+			return
+		}
+		if v, ok = d.blockEvents.Get(SealHash(header)); !ok {
+			return
+		}
+		// Key the events of the locally sealed block by its hash from now on
+		d.blockEvents.Remove(SealHash(header))
+		d.blockEvents.Add(hash, v)
+	}
+	events := v.(*blockEvents)
+
+	if events.validatorSet != nil {
+		ev := *events.validatorSet
+		ev.Number, ev.Hash, ev.Reorged = number, hash, reorged
+		log.Debug("Dpos validator set changed", "number", ev.Number, "added", len(ev.Added), "removed", len(ev.Removed), "reorged", reorged)
+		d.validatorSetFeed.Send(&ev)
+	}
+	for _, proposal := range events.proposals {
+		ev := *proposal
+		ev.Number, ev.Hash, ev.Reorged = number, hash, reorged
+		d.proposalFeed.Send(&ev)
+	}
+	for _, punishment := range events.punishments {
+		ev := *punishment
+		ev.Number, ev.Hash, ev.Reorged = number, hash, reorged
+		d.punishFeed.Send(&ev)
+	}
+}
+
+// SubscribeValidatorSetChanges registers a subscription of ValidatorSetChangeEvent.
+func (d *Dpos) SubscribeValidatorSetChanges(ch chan<- *ValidatorSetChangeEvent) event.Subscription {
+	return d.scope.Track(d.validatorSetFeed.Subscribe(ch))
+}
+
+// SubscribeProposals registers a subscription of ProposalEvent.
+func (d *Dpos) SubscribeProposals(ch chan<- *ProposalEvent) event.Subscription {
+	return d.scope.Track(d.proposalFeed.Subscribe(ch))
+}
+
+// SubscribePunishments registers a subscription of PunishmentEvent.
+func (d *Dpos) SubscribePunishments(ch chan<- *PunishmentEvent) event.Subscription {
+	return d.scope.Track(d.punishFeed.Subscribe(ch))
+}
@@ -0,0 +1,62 @@
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestValidatorSetChangeEvents(t *testing.T) {
+	prev := map[common.Address]struct{}{{0x01}: {}, {0x02}: {}, {0x03}: {}}
+	ev := newValidatorSetChange(5, prev, []common.Address{{0x02}, {0x03}, {0x04}})
+	if len(ev.Added) != 1 || ev.Added[0] != (common.Address{0x04}) {
+		t.Errorf("added validators mismatch: have %x", ev.Added)
+	}
+	if len(ev.Removed) != 1 || ev.Removed[0] != (common.Address{0x01}) {
+		t.Errorf("removed validators mismatch: have %x", ev.Removed)
+	}
+	db := rawdb.NewMemoryDatabase()
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, db)
+
+	changes := make(chan *ValidatorSetChangeEvent, 4)
+	sub := engine.SubscribeValidatorSetChanges(changes)
+	defer sub.Unsubscribe()
+
+	genesis := insertTestBlock(db, nil, 0)
+	engine.updateBlockEvents()
+
+	// Events of finalized blocks are only posted once the blocks become canonical
+	parent := insertTestBlock(db, genesis, 0)
+	side := insertTestBlock(db, parent, 1)
+	engine.recordBlockEvents(side, &blockEvents{validatorSet: ev})
+	engine.recordValidatorSetChange(&ValidatorSetChangeEvent{Hash: side.Hash()})
+
+	main := insertTestBlock(db, parent, 0)
+	engine.updateBlockEvents()
+	if len(changes) != 0 {
+		t.Fatalf("side chain validator set change posted")
+	}
+	// Reorging in posts the events once, reorging out posts them again flagged
+	rawdb.WriteCanonicalHash(db, side.Hash(), 2)
+	rawdb.WriteHeadBlockHash(db, side.Hash())
+	engine.updateBlockEvents()
+	engine.updateBlockEvents()
+	if len(changes) != 1 {
+		t.Fatalf("validator set change posted %d times", len(changes))
+	}
+	if have := <-changes; have.Number != 2 || have.Hash != side.Hash() || have.Reorged || len(have.Added) != 1 {
+		t.Errorf("event mismatch: have %d/%x/%v/%x", have.Number, have.Hash, have.Reorged, have.Added)
+	}
+	rawdb.WriteCanonicalHash(db, main.Hash(), 2)
+	insertTestBlock(db, main, 0)
+	engine.updateBlockEvents()
+	if len(changes) != 1 {
+		t.Fatalf("reorged validator set change posted %d times", len(changes))
+	}
+	if have := <-changes; have.Hash != side.Hash() || !have.Reorged {
+		t.Errorf("reorged event mismatch: have %x/%v", have.Hash, have.Reorged)
+	}
+}
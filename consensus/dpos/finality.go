@@ -0,0 +1,246 @@
+package dpos
+
+import (
+	"errors"
+
+	"github.com/DxChainNetwork/dxc/accounts"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/log"
+	"github.com/DxChainNetwork/dxc/rlp"
+)
+
+const (
+	inmemoryAttestations = 1024 // Number of recent blocks to collect attestations for
+)
+
+var (
+	// attestationPrefix separates the attestation signing domain from the header sealing one.
+	attestationPrefix = []byte("dpos-attestation")
+
+	// errInvalidAttestation is returned if an attestation signature can't be recovered.
+	errInvalidAttestation = errors.New("invalid attestation")
+
+	// errUnknownAttestationBlock is returned if an attestation refers to a block
+	// which is not known locally.
+	errUnknownAttestationBlock = errors.New("attestation for unknown block")
+
+	// errUnauthorizedAttester is returned if an attestation is signed by a party
+	// that is not a validator of the attested block's epoch.
+	errUnauthorizedAttester = errors.New("unauthorized attester")
+
+	// errFinalizedReorg is returned if a header forks off the chain below the
+	// latest finalized block.
+	errFinalizedReorg = errors.New("reorg below the finalized block")
+)
+
+// Attestation is a vote from a validator claiming that a block is part of the
+// canonical chain. Once more than 2/3 of the validators of the block's epoch
+// attested a block, the block and all of its ancestors become final.
+type Attestation struct {
+	Number    uint64      `json:"number"`
+	Hash      common.Hash `json:"hash"`
+	Signature []byte      `json:"signature"`
+}
+
+// attestationRLP returns the data to be signed by an attester.
+func attestationRLP(number uint64, hash common.Hash) []byte {
+	enc, err := rlp.EncodeToBytes([]interface{}{attestationPrefix, number, hash})
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return enc
+}
+
+// ID returns the unique identifier of the attestation, used to filter duplicates.
+func (a *Attestation) ID() common.Hash {
+	return crypto.Keccak256Hash(attestationRLP(a.Number, a.Hash), a.Signature)
+}
+
+// Recover extracts the address of the attester.
+func (a *Attestation) Recover() (common.Address, error) {
+	if len(a.Signature) != crypto.SignatureLength {
+		return common.Address{}, errInvalidAttestation
+	}
+	pubkey, err := crypto.Ecrecover(crypto.Keccak256(attestationRLP(a.Number, a.Hash)), a.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// Attest signs an attestation for the given header if the local validator is part
+// of the validator set of the header's epoch. A nil attestation is returned if the
+// node is not allowed to attest.
+func (d *Dpos) Attest(chain consensus.ChainHeaderReader, header *types.Header) (*Attestation, error) {
+	d.lock.RLock()
+	val, key, signFn := d.validator, d.signingKey, d.signFn
+	d.lock.RUnlock()
+
+	if signFn == nil || header.Number.Uint64() == 0 {
+		return nil, nil
+	}
+	snap, err := d.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := snap.Validators[val]; !ok || snap.signerOf(val) != key {
+		return nil, nil
+	}
+	number, hash := header.Number.Uint64(), header.Hash()
+	sig, err := signFn(accounts.Account{Address: key}, accounts.MimetypeDpos, attestationRLP(number, hash))
+	if err != nil {
+		return nil, err
+	}
+	att := &Attestation{Number: number, Hash: hash, Signature: sig}
+	if _, err := d.AddAttestation(chain, att); err != nil {
+		return nil, err
+	}
+	return att, nil
+}
+
+// AddAttestation verifies an attestation against the validator set of the attested
+// block's epoch and adds it into the pool. If the block collects attestations from
+// more than 2/3 of the validators, it's recorded as the latest finalized block.
+// The returned flag reports whether the attestation was not seen before, so that
+// the caller knows whether to relay it.
+func (d *Dpos) AddAttestation(chain consensus.ChainHeaderReader, att *Attestation) (bool, error) {
+	if finalized := d.FinalizedHeader(chain); finalized != nil && att.Number <= finalized.Number.Uint64() {
+		return false, nil
+	}
+	header := chain.GetHeader(att.Hash, att.Number)
+	if header == nil || att.Number == 0 {
+		return false, errUnknownAttestationBlock
+	}
+	signer, err := att.Recover()
+	if err != nil {
+		return false, err
+	}
+	snap, err := d.snapshot(chain, att.Number-1, header.ParentHash, nil)
+	if err != nil {
+		return false, err
+	}
+	attester := snap.validatorOf(signer)
+	if _, ok := snap.Validators[attester]; !ok {
+		return false, errUnauthorizedAttester
+	}
+
+	d.attLock.Lock()
+	defer d.attLock.Unlock()
+
+	var votes map[common.Address]*Attestation
+	if v, ok := d.attestations.Get(att.Hash); ok {
+		votes = v.(map[common.Address]*Attestation)
+	} else {
+		votes = make(map[common.Address]*Attestation)
+		d.attestations.Add(att.Hash, votes)
+	}
+	if _, ok := votes[attester]; ok {
+		return false, nil
+	}
+	votes[attester] = att
+
+	if len(votes)*3 > len(snap.Validators)*2 {
+		d.finalize(chain, header)
+	}
+	return true, nil
+}
+
+// finalize records the header as the latest finalized block if it's canonical and
+// higher than the current finalized one, persisting the marker. It's the only place
+// the marker is written, the caller must hold attLock.
+func (d *Dpos) finalize(chain consensus.ChainHeaderReader, header *types.Header) {
+	d.finalLock.Lock()
+	defer d.finalLock.Unlock()
+
+	number := header.Number.Uint64()
+	if finalized := d.finalizedHeader(chain); finalized != nil && number <= finalized.Number.Uint64() {
+		return
+	}
+	if canonical := chain.GetHeaderByNumber(number); canonical == nil || canonical.Hash() != header.Hash() {
+		return
+	}
+	rawdb.WriteFinalizedBlockHash(d.db, header.Hash())
+	d.finalized.Store(header)
+
+	log.Info("Block finalized by attestations", "number", number, "hash", header.Hash())
+}
+
+// FinalizedHeader returns the latest block finalized by validator attestations, or
+// nil if no block has been finalized yet.
+func (d *Dpos) FinalizedHeader(chain consensus.ChainHeaderReader) *types.Header {
+	d.finalLock.Lock()
+	defer d.finalLock.Unlock()
+
+	return d.finalizedHeader(chain)
+}
+
+// finalizedHeader returns the latest finalized block, loading the marker from the
+// database on first use. The chain can't be reorged below it, so the marker is
+// never rewound. The caller must hold finalLock.
+func (d *Dpos) finalizedHeader(chain consensus.ChainHeaderReader) *types.Header {
+	if header, _ := d.finalized.Load().(*types.Header); header != nil {
+		return header
+	}
+	hash := rawdb.ReadFinalizedBlockHash(d.db)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	number := rawdb.ReadHeaderNumber(d.db, hash)
+	if number == nil {
+		return nil
+	}
+	header := chain.GetHeader(hash, *number)
+	if header != nil {
+		d.finalized.Store(header)
+	}
+	return header
+}
+
+// verifyFinality checks that the header doesn't fork off the chain below the latest
+// finalized block. The headers above it extending a batch of verified parents are
+// valid, any other one is traced back to the canonical chain.
+func (d *Dpos) verifyFinality(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header) error {
+	finalized := d.FinalizedHeader(chain)
+	if finalized == nil {
+		return nil
+	}
+	limit := finalized.Number.Uint64()
+	if header.Number.Uint64() > limit && len(parents) > 0 {
+		return nil
+	}
+	for {
+		number := header.Number.Uint64()
+		if canonical := chain.GetHeaderByNumber(number); canonical != nil && canonical.Hash() == header.Hash() {
+			return nil
+		}
+		if number <= limit {
+			return errFinalizedReorg
+		}
+		if header = chain.GetHeader(header.ParentHash, number-1); header == nil {
+			return consensus.ErrUnknownAncestor
+		}
+	}
+}
+
+// Attestations returns the attestations collected for the given block.
+func (d *Dpos) Attestations(hash common.Hash) []*Attestation {
+	d.attLock.Lock()
+	defer d.attLock.Unlock()
+
+	v, ok := d.attestations.Get(hash)
+	if !ok {
+		return nil
+	}
+	votes := v.(map[common.Address]*Attestation)
+	atts := make([]*Attestation, 0, len(votes))
+	for _, att := range votes {
+		atts = append(atts, att)
+	}
+	return atts
+}
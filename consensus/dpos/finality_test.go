@@ -0,0 +1,109 @@
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestAttestationRecover(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	hash := common.HexToHash("0x01")
+	sig, err := crypto.Sign(crypto.Keccak256(attestationRLP(10, hash)), key)
+	if err != nil {
+		t.Fatalf("failed to sign attestation: %v", err)
+	}
+	att := &Attestation{Number: 10, Hash: hash, Signature: sig}
+	if attester, err := att.Recover(); err != nil || attester != signer {
+		t.Fatalf("attester mismatch: have %x/%v, want %x", attester, err, signer)
+	}
+	// An attestation must not be replayable for another block
+	forged := &Attestation{Number: 11, Hash: hash, Signature: sig}
+	if attester, err := forged.Recover(); err == nil && attester == signer {
+		t.Fatalf("forged attestation recovered the attester")
+	}
+	if att.ID() == forged.ID() {
+		t.Fatalf("attestation id collision")
+	}
+}
+
+func TestFinalizedReorg(t *testing.T) {
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+
+	chain := make(testHeaderChain, 10)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i))}
+		if i > 0 {
+			chain[i].ParentHash = chain[i-1].Hash()
+		}
+	}
+	engine.finalize(chain, chain[6])
+	if have := engine.FinalizedHeader(chain); have == nil || have.Hash() != chain[6].Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want %d", have, 6)
+	}
+	// Reading the marker never rewinds it, even if the canonical chain disagrees
+	reorged := append(testHeaderChain{}, chain...)
+	reorged[6] = &types.Header{Number: big.NewInt(6), ParentHash: chain[5].Hash(), Extra: []byte{0x01}}
+	if have := engine.FinalizedHeader(reorged); have == nil || have.Hash() != chain[6].Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want %d", have, 6)
+	}
+	if hash := rawdb.ReadFinalizedBlockHash(engine.db); hash != chain[6].Hash() {
+		t.Fatalf("finalized marker rewound: have %x, want %x", hash, chain[6].Hash())
+	}
+
+	// The chain may fork off above the finalized block only
+	fork := &types.Header{Number: big.NewInt(7), ParentHash: chain[6].Hash(), Extra: []byte{0x01}}
+	if err := engine.verifyFinality(chain, fork, nil); err != nil {
+		t.Errorf("fork above the finalized block rejected: %v", err)
+	}
+	reorg := &types.Header{Number: big.NewInt(6), ParentHash: chain[5].Hash(), Extra: []byte{0x01}}
+	if err := engine.verifyFinality(chain, reorg, nil); err != errFinalizedReorg {
+		t.Errorf("error mismatch: have %v, want %v", err, errFinalizedReorg)
+	}
+	if err := engine.verifyFinality(chain, chain[6], nil); err != nil {
+		t.Errorf("finalized block rejected: %v", err)
+	}
+	// Side chains forking off below the finalized block are rejected at any height,
+	// unless extending a batch of verified headers
+	side := testHeaderChain{chain[5], reorg}
+	for i := 7; i < 12; i++ {
+		side = append(side, &types.Header{Number: big.NewInt(int64(i)), ParentHash: side[len(side)-1].Hash(), Extra: []byte{0x01}})
+	}
+	known := &sideHeaderChain{testHeaderChain: chain, side: side}
+	if err := engine.verifyFinality(known, side[len(side)-1], nil); err != errFinalizedReorg {
+		t.Errorf("error mismatch: have %v, want %v", err, errFinalizedReorg)
+	}
+	if err := engine.verifyFinality(known, side[len(side)-1], side[2:len(side)-1]); err != nil {
+		t.Errorf("header extending verified parents rejected: %v", err)
+	}
+	// Side chain blocks are never finalized
+	engine.finalize(chain, fork)
+	if have := engine.FinalizedHeader(chain); have.Hash() != chain[6].Hash() {
+		t.Errorf("side chain block finalized: %d", have.Number)
+	}
+	if hash := rawdb.ReadFinalizedBlockHash(engine.db); hash != chain[6].Hash() {
+		t.Errorf("finalized marker mismatch: have %x, want %x", hash, chain[6].Hash())
+	}
+}
+
+// sideHeaderChain is a test chain also serving the headers of a side chain by hash.
+type sideHeaderChain struct {
+	testHeaderChain
+	side []*types.Header
+}
+
+func (c *sideHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	for _, header := range append(c.testHeaderChain, c.side...) {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
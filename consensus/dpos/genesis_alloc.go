@@ -0,0 +1,47 @@
+package dpos
+
+import (
+	"encoding/json"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+// SystemContractsAlloc returns the genesis allocation of the system contracts. The
+// built-in contracts of GenesisAlloc are relocated or replaced as configured by the
+// system contract overrides of the config, which may be nil.
+func SystemContractsAlloc(config *params.DposConfig) (core.GenesisAlloc, error) {
+	defaults := make(core.GenesisAlloc)
+	if err := json.Unmarshal([]byte(GenesisAlloc), &defaults); err != nil {
+		return nil, err
+	}
+	var contracts []params.DposSystemContract
+	if config != nil {
+		contracts = config.SystemContracts
+	}
+	addrs, _, err := systemcontract.CheckConfig(contracts)
+	if err != nil {
+		return nil, err
+	}
+	codes := make(map[string][]byte)
+	for _, contract := range contracts {
+		if len(contract.Code) > 0 {
+			codes[contract.Name] = contract.Code
+		}
+	}
+	alloc := make(core.GenesisAlloc)
+	for _, name := range systemcontract.GenesisContractNames {
+		account := defaults[systemcontract.DefaultAddress(name)]
+		if code, ok := codes[name]; ok {
+			account.Code = common.CopyBytes(code)
+		}
+		addr, ok := addrs[name]
+		if !ok {
+			addr = systemcontract.DefaultAddress(name)
+		}
+		alloc[addr] = account
+	}
+	return alloc, nil
+}
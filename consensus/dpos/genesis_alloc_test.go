@@ -0,0 +1,56 @@
+package dpos
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestSystemContractsAlloc(t *testing.T) {
+	relocated := common.HexToAddress("0x000000000000000000000000000000000000a001")
+	config := &params.DposConfig{SystemContracts: []params.DposSystemContract{
+		{Name: systemcontract.ValidatorsContractName, Address: relocated},
+		{Name: systemcontract.NodeVotesContractName, Code: []byte{0x60, 0x00}},
+	}}
+	alloc, err := SystemContractsAlloc(config)
+	if err != nil {
+		t.Fatalf("failed to build system contracts alloc: %v", err)
+	}
+	if len(alloc) != len(systemcontract.GenesisContractNames) {
+		t.Fatalf("alloc size mismatch: have %d, want %d", len(alloc), len(systemcontract.GenesisContractNames))
+	}
+	if _, ok := alloc[systemcontract.DefaultAddress(systemcontract.ValidatorsContractName)]; ok {
+		t.Errorf("relocated contract left at default address")
+	}
+	if len(alloc[relocated].Code) == 0 {
+		t.Errorf("relocated contract has no code")
+	}
+	if code := alloc[systemcontract.DefaultAddress(systemcontract.NodeVotesContractName)].Code; !bytes.Equal(code, []byte{0x60, 0x00}) {
+		t.Errorf("replaced code mismatch: have %x", code)
+	}
+	// Contracts may not share an address
+	config.SystemContracts[1].Address = relocated
+	if _, err := SystemContractsAlloc(config); err == nil {
+		t.Errorf("overlapping system contracts accepted")
+	}
+}
+
+func TestApplySystemContractsConfig(t *testing.T) {
+	// The engines of the process share the built-in system contracts
+	if err := systemcontract.ApplyConfig(nil); err != nil {
+		t.Fatalf("failed to apply config: %v", err)
+	}
+	if err := systemcontract.ApplyConfig([]params.DposSystemContract{{Name: systemcontract.ValidatorsContractName}}); err != nil {
+		t.Fatalf("failed to apply the same config: %v", err)
+	}
+	relocated := []params.DposSystemContract{{Name: systemcontract.ValidatorsContractName, Address: common.HexToAddress("0xa001")}}
+	if err := systemcontract.ApplyConfig(relocated); err == nil {
+		t.Fatalf("conflicting config applied")
+	}
+	if addr, _ := systemcontract.ContractAddress(systemcontract.ValidatorsContractName); addr != systemcontract.DefaultAddress(systemcontract.ValidatorsContractName) {
+		t.Errorf("conflicting config relocated the contract to %x", addr)
+	}
+}
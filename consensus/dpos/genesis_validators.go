@@ -0,0 +1,130 @@
+package dpos
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/vmcaller"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/log"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+// errMismatchInitialValidators is returned if the configured initial validators
+// are not the validators of the genesis block.
+var errMismatchInitialValidators = errors.New("initial validators mismatch genesis validators")
+
+// initialValidators returns the genesis validators with the parameters to register
+// them with. If no initial validators are configured, each genesis validator is
+// registered with the default parameters.
+func (d *Dpos) initialValidators(genesisValidators []common.Address) ([]params.DposInitialValidator, error) {
+	if len(genesisValidators) == 0 {
+		return nil, errInvalidValidatorsLength
+	}
+	if len(d.config.InitialValidators) == 0 {
+		initials := make([]params.DposInitialValidator, len(genesisValidators))
+		for i, validator := range genesisValidators {
+			initials[i] = params.DposInitialValidator{
+				Address: validator,
+				Deposit: systemcontract.InitDeposit,
+				Rate:    systemcontract.InitRate,
+				Name:    fmt.Sprintf("dxc-validator-%d", i+1),
+				Details: systemcontract.InitDetails,
+			}
+		}
+		return initials, nil
+	}
+	if err := d.config.CheckInitialValidators(); err != nil {
+		return nil, err
+	}
+	if len(d.config.InitialValidators) != len(genesisValidators) {
+		return nil, errMismatchInitialValidators
+	}
+	listed := make(map[common.Address]bool)
+	for _, validator := range genesisValidators {
+		listed[validator] = true
+	}
+	for _, val := range d.config.InitialValidators {
+		if !listed[val.Address] {
+			return nil, errMismatchInitialValidators
+		}
+	}
+	return d.config.InitialValidators, nil
+}
+
+// registerGenesisValidators registers the genesis validators other than the first
+// one, which is registered by the Validators contract initialization. Each of them
+// submits a validator proposal paying its deposit from its balance, guaranteed by
+// the first validator, so they leave the same records as the validators added
+// later on. They become effective validators of the first epoch.
+func (d *Dpos) registerGenesisValidators(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, initials []params.DposInitialValidator) error {
+	if len(initials) < 2 {
+		return nil
+	}
+	ret, err := d.commonCallContract(header, state, d.abi[systemcontract.SystemRewardsContractName], systemcontract.SystemRewardsContractAddr, "currentEpoch", 1)
+	if err != nil {
+		return err
+	}
+	epoch, ok := ret[0].(*big.Int)
+	if !ok {
+		return errors.New("invalid currentEpoch")
+	}
+	call := func(from common.Address, to common.Address, value *big.Int, contract string, method string, args ...interface{}) error {
+		data, err := d.abi[contract].Pack(method, args...)
+		if err != nil {
+			return err
+		}
+		msg := vmcaller.NewLegacyMessage(from, &to, state.GetNonce(from), value, math.MaxUint64, new(big.Int), data, false)
+		if _, err := vmcaller.ExecuteMsg(msg, state, header, newChainContext(chain, d), d.chainConfig); err != nil {
+			log.Error("Register genesis validator failed", "contract", contract, "method", method, "err", err)
+			return err
+		}
+		return nil
+	}
+	validatorProposalsABI := d.abi[systemcontract.ValidatorProposalsContractName]
+	for _, val := range initials[1:] {
+		if state.GetBalance(val.Address).Cmp(val.Deposit) < 0 {
+			return fmt.Errorf("insufficient balance for the deposit of genesis validator %s", val.Address.Hex())
+		}
+		if err := call(val.Address, systemcontract.ValidatorProposalsContractAddr, val.Deposit, systemcontract.ValidatorProposalsContractName,
+			"initProposal", uint8(0), val.Rate, val.Name, val.Details); err != nil {
+			return err
+		}
+		// A genesis validator has no earlier proposal
+		ret, err := d.commonCallContract(header, state, validatorProposalsABI, systemcontract.ValidatorProposalsContractAddr, "proposals", 1, val.Address, common.Big0)
+		if err != nil {
+			return err
+		}
+		id, ok := ret[0].([4]byte)
+		if !ok {
+			return errors.New("invalid proposal id")
+		}
+		if err := call(initials[0].Address, systemcontract.ValidatorProposalsContractAddr, new(big.Int), systemcontract.ValidatorProposalsContractName,
+			"guarantee", id); err != nil {
+			return err
+		}
+		if err := call(systemcontract.ValidatorsContractAddr, systemcontract.SystemRewardsContractAddr, new(big.Int), systemcontract.SystemRewardsContractName,
+			"updateValidatorWhileElect", val.Address, val.Rate, epoch); err != nil {
+			return err
+		}
+	}
+	// Refresh the epoch info with the deposits of all the genesis validators
+	validatorsABI := d.abi[systemcontract.ValidatorsContractName]
+	ret, err = d.commonCallContract(header, state, validatorsABI, systemcontract.ValidatorsContractAddr, "totalDeposit", 1)
+	if err != nil {
+		return err
+	}
+	total, ok := ret[0].(*big.Int)
+	if !ok {
+		return errors.New("invalid totalDeposit")
+	}
+	count := big.NewInt(int64(len(initials)))
+	return call(systemcontract.ValidatorsContractAddr, systemcontract.SystemRewardsContractAddr, new(big.Int), systemcontract.SystemRewardsContractName,
+		"updateEpochWhileElect", total, count, count, epoch)
+}
@@ -0,0 +1,73 @@
+package dpos
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestInitialValidators(t *testing.T) {
+	genesis := []common.Address{{0x01}, {0x02}}
+
+	// Without configuration every genesis validator is registered with the defaults
+	engine := &Dpos{config: &params.DposConfig{Epoch: 10}}
+	initials, err := engine.initialValidators(genesis)
+	if err != nil {
+		t.Fatalf("failed to resolve initial validators: %v", err)
+	}
+	if len(initials) != 2 || initials[1].Address != genesis[1] || initials[1].Deposit.Cmp(systemcontract.InitDeposit) != 0 {
+		t.Fatalf("default initial validators mismatch: have %+v", initials)
+	}
+	// Configured validators must be the genesis ones
+	engine.config.InitialValidators = []params.DposInitialValidator{
+		{Address: genesis[0], Deposit: big.NewInt(1), Rate: 80},
+		{Address: common.Address{0x03}, Deposit: big.NewInt(1), Rate: 80},
+	}
+	if _, err := engine.initialValidators(genesis); err != errMismatchInitialValidators {
+		t.Fatalf("error mismatch: have %v, want %v", err, errMismatchInitialValidators)
+	}
+	engine.config.InitialValidators[1].Address = genesis[1]
+	if initials, err = engine.initialValidators(genesis); err != nil || initials[1].Rate != 80 {
+		t.Fatalf("configured initial validators mismatch: have %+v/%v", initials, err)
+	}
+}
+
+func TestRegisterGenesisValidators(t *testing.T) {
+	validators := []common.Address{{0x01}, {0x02}, {0x03}}
+	engine, _, statedb := newPreviewTestEngine(t, validators)
+	header := &types.Header{Number: big.NewInt(1), Coinbase: validators[0], Difficulty: diffInTurn}
+
+	ret, err := engine.commonCallContract(header, statedb, engine.abi[systemcontract.ValidatorsContractName], systemcontract.ValidatorsContractAddr, "getEffictiveValidators", 1)
+	if err != nil {
+		t.Fatalf("failed to get effective validators: %v", err)
+	}
+	if effective := ret[0].([]common.Address); !reflect.DeepEqual(effective, validators) {
+		t.Fatalf("effective validators mismatch: have %x, want %x", effective, validators)
+	}
+	// The deposits are paid by the validators
+	for _, val := range validators {
+		if balance := statedb.GetBalance(val); balance.Sign() != 0 {
+			t.Errorf("validator %x kept its deposit: %v", val, balance)
+		}
+	}
+	// The extra validators are added by passed proposals guaranteed by the first one
+	proposalsABI := engine.abi[systemcontract.ValidatorProposalsContractName]
+	for _, val := range validators[1:] {
+		ret, err := engine.commonCallContract(header, statedb, proposalsABI, systemcontract.ValidatorProposalsContractAddr, "proposals", 1, val, common.Big0)
+		if err != nil {
+			t.Fatalf("failed to get proposal of %x: %v", val, err)
+		}
+		info, err := engine.commonCallContract(header, statedb, proposalsABI, systemcontract.ValidatorProposalsContractAddr, "proposalInfos", 11, ret[0])
+		if err != nil {
+			t.Fatalf("failed to get proposal info of %x: %v", val, err)
+		}
+		if proposer, guarantee, status := info[1].(common.Address), info[8].(common.Address), info[10].(uint8); proposer != val || guarantee != validators[0] || status != 1 {
+			t.Errorf("proposal of %x mismatch: proposer %x, guarantee %x, status %d", val, proposer, guarantee, status)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package dpos
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/DxChainNetwork/dxc/accounts/abi"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/rlp"
+)
+
+// GovernanceProposal is the json representation of a system governance proposal.
+type GovernanceProposal struct {
+	Id     *hexutil.Big   `json:"id"`
+	Action *hexutil.Big   `json:"action"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Value  *hexutil.Big   `json:"value"`
+	Data   hexutil.Bytes  `json:"data"`
+}
+
+// newGovernanceProposal converts a proposal to its json representation.
+func newGovernanceProposal(prop *Proposal) *GovernanceProposal {
+	return &GovernanceProposal{
+		Id:     (*hexutil.Big)(prop.Id),
+		Action: (*hexutil.Big)(prop.Action),
+		From:   prop.From,
+		To:     prop.To,
+		Value:  (*hexutil.Big)(prop.Value),
+		Data:   prop.Data,
+	}
+}
+
+// proposal converts the json representation back to a proposal, the missing
+// numeric fields default to zero.
+func (p *GovernanceProposal) proposal() *Proposal {
+	toBig := func(v *hexutil.Big) *big.Int {
+		if v == nil {
+			return new(big.Int)
+		}
+		return new(big.Int).Set(v.ToInt())
+	}
+	return &Proposal{
+		Id:     toBig(p.Id),
+		Action: toBig(p.Action),
+		From:   p.From,
+		To:     p.To,
+		Value:  toBig(p.Value),
+		Data:   common.CopyBytes(p.Data),
+	}
+}
+
+// AccountState is the state of an account touched by a simulated proposal. Only
+// the storage slots written by the proposal are included.
+type AccountState struct {
+	Balance  *hexutil.Big                `json:"balance"`
+	Nonce    hexutil.Uint64              `json:"nonce"`
+	CodeHash common.Hash                 `json:"codeHash"`
+	Storage  map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// AccountDiff is the state of an account before and after a simulated proposal.
+type AccountDiff struct {
+	Before *AccountState `json:"before"`
+	After  *AccountState `json:"after"`
+}
+
+// GovernanceSimulation is the outcome of executing a system governance proposal
+// against a copy of the state.
+type GovernanceSimulation struct {
+	Number       uint64                          `json:"number"`
+	Receipt      *types.Receipt                  `json:"receipt"`
+	Logs         []*types.Log                    `json:"logs"`
+	StateDiff    map[common.Address]*AccountDiff `json:"stateDiff"`
+	Error        string                          `json:"error,omitempty"`
+	RevertReason string                          `json:"revertReason,omitempty"`
+}
+
+// touchRecorder is an evm tracer recording the accounts and storage slots a
+// proposal may modify.
+type touchRecorder struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+func newTouchRecorder() *touchRecorder {
+	return &touchRecorder{accounts: make(map[common.Address]map[common.Hash]struct{})}
+}
+
+func (r *touchRecorder) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := r.accounts[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		r.accounts[addr] = slots
+	}
+	return slots
+}
+
+func (r *touchRecorder) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	r.touch(from)
+	r.touch(to)
+}
+
+func (r *touchRecorder) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	contract := scope.Contract.Address()
+	slots := r.touch(contract)
+
+	stack := scope.Stack
+	switch op {
+	case vm.SSTORE:
+		if len(stack.Data()) >= 1 {
+			slots[common.Hash(stack.Back(0).Bytes32())] = struct{}{}
+		}
+	case vm.CALL, vm.CALLCODE:
+		if len(stack.Data()) >= 2 {
+			r.touch(common.Address(stack.Back(1).Bytes20()))
+		}
+	case vm.SELFDESTRUCT:
+		if len(stack.Data()) >= 1 {
+			r.touch(common.Address(stack.Back(0).Bytes20()))
+		}
+	}
+}
+
+func (r *touchRecorder) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (r *touchRecorder) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {}
+
+// accountState reads the state of the account, with the given storage slots.
+func accountState(statedb *state.StateDB, addr common.Address, slots map[common.Hash]struct{}) *AccountState {
+	account := &AccountState{
+		Balance:  (*hexutil.Big)(statedb.GetBalance(addr)),
+		Nonce:    hexutil.Uint64(statedb.GetNonce(addr)),
+		CodeHash: statedb.GetCodeHash(addr),
+	}
+	if len(slots) > 0 {
+		account.Storage = make(map[common.Hash]common.Hash, len(slots))
+		for slot := range slots {
+			account.Storage[slot] = statedb.GetState(addr, slot)
+		}
+	}
+	return account
+}
+
+// diff returns the changes between the states of the recorded accounts, the slots
+// left unchanged are omitted.
+func (r *touchRecorder) diff(pre, post *state.StateDB) map[common.Address]*AccountDiff {
+	diffs := make(map[common.Address]*AccountDiff)
+	for addr, slots := range r.accounts {
+		before, after := accountState(pre, addr, slots), accountState(post, addr, slots)
+		for slot, value := range before.Storage {
+			if after.Storage[slot] == value {
+				delete(before.Storage, slot)
+				delete(after.Storage, slot)
+			}
+		}
+		if before.Balance.ToInt().Cmp(after.Balance.ToInt()) == 0 && before.Nonce == after.Nonce &&
+			before.CodeHash == after.CodeHash && len(before.Storage) == 0 {
+			continue
+		}
+		diffs[addr] = &AccountDiff{Before: before, After: after}
+	}
+	return diffs
+}
+
+// simulateProposal executes the system governance proposal on top of the given
+// header against a copy of its state, as the next block would. The given state is
+// left untouched.
+func (d *Dpos) simulateProposal(chain consensus.ChainHeaderReader, parent *types.Header, statedb *state.StateDB, prop *Proposal) (*GovernanceSimulation, error) {
+	propRLP, err := rlp.EncodeToBytes(prop)
+	if err != nil {
+		return nil, err
+	}
+	number := parent.Number.Uint64() + 1
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).SetUint64(number),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + d.config.PeriodAt(number),
+		Difficulty: new(big.Int).Set(diffInTurn),
+		Coinbase:   parent.Coinbase,
+	}
+	// The unsigned system governance transaction identifies the logs of the proposal
+	txHash := types.NewTransaction(0, systemcontract.SysGovToAddr, new(big.Int), header.GasLimit, new(big.Int), propRLP).Hash()
+
+	var (
+		post     = statedb.Copy()
+		recorder = newTouchRecorder()
+	)
+	recorder.touch(prop.From)
+	recorder.touch(prop.To)
+	if isStateAction(prop.Action) {
+		// The state actions are applied without the evm
+		addr, slots := d.stateActionWrites(prop)
+		written := recorder.touch(addr)
+		for _, slot := range slots {
+			written[slot] = struct{}{}
+		}
+	}
+	receipt, ret, err := d.applyProposalMsg(chain, header, post, prop, 0, txHash, common.Hash{}, vm.Config{Debug: true, Tracer: recorder})
+
+	sim := &GovernanceSimulation{
+		Number:    header.Number.Uint64(),
+		Receipt:   receipt,
+		Logs:      receipt.Logs,
+		StateDiff: recorder.diff(statedb, post),
+	}
+	if sim.Logs == nil {
+		sim.Logs = []*types.Log{}
+	}
+	if err != nil {
+		sim.Error = err.Error()
+		if err == vm.ErrExecutionReverted {
+			if reason, errUnpack := abi.UnpackRevert(ret); errUnpack == nil {
+				sim.RevertReason = reason
+			}
+		}
+	}
+	return sim, nil
+}
+
+// passedProposals returns the passed system governance proposals which will be
+// executed by the block following the given header.
+func (d *Dpos) passedProposals(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB) ([]*Proposal, error) {
+	count, err := d.getPassedProposalCount(chain, header, statedb)
+	if err != nil {
+		return nil, err
+	}
+	props := make([]*Proposal, 0, count)
+	for i := uint32(0); i < count; i++ {
+		prop, err := d.getPassedProposalByIndex(chain, header, statedb, i)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, prop)
+	}
+	return props, nil
+}
@@ -0,0 +1,117 @@
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestSimulateProposal(t *testing.T) {
+	statedb := newTestState()
+
+	var (
+		store  = common.HexToAddress("0x1001")
+		revert = common.HexToAddress("0x1002")
+	)
+	// sstore(0, calldataload(0))
+	statedb.SetCode(store, []byte{0x60, 0x00, 0x35, 0x60, 0x00, 0x55, 0x00})
+	// revert(0, 0)
+	statedb.SetCode(revert, []byte{0x60, 0x00, 0x60, 0x00, 0xfd})
+
+	// The system messages are executed without preparing the access list
+	config := *params.TestChainConfig
+	config.BerlinBlock, config.LondonBlock = nil, nil
+	engine := &Dpos{chainConfig: &config, config: &params.DposConfig{Period: 3}}
+	parent := &types.Header{Number: big.NewInt(10), GasLimit: 8000000, Difficulty: big.NewInt(2)}
+
+	prop := &Proposal{Id: big.NewInt(1), Action: new(big.Int), From: common.HexToAddress("0xff"), To: store, Value: new(big.Int), Data: common.Hash{0x2a}.Bytes()}
+	sim, err := engine.simulateProposal(nil, parent, statedb, prop)
+	if err != nil {
+		t.Fatalf("failed to simulate proposal: %v", err)
+	}
+	if sim.Error != "" || sim.Receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("proposal failed: %s", sim.Error)
+	}
+	diff, ok := sim.StateDiff[store]
+	if !ok || diff.After.Storage[common.Hash{}] != (common.Hash{0x2a}) {
+		t.Fatalf("storage change missing: %v", sim.StateDiff)
+	}
+	if value := statedb.GetState(store, common.Hash{}); value != (common.Hash{}) {
+		t.Errorf("original state modified: %x", value)
+	}
+	prop.To = revert
+	if sim, err = engine.simulateProposal(nil, parent, statedb, prop); err != nil {
+		t.Fatalf("failed to simulate proposal: %v", err)
+	}
+	if sim.Receipt.Status != types.ReceiptStatusFailed || sim.Error == "" {
+		t.Errorf("reverted proposal reported as successful")
+	}
+	// The next block is timed by the period in effect at its number
+	clock := common.HexToAddress("0x1003")
+	statedb.SetCode(clock, []byte{0x42, 0x60, 0x00, 0x55, 0x00}) // sstore(0, timestamp)
+	engine.config.Forks = []params.DposForkConfig{{Block: big.NewInt(11), Period: 1}}
+	engine.config.Epoch = 100
+
+	prop.To = clock
+	parent.Time = 1000
+	if sim, err = engine.simulateProposal(nil, parent, statedb, prop); err != nil {
+		t.Fatalf("failed to simulate proposal: %v", err)
+	}
+	if have := sim.StateDiff[clock].After.Storage[common.Hash{}].Big(); have.Uint64() != 1001 {
+		t.Errorf("block time mismatch: have %v, want 1001", have)
+	}
+}
+
+func TestSimulateStateActions(t *testing.T) {
+	var (
+		target = common.HexToAddress("0x1001")
+		param  = params.DposGovernableParam{Name: "minDeposit", Address: common.HexToAddress("0x1002"), Slot: common.Hash{0x05}}
+	)
+	statedb := newTestState()
+	statedb.SetCode(target, []byte{0x00})
+	statedb.SetCode(param.Address, []byte{0x00})
+
+	engine := &Dpos{
+		chainConfig: params.TestChainConfig,
+		config:      &params.DposConfig{Period: 3, GovernanceActionsBlock: big.NewInt(0), GovernableParams: []params.DposGovernableParam{param}},
+	}
+	parent := &types.Header{Number: big.NewInt(10), GasLimit: 8000000, Difficulty: big.NewInt(2)}
+
+	tests := []struct {
+		action int64
+		value  int64
+		data   []byte
+		addr   common.Address
+		check  func(diff *AccountDiff) bool
+	}{
+		{ProposalActionSetCode, 0, []byte{0x60, 0x01}, target, func(diff *AccountDiff) bool {
+			return diff.After.CodeHash != diff.Before.CodeHash
+		}},
+		{ProposalActionSetStorage, 0, append(common.Hash{0x01}.Bytes(), common.Hash{0xaa}.Bytes()...), target, func(diff *AccountDiff) bool {
+			return diff.Before.Storage[common.Hash{0x01}] == (common.Hash{}) && diff.After.Storage[common.Hash{0x01}] == (common.Hash{0xaa})
+		}},
+		{ProposalActionAdjustBalance, 100, nil, target, func(diff *AccountDiff) bool {
+			return diff.After.Balance.ToInt().Int64() == 100
+		}},
+		{ProposalActionSetParam, 42, []byte("minDeposit"), param.Address, func(diff *AccountDiff) bool {
+			return diff.After.Storage[param.Slot] == common.BigToHash(big.NewInt(42))
+		}},
+	}
+	for _, tt := range tests {
+		prop := &Proposal{Id: big.NewInt(1), Action: big.NewInt(tt.action), To: target, Value: big.NewInt(tt.value), Data: tt.data}
+		sim, err := engine.simulateProposal(nil, parent, statedb, prop)
+		if err != nil || sim.Error != "" {
+			t.Fatalf("action %d: failed to simulate proposal: %v %s", tt.action, err, sim.Error)
+		}
+		diff, ok := sim.StateDiff[tt.addr]
+		if !ok || !tt.check(diff) {
+			t.Errorf("action %d: state diff mismatch: %v", tt.action, sim.StateDiff)
+		}
+		if len(sim.StateDiff) != 1 {
+			t.Errorf("action %d: unexpected accounts in the state diff: %v", tt.action, sim.StateDiff)
+		}
+	}
+}
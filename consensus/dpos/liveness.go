@@ -0,0 +1,66 @@
+package dpos
+
+import (
+	"github.com/DxChainNetwork/dxc/common"
+)
+
+const (
+	livenessEpochs = 8 // Number of recent epochs to keep the validator liveness records for
+)
+
+// Liveness is the block production record of a validator within one epoch.
+type Liveness struct {
+	Produced  uint64 `json:"produced"`  // Blocks sealed by the validator, in-turn or not
+	Missed    uint64 `json:"missed"`    // In-turn slots of the validator sealed by another one
+	OutOfTurn uint64 `json:"outOfTurn"` // Blocks sealed by the validator out of its turn
+}
+
+// recordLiveness accounts the block sealed by the signer into the liveness index,
+// and drops the records of the epochs no longer tracked. It must be called before
+// the validator set is updated by the block, as in-turn-ness is decided by the
+// validator set the block was sealed with.
+func (s *Snapshot) recordLiveness(number uint64, signer common.Address) {
+	epoch := s.config.EpochNumber(number)
+	if s.Liveness == nil {
+		s.Liveness = make(map[uint64]map[common.Address]*Liveness)
+	}
+	records, ok := s.Liveness[epoch]
+	if !ok {
+		records = make(map[common.Address]*Liveness)
+		s.Liveness[epoch] = records
+
+		for old := range s.Liveness {
+			if old+livenessEpochs <= epoch {
+				delete(s.Liveness, old)
+			}
+		}
+	}
+	record := func(validator common.Address) *Liveness {
+		if records[validator] == nil {
+			records[validator] = new(Liveness)
+		}
+		return records[validator]
+	}
+	record(signer).Produced++
+
+	if inturn := s.inturnValidator(number); inturn != signer {
+		record(signer).OutOfTurn++
+		record(inturn).Missed++
+	}
+}
+
+// copyLiveness creates a deep copy of the liveness index.
+func (s *Snapshot) copyLiveness() map[uint64]map[common.Address]*Liveness {
+	if s.Liveness == nil {
+		return nil
+	}
+	cpy := make(map[uint64]map[common.Address]*Liveness, len(s.Liveness))
+	for epoch, records := range s.Liveness {
+		cpy[epoch] = make(map[common.Address]*Liveness, len(records))
+		for validator, record := range records {
+			r := *record
+			cpy[epoch][validator] = &r
+		}
+	}
+	return cpy
+}
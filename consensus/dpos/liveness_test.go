@@ -0,0 +1,33 @@
+package dpos
+
+import (
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestRecordLiveness(t *testing.T) {
+	validators := []common.Address{{0x01}, {0x02}, {0x03}}
+	snap := newSnapshot(&params.DposConfig{Epoch: 10}, nil, 0, common.Hash{}, validators, nil)
+
+	// Block 1 is sealed in-turn, block 2 is sealed by 0x01 in place of 0x03
+	snap.recordLiveness(1, validators[1])
+	snap.recordLiveness(2, validators[0])
+
+	records := snap.Liveness[0]
+	if have := *records[validators[0]]; have != (Liveness{Produced: 1, OutOfTurn: 1}) {
+		t.Errorf("liveness mismatch of %x: have %+v", validators[0], have)
+	}
+	if have := *records[validators[1]]; have != (Liveness{Produced: 1}) {
+		t.Errorf("liveness mismatch of %x: have %+v", validators[1], have)
+	}
+	if have := *records[validators[2]]; have != (Liveness{Missed: 1}) {
+		t.Errorf("liveness mismatch of %x: have %+v", validators[2], have)
+	}
+	// Records of old epochs are dropped
+	snap.recordLiveness(livenessEpochs*10, validators[0])
+	if _, ok := snap.Liveness[0]; ok {
+		t.Errorf("stale liveness records not dropped")
+	}
+}
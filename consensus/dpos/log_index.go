@@ -0,0 +1,131 @@
+package dpos
+
+import (
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/ethdb"
+	"github.com/DxChainNetwork/dxc/event"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+const logIndexBatch = 1024 // Number of blocks indexed between two database writes
+
+// chainHeadSubscriber is the part of the blockchain followed by the log indexes.
+type chainHeadSubscriber interface {
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// logIndexChanges accumulates the changes of a batch of blocks on top of an index
+// maintained from the receipt logs of the canonical blocks.
+type logIndexChanges interface {
+	// apply accumulates the changes of the logs of a block, reverting them if the
+	// block left the canonical chain.
+	apply(receipts types.Receipts, revert bool)
+
+	// flush writes the accumulated changes into the batch and resets them.
+	flush(batch ethdb.Batch)
+}
+
+// logIndex is an index maintained from the receipt logs of the canonical blocks,
+// along with the latest block it includes.
+type logIndex struct {
+	name      string // Name of the index in the logs
+	readHead  func(db ethdb.KeyValueReader) (uint64, common.Hash, bool)
+	writeHead func(db ethdb.KeyValueWriter, number uint64, hash common.Hash)
+	changes   logIndexChanges
+
+	// seed accumulates the entries of the genesis state, which has no logs, when
+	// the index is created. Nil if the genesis state has nothing to index.
+	seed func(genesis *types.Header) error
+}
+
+// startLogIndex starts maintaining the index from the canonical blocks, until the
+// engine is closed.
+func (d *Dpos) startLogIndex(index *logIndex, feed chainHeadSubscriber) {
+	events := make(chan core.ChainHeadEvent, 16)
+	sub := d.scope.Track(feed.SubscribeChainHeadEvent(events))
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			if err := d.updateLogIndex(index, sub.Err()); err != nil {
+				log.Warn("Failed to update dpos index", "index", index.name, "err", err)
+			}
+			select {
+			case <-events:
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// flushLogIndex writes the accumulated changes along with the new head of the index.
+func (d *Dpos) flushLogIndex(index *logIndex, number uint64, hash common.Hash) {
+	batch := d.db.NewBatch()
+	index.changes.flush(batch)
+	index.writeHead(batch, number, hash)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write dpos index", "index", index.name, "err", err)
+	}
+}
+
+// updateLogIndex brings the index to the canonical head, first reverting the
+// blocks indexed on a chain that was reorged out.
+func (d *Dpos) updateLogIndex(index *logIndex, quit <-chan error) error {
+	number, hash, ok := index.readHead(d.db)
+	if !ok {
+		number, hash = 0, rawdb.ReadCanonicalHash(d.db, 0)
+		if index.seed != nil {
+			genesis := rawdb.ReadHeader(d.db, hash, 0)
+			if genesis == nil {
+				return errUnknownBlock
+			}
+			if err := index.seed(genesis); err != nil {
+				return err
+			}
+			d.flushLogIndex(index, number, hash)
+		}
+	}
+	// Unwind the blocks which are not canonical anymore
+	for hash != rawdb.ReadCanonicalHash(d.db, number) {
+		header := rawdb.ReadHeader(d.db, hash, number)
+		if header == nil || number == 0 {
+			return errUnknownBlock
+		}
+		index.changes.apply(rawdb.ReadRawReceipts(d.db, hash, number), true)
+		number, hash = number-1, header.ParentHash
+		d.flushLogIndex(index, number, hash)
+	}
+	// Index the canonical blocks up to the head
+	head := rawdb.ReadHeadBlockHash(d.db)
+	headNumber := rawdb.ReadHeaderNumber(d.db, head)
+	if headNumber == nil {
+		return errUnknownBlock
+	}
+	start := number
+	for number < *headNumber {
+		next := rawdb.ReadCanonicalHash(d.db, number+1)
+		header := rawdb.ReadHeader(d.db, next, number+1)
+		if header == nil || header.ParentHash != hash {
+			// Reorged meanwhile, the next update unwinds it
+			break
+		}
+		index.changes.apply(rawdb.ReadRawReceipts(d.db, next, number+1), false)
+		number, hash = number+1, next
+
+		if (number-start)%logIndexBatch == 0 {
+			d.flushLogIndex(index, number, hash)
+			log.Debug("Indexed dpos logs", "index", index.name, "number", number)
+			select {
+			case <-quit:
+				return nil
+			default:
+			}
+		}
+	}
+	d.flushLogIndex(index, number, hash)
+	return nil
+}
@@ -0,0 +1,233 @@
+package dpos
+
+import (
+	"errors"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/core/types"
+)
+
+const (
+	maxProofCheckpoints = 512  // Maximum number of checkpoints proven in one validator set proof
+	maxProofHeaders     = 8192 // Maximum number of headers served in one validator set proof
+)
+
+var (
+	// errInvalidProofCheckpoint is returned if a header of a validator set proof is
+	// not the checkpoint following the previous one.
+	errInvalidProofCheckpoint = errors.New("validator set proof header is not the next checkpoint")
+
+	// errInvalidProofAncestry is returned if the headers proving a checkpoint are not
+	// consecutive blocks of its epoch linked by their parent hashes.
+	errInvalidProofAncestry = errors.New("validator set proof headers not linked to the checkpoint")
+
+	// errUnauthorizedProofSigner is returned if a header of a validator set proof is
+	// not sealed by a member of the previous validator set.
+	errUnauthorizedProofSigner = errors.New("validator set proof header sealed by unauthorized validator")
+
+	// errInsufficientProofSeals is returned if the headers proving a checkpoint are
+	// not sealed by more than 2/3 of the weight of the previous validator set.
+	errInsufficientProofSeals = errors.New("validator set proof checkpoint sealed by too few validators")
+)
+
+// ValidatorSetProof is a compact proof of the validator set transitions between
+// two epochs. Each transition consists of an epoch checkpoint header, carrying the
+// validator set of its epoch, preceded by its closest ancestors, the chain of them
+// being sealed by validators holding more than 2/3 of the weight of the previous
+// validator set. If the ancestors reach back to the previous checkpoint, they are
+// linked to it by parent hash as well.
+type ValidatorSetProof struct {
+	Checkpoints [][]*types.Header `json:"checkpoints"`
+}
+
+// nextCheckpoint returns the checkpoint block following the given one.
+func (d *Dpos) nextCheckpoint(checkpoint uint64) uint64 {
+	return checkpoint + d.config.Epoch
+}
+
+// ValidatorSetProof assembles the checkpoint headers following the trusted checkpoint
+// at the given number, up to the given head, each along with the ancestors needed
+// to reach the sealing quorum of the previous validator set. At most maxProofCheckpoints
+// checkpoints are returned, the caller can continue from the last one.
+func (d *Dpos) ValidatorSetProof(chain consensus.ChainHeaderReader, from uint64, head *types.Header) (*ValidatorSetProof, error) {
+	if !d.config.IsCheckpoint(from) {
+		return nil, errInvalidProofCheckpoint
+	}
+	trusted := chain.GetHeaderByNumber(from)
+	if trusted == nil {
+		return nil, errUnknownBlock
+	}
+	validators, weights, signers, err := d.checkpointValidators(trusted)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		proof = &ValidatorSetProof{Checkpoints: [][]*types.Header{}}
+		count int
+	)
+	for number := d.nextCheckpoint(from); number <= head.Number.Uint64() && len(proof.Checkpoints) < maxProofCheckpoints; number = d.nextCheckpoint(number) {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		// Gather the ancestors of the checkpoint until the previous validators sealing
+		// them hold the quorum
+		quorum := newProofQuorum(validators, weights, signers)
+		headers := []*types.Header{header}
+		for {
+			signer, err := ecrecover(header, d.signatures)
+			if err != nil {
+				return nil, err
+			}
+			if !quorum.seal(signer) {
+				return nil, errUnauthorizedProofSigner
+			}
+			if quorum.reached() {
+				break
+			}
+			if header.Number.Uint64() == from+1 {
+				return nil, errInsufficientProofSeals
+			}
+			if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+				return nil, errUnknownBlock
+			}
+			headers = append(headers, header)
+		}
+		if count += len(headers); count > maxProofHeaders && len(proof.Checkpoints) > 0 {
+			break
+		}
+		for i := 0; i < len(headers)/2; i++ {
+			headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
+		}
+		proof.Checkpoints = append(proof.Checkpoints, headers)
+
+		checkpoint := headers[len(headers)-1]
+		if validators, weights, signers, err = d.checkpointValidators(checkpoint); err != nil {
+			return nil, err
+		}
+		from = number
+	}
+	return proof, nil
+}
+
+// VerifyValidatorSetProof checks the validator set transitions of the proof, starting
+// from the trusted checkpoint header, and returns the last checkpoint header along
+// with its validator set.
+func (d *Dpos) VerifyValidatorSetProof(trusted *types.Header, proof *ValidatorSetProof) (*types.Header, []common.Address, []uint64, error) {
+	if !d.config.IsCheckpoint(trusted.Number.Uint64()) {
+		return nil, nil, nil, errInvalidProofCheckpoint
+	}
+	validators, weights, signers, err := d.checkpointValidators(trusted)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	last := trusted
+	for _, headers := range proof.Checkpoints {
+		if len(headers) == 0 {
+			return nil, nil, nil, errInvalidProofCheckpoint
+		}
+		var (
+			previous   = last.Number.Uint64()
+			checkpoint = headers[len(headers)-1]
+		)
+		if checkpoint.Number == nil || checkpoint.Number.Uint64() != d.nextCheckpoint(previous) {
+			return nil, nil, nil, errInvalidProofCheckpoint
+		}
+		// The ancestors must be consecutive blocks of the epoch, and linked to the
+		// previous checkpoint if they start right after it
+		first := headers[0]
+		if first.Number == nil || first.Number.Uint64() <= previous || uint64(len(headers)) != checkpoint.Number.Uint64()-first.Number.Uint64()+1 {
+			return nil, nil, nil, errInvalidProofAncestry
+		}
+		if first.Number.Uint64() == previous+1 && first.ParentHash != last.Hash() {
+			return nil, nil, nil, errInvalidProofAncestry
+		}
+		quorum := newProofQuorum(validators, weights, signers)
+		for i, header := range headers {
+			if i > 0 && header.ParentHash != headers[i-1].Hash() {
+				return nil, nil, nil, errInvalidProofAncestry
+			}
+			// The epoch is sealed with the signing keys of the previous checkpoint, if any
+			signer, err := ecrecover(header, d.signatures)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if !quorum.seal(signer) {
+				return nil, nil, nil, errUnauthorizedProofSigner
+			}
+		}
+		if !quorum.reached() {
+			return nil, nil, nil, errInsufficientProofSeals
+		}
+		if validators, weights, signers, err = d.checkpointValidators(checkpoint); err != nil {
+			return nil, nil, nil, err
+		}
+		last = checkpoint
+	}
+	return last, validators, weights, nil
+}
+
+// proofQuorum accumulates the weight of the distinct validators sealing the headers
+// proving a checkpoint.
+type proofQuorum struct {
+	weights map[common.Address]uint64 // Weight of each sealing key of the validator set
+	sealed  map[common.Address]bool   // Sealing keys already counted
+	total   uint64                    // Total weight of the validator set
+	signed  uint64                    // Weight of the validators having sealed
+}
+
+// newProofQuorum creates a quorum over the validator set of a checkpoint. Unweighted
+// validators count as one each.
+func newProofQuorum(validators []common.Address, weights []uint64, signers []common.Address) *proofQuorum {
+	quorum := &proofQuorum{
+		weights: make(map[common.Address]uint64, len(validators)),
+		sealed:  make(map[common.Address]bool),
+	}
+	for i, validator := range validators {
+		key, weight := validator, uint64(1)
+		if signers != nil {
+			key = signers[i]
+		}
+		if weights != nil {
+			weight = weights[i]
+		}
+		quorum.weights[key] += weight
+		quorum.total += weight
+	}
+	return quorum
+}
+
+// seal counts the weight of the validator sealing with the given key, returning
+// false if it is not a member of the validator set.
+func (q *proofQuorum) seal(signer common.Address) bool {
+	weight, ok := q.weights[signer]
+	if !ok {
+		return false
+	}
+	if !q.sealed[signer] {
+		q.sealed[signer] = true
+		q.signed += weight
+	}
+	return true
+}
+
+// reached returns whether the sealing validators hold more than 2/3 of the weight.
+func (q *proofQuorum) reached() bool {
+	return 3*q.signed > 2*q.total
+}
+
+// checkpointValidators validates the layout of the extra-data of a checkpoint header
+// and extracts the validator set, along with the signing keys, from it.
+func (d *Dpos) checkpointValidators(header *types.Header) ([]common.Address, []uint64, []common.Address, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, nil, nil, errMissingSignature
+	}
+	number := header.Number.Uint64()
+	validatorsBytes := len(header.Extra) - extraVanity - extraSeal
+	if validatorsBytes == 0 || validatorsBytes%d.checkpointEntryLength(number) != 0 {
+		return nil, nil, nil, errInvalidCheckpointValidators
+	}
+	validators, weights, signers := parseCheckpointValidators(header, isWeightedCheckpoint(d.config, number), isKeyedCheckpoint(d.config, number))
+	return validators, weights, signers, nil
+}
@@ -0,0 +1,108 @@
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestValidatorSetProof(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 5)
+	addrs := make([]common.Address, len(keys))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+
+	seal := func(header *types.Header, key *ecdsa.PrivateKey) *types.Header {
+		sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatalf("failed to sign header: %v", err)
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		return header
+	}
+	block := func(parent *types.Header, key *ecdsa.PrivateKey, validators ...common.Address) *types.Header {
+		header := &types.Header{Number: big.NewInt(0), Difficulty: diffInTurn}
+		if parent != nil {
+			header.Number.Add(parent.Number, common.Big1)
+			header.ParentHash = parent.Hash()
+		}
+		header.Extra = make([]byte, extraVanity)
+		if len(validators) > 0 {
+			header.Extra = append(header.Extra, encodeCheckpointValidators(validators, nil)...)
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+		return seal(header, key)
+	}
+	// Validators 0-3 hand over to validator 4 at block 10, which hands back to
+	// validator 0 at block 20
+	chain := testHeaderChain{block(nil, keys[0], addrs[:4]...)}
+	for number := 1; number <= 20; number++ {
+		var (
+			key        = keys[4]
+			validators []common.Address
+		)
+		if number <= 10 {
+			key = keys[number%4]
+		}
+		switch number {
+		case 10:
+			validators = addrs[4:]
+		case 20:
+			validators = addrs[:1]
+		}
+		chain = append(chain, block(chain[number-1], key, validators...))
+	}
+	proof, err := engine.ValidatorSetProof(chain, 0, chain.CurrentHeader())
+	if err != nil {
+		t.Fatalf("failed to assemble proof: %v", err)
+	}
+	// Three of the four initial validators are needed for the first transition
+	if len(proof.Checkpoints) != 2 || len(proof.Checkpoints[0]) != 3 || len(proof.Checkpoints[1]) != 1 {
+		t.Fatalf("proof layout mismatch: have %d checkpoints", len(proof.Checkpoints))
+	}
+	last, validators, _, err := engine.VerifyValidatorSetProof(chain[0], proof)
+	if err != nil {
+		t.Fatalf("failed to verify proof: %v", err)
+	}
+	if last.Hash() != chain[20].Hash() || len(validators) != 1 || validators[0] != addrs[0] {
+		t.Fatalf("validator set mismatch: have %x, want %x", validators, addrs[:1])
+	}
+	// A single former validator can't forge a transition
+	forged := block(chain[9], keys[1], addrs[1])
+	proof.Checkpoints[0] = []*types.Header{forged}
+	if _, _, _, err := engine.VerifyValidatorSetProof(chain[0], proof); err != errInsufficientProofSeals {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInsufficientProofSeals)
+	}
+	// Ancestors not linked to the checkpoint are rejected
+	proof.Checkpoints[0] = []*types.Header{chain[8], chain[9], forged}
+	proof.Checkpoints[0][1] = block(chain[7], keys[1])
+	if _, _, _, err := engine.VerifyValidatorSetProof(chain[0], proof); err != errInvalidProofAncestry {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidProofAncestry)
+	}
+	// Ancestors starting right after the previous checkpoint must be its children
+	proof.Checkpoints[0] = append([]*types.Header{}, chain[1:11]...)
+	proof.Checkpoints[0][0] = block(block(nil, keys[1], addrs[1]), keys[1])
+	if _, _, _, err := engine.VerifyValidatorSetProof(chain[0], proof); err != errInvalidProofAncestry {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidProofAncestry)
+	}
+	// A transition sealed by an outsider is rejected
+	proof.Checkpoints[0] = []*types.Header{chain[8], chain[9], chain[10]}
+	proof.Checkpoints[1] = []*types.Header{block(chain[19], keys[2], addrs[2])}
+	if _, _, _, err := engine.VerifyValidatorSetProof(chain[0], proof); err != errUnauthorizedProofSigner {
+		t.Fatalf("error mismatch: have %v, want %v", err, errUnauthorizedProofSigner)
+	}
+	// Skipping an epoch is rejected
+	proof.Checkpoints = proof.Checkpoints[1:]
+	if _, _, _, err := engine.VerifyValidatorSetProof(chain[0], proof); err != errInvalidProofCheckpoint {
+		t.Fatalf("error mismatch: have %v, want %v", err, errInvalidProofCheckpoint)
+	}
+}
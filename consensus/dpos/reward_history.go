@@ -0,0 +1,380 @@
+package dpos
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+)
+
+const (
+	inmemoryRewardEpochs   = 256  // Number of recent epochs to remember the sealed block counts for
+	inmemoryRewardHistory  = 4096 // Number of epoch rewards of closed epochs to keep in memory
+	maxRewardHistoryEpochs = 256  // Maximum number of epochs walked by a reward history query
+
+	// Maximum number of headers a reward history query walks to count the sealed
+	// blocks of the epochs neither indexed nor in the snapshot liveness records
+	maxRewardHistoryHeaders = 4 * 14400
+
+	secondsPerYear = 365 * 24 * 3600
+)
+
+// Roles of an account in the rewards of an epoch.
+const (
+	rewardRoleValidator = "validator" // Rewards of the account's own validator
+	rewardRoleVoter     = "voter"     // Share of the rewards of a validator voted for
+)
+
+var (
+	// errInvalidEpochRange is returned if the epoch range of a reward history
+	// query is reversed, too long or in the future.
+	errInvalidEpochRange = errors.New("invalid epoch range")
+
+	// errRewardHistoryTooLong is returned if answering a reward history query would
+	// walk more headers than allowed.
+	errRewardHistoryTooLong = errors.New("too many blocks to count, narrow the epoch range")
+)
+
+// RewardHistory is the reward earned by an account in each epoch of a range, as a
+// validator and through the validators it voted for.
+type RewardHistory struct {
+	Address common.Address `json:"address"`
+	Epochs  []*EpochReward `json:"epochs"`
+}
+
+// EpochReward is the reward a validator earned in an epoch. For voters it is the
+// reward of one of the validators voted for, along with the share of the voter.
+type EpochReward struct {
+	Epoch           uint64         `json:"epoch"`
+	Role            string         `json:"role"` // Role of the account in the epoch, validator or voter
+	Validator       common.Address `json:"validator"`
+	Blocks          uint64         `json:"blocks"`          // Blocks sealed by the validator in the epoch
+	BlockReward     *hexutil.Big   `json:"blockReward"`     // Part of the rewards minted per sealed block
+	FeeShare        *hexutil.Big   `json:"feeShare"`        // Part of the rewards collected from transaction fees
+	Rate            uint8          `json:"rate"`            // Percentage of the rewards going to the voters
+	ValidatorReward *hexutil.Big   `json:"validatorReward"` // Rewards kept by the validator
+	VotersReward    *hexutil.Big   `json:"votersReward"`    // Rewards shared by all the voters
+	VoterShare      *hexutil.Big   `json:"voterShare,omitempty"`
+	Stake           *hexutil.Big   `json:"stake"` // Deposit of the validator or votes of the voter
+	APR             float64        `json:"apr"`   // Annualized yield of the stake
+
+	// Approximate is set if the epoch is neither indexed nor its state available
+	// anymore, and the stakes were read from the latest state instead.
+	Approximate bool `json:"approximate,omitempty"`
+}
+
+// epochSeal is the block production record of a whole epoch.
+type epochSeal struct {
+	sealers map[common.Address]uint64 // Number of blocks sealed by each validator
+	seconds uint64                    // Time elapsed during the epoch
+}
+
+// rewardKey identifies the rewards of an account in a role in an epoch of a chain.
+type rewardKey struct {
+	addr common.Address
+	role string
+	last common.Hash // Hash of the last block of the epoch
+}
+
+// epochBlocks returns the first and last blocks of the reward epoch, the inverse
+// of DposConfig.EpochNumber.
+func (d *Dpos) epochBlocks(epoch uint64) (uint64, uint64) {
+	first := epoch * d.config.Epoch
+	return first, first + d.config.Epoch - 1
+}
+
+// sealEpoch counts the blocks sealed by each validator from the given first block
+// up to the last header, which is the end of the epoch unless it's still open.
+//
+// The counts are the liveness records of the snapshot at the last header. If the
+// records miss blocks, e.g. the snapshot was stored before they were introduced,
+// the headers are walked instead, charging them to the budget of the query.
+func (d *Dpos) sealEpoch(chain consensus.ChainHeaderReader, first uint64, last *types.Header, budget *uint64) (*epochSeal, error) {
+	if seal, ok := d.rewardEpochs.Get(last.Hash()); ok {
+		return seal.(*epochSeal), nil
+	}
+	snap, err := d.snapshot(chain, last.Number.Uint64(), last.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		seal     = &epochSeal{sealers: make(map[common.Address]uint64)}
+		produced uint64
+		sealed   = last.Number.Uint64() - first + 1
+	)
+	if first == 0 {
+		sealed-- // The genesis block is not sealed
+	}
+	for validator, record := range snap.Liveness[d.config.EpochNumber(first)] {
+		seal.sealers[validator] = record.Produced
+		produced += record.Produced
+	}
+	if produced != sealed {
+		if sealed > *budget {
+			return nil, errRewardHistoryTooLong
+		}
+		*budget -= sealed
+
+		seal.sealers = make(map[common.Address]uint64)
+		for header := last; header.Number.Uint64() >= first && header.Number.Sign() > 0; {
+			seal.sealers[header.Coinbase]++
+			if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+				return nil, errUnknownBlock
+			}
+		}
+	}
+	start := chain.GetHeaderByNumber(first)
+	if first > 0 {
+		start = chain.GetHeaderByNumber(first - 1)
+	}
+	if start == nil {
+		return nil, errUnknownBlock
+	}
+	seal.seconds = last.Time - start.Time
+	d.rewardEpochs.Add(last.Hash(), seal)
+	return seal, nil
+}
+
+// indexedSeal returns the block production record of the epoch closed by the
+// checkpoint of the index, nil if not indexed.
+func indexedSeal(index *EpochIndex) *epochSeal {
+	if index == nil || index.Seconds == 0 {
+		return nil
+	}
+	seal := &epochSeal{sealers: make(map[common.Address]uint64), seconds: index.Seconds}
+	for _, sealer := range index.Sealers {
+		seal.sealers[sealer.Address] = sealer.Blocks
+	}
+	return seal
+}
+
+// splitReward splits the rewards a validator earned in an epoch into the part
+// minted per sealed block and the part collected from the transaction fees.
+func splitReward(total *big.Int, perBlock *big.Int, blocks uint64) (*big.Int, *big.Int) {
+	minted := new(big.Int).Mul(perBlock, new(big.Int).SetUint64(blocks))
+	minted.Sub(minted, new(big.Int).Div(new(big.Int).Mul(minted, big.NewInt(foundationRewardPercent)), big.NewInt(100)))
+	if minted.Cmp(total) > 0 {
+		minted.Set(total)
+	}
+	return minted, new(big.Int).Sub(total, minted)
+}
+
+// annualize returns the yearly yield of the stake earning the reward over the
+// given number of seconds.
+func annualize(reward *big.Int, stake *big.Int, seconds uint64) float64 {
+	if stake.Sign() <= 0 || seconds == 0 {
+		return 0
+	}
+	yield := new(big.Float).Quo(new(big.Float).SetInt(reward), new(big.Float).SetInt(stake))
+	yield.Mul(yield, new(big.Float).SetFloat64(float64(secondsPerYear)/float64(seconds)))
+	apr, _ := yield.Float64()
+	return apr
+}
+
+// voterInfos returns the votes of the voter for every validator it voted for.
+func (d *Dpos) voterInfos(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, voter common.Address) ([]systemcontract.VotesRewardRedeemInfo, error) {
+	nodeVotes := systemcontract.NewNodeVotes()
+	count, err := nodeVotes.VoteListLength(statedb, header, newChainContext(chain, d), d.chainConfig, voter)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		size  = big.NewInt(50)
+		infos []systemcontract.VotesRewardRedeemInfo
+	)
+	for page := int64(1); new(big.Int).Mul(big.NewInt(page-1), size).Cmp(count) < 0; page++ {
+		list, err := nodeVotes.VotesRewardRedeemInfoWithPage(statedb, header, newChainContext(chain, d), d.chainConfig, voter, big.NewInt(page), size)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, list...)
+	}
+	return infos, nil
+}
+
+// epochRole returns whether the account staked as a validator in the epoch, as
+// of its checkpoint if indexed.
+func (d *Dpos) epochRole(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, start *EpochIndex, addr common.Address) (bool, error) {
+	if start != nil {
+		record, ok := start.record(addr)
+		return ok && record.Validator.Deposit != nil && record.Validator.Deposit.Sign() > 0, nil
+	}
+	validator, err := systemcontract.NewValidators().GetValidator(statedb, header, newChainContext(chain, d), d.chainConfig, addr)
+	if err != nil {
+		return false, err
+	}
+	return validator.Deposit != nil && validator.Deposit.Sign() > 0, nil
+}
+
+// epochRewards returns the rewards the account earned in the epoch, as of the
+// given head, as a validator and as a voter. The rewards of closed epochs are
+// cached.
+//
+// The role, the stake and the block reward of the epoch are read from the index
+// of its checkpoint, its rewards and sealed blocks from the index of the next
+// checkpoint. The state at the end of the epoch answers the rest, or the latest
+// one if pruned.
+func (d *Dpos) epochRewards(chain consensus.ChainHeaderReader, head *types.Header, headState *state.StateDB, addr common.Address, epoch uint64, budget *uint64) ([]*EpochReward, error) {
+	first, last := d.epochBlocks(epoch)
+	if first > head.Number.Uint64() {
+		return nil, errInvalidEpochRange
+	}
+	var (
+		closed  = last <= head.Number.Uint64()
+		lastHdr = head
+		statedb = headState.Copy()
+		approx  bool
+		start   = d.readEpochIndex(chain, first)
+		end     *EpochIndex
+	)
+	if closed {
+		if lastHdr = chain.GetHeaderByNumber(last); lastHdr == nil {
+			return nil, errUnknownBlock
+		}
+		validatorKey, voterKey := rewardKey{addr, rewardRoleValidator, lastHdr.Hash()}, rewardKey{addr, rewardRoleVoter, lastHdr.Hash()}
+		if validator, ok := d.rewardCache.Get(validatorKey); ok {
+			if voter, ok := d.rewardCache.Get(voterKey); ok {
+				return append(append([]*EpochReward{}, validator.([]*EpochReward)...), voter.([]*EpochReward)...), nil
+			}
+		}
+		if exact, err := d.stateFn(lastHdr.Root); err == nil {
+			statedb = exact
+		} else {
+			// Pruned state, the rewards are kept forever but the stakes changed since
+			approx = true
+		}
+		if last < head.Number.Uint64() {
+			end = d.readEpochIndex(chain, last+1)
+		}
+	}
+	seal := indexedSeal(end)
+	if seal == nil {
+		var err error
+		if seal, err = d.sealEpoch(chain, first, lastHdr, budget); err != nil {
+			return nil, err
+		}
+	}
+	var (
+		chainContext  = newChainContext(chain, d)
+		systemRewards = systemcontract.NewSystemRewards()
+		epochNum      = new(big.Int).SetUint64(epoch)
+		blockReward   *big.Int
+	)
+	if start != nil {
+		blockReward = start.Info.BlockReward
+	} else {
+		epochInfo, err := systemRewards.GetEpochInfo(statedb, lastHdr, chainContext, d.chainConfig, epochNum)
+		if err != nil {
+			return nil, err
+		}
+		blockReward = epochInfo.BlockReward
+	}
+	// earned fills in the rewards of the validator in the epoch
+	earned := func(val common.Address, role string) (*EpochReward, error) {
+		var reward *systemcontract.Reward
+		if record, ok := end.record(val); ok {
+			reward = &record.Reward
+		} else {
+			var err error
+			if reward, err = systemRewards.GetValRewardInfoByEpoch(statedb, lastHdr, chainContext, d.chainConfig, val, epochNum); err != nil {
+				return nil, err
+			}
+		}
+		var (
+			blocks           = seal.sealers[val]
+			total            = new(big.Int).Add(reward.ValidatorReward, reward.DelegatorsReward)
+			minted, feeShare = splitReward(total, blockReward, blocks)
+		)
+		return &EpochReward{
+			Epoch:           epoch,
+			Role:            role,
+			Validator:       val,
+			Blocks:          blocks,
+			BlockReward:     (*hexutil.Big)(minted),
+			FeeShare:        (*hexutil.Big)(feeShare),
+			Rate:            reward.Rate,
+			ValidatorReward: (*hexutil.Big)(reward.ValidatorReward),
+			VotersReward:    (*hexutil.Big)(reward.DelegatorsReward),
+		}, nil
+	}
+	isValidator, err := d.epochRole(chain, lastHdr, statedb, start, addr)
+	if err != nil {
+		return nil, err
+	}
+	var validatorRewards []*EpochReward
+	if isValidator {
+		reward, err := earned(addr, rewardRoleValidator)
+		if err != nil {
+			return nil, err
+		}
+		if record, ok := start.record(addr); ok {
+			reward.Stake = (*hexutil.Big)(record.Validator.Deposit)
+		} else {
+			validator, err := systemcontract.NewValidators().GetValidator(statedb, lastHdr, chainContext, d.chainConfig, addr)
+			if err != nil {
+				return nil, err
+			}
+			reward.Stake, reward.Approximate = (*hexutil.Big)(validator.Deposit), approx
+		}
+		reward.APR = annualize(reward.ValidatorReward.ToInt(), reward.Stake.ToInt(), seal.seconds)
+		validatorRewards = append(validatorRewards, reward)
+	}
+	// The votes are not indexed, they are read from the state
+	infos, err := d.voterInfos(chain, lastHdr, statedb, addr)
+	if err != nil {
+		return nil, err
+	}
+	var voterRewards []*EpochReward
+	for _, info := range infos {
+		if info.Amount == nil || info.Amount.Sign() == 0 {
+			continue
+		}
+		reward, err := earned(info.Validator, rewardRoleVoter)
+		if err != nil {
+			return nil, err
+		}
+		share := new(big.Int)
+		if info.ValidatorTotalVotes.Sign() > 0 {
+			share.Mul(reward.VotersReward.ToInt(), info.Amount)
+			share.Div(share, info.ValidatorTotalVotes)
+		}
+		reward.VoterShare = (*hexutil.Big)(share)
+		reward.Stake = (*hexutil.Big)(info.Amount)
+		reward.APR = annualize(share, info.Amount, seal.seconds)
+		reward.Approximate = approx
+		voterRewards = append(voterRewards, reward)
+	}
+	if closed {
+		if start != nil || !approx {
+			d.rewardCache.Add(rewardKey{addr, rewardRoleValidator, lastHdr.Hash()}, validatorRewards)
+		}
+		if !approx {
+			d.rewardCache.Add(rewardKey{addr, rewardRoleVoter, lastHdr.Hash()}, voterRewards)
+		}
+	}
+	return append(validatorRewards, voterRewards...), nil
+}
+
+// rewardHistory walks the epochs of the given range, returning the rewards the
+// account earned in each of them, in the roles it had in the epoch.
+func (d *Dpos) rewardHistory(chain consensus.ChainHeaderReader, head *types.Header, headState *state.StateDB, addr common.Address, from, to uint64) (*RewardHistory, error) {
+	if from > to || to-from >= maxRewardHistoryEpochs || to > d.config.EpochNumber(head.Number.Uint64()) {
+		return nil, fmt.Errorf("%w: %d-%d, at most %d epochs up to the current one", errInvalidEpochRange, from, to, maxRewardHistoryEpochs)
+	}
+	history := &RewardHistory{Address: addr, Epochs: []*EpochReward{}}
+	budget := uint64(maxRewardHistoryHeaders)
+	for epoch := from; epoch <= to; epoch++ {
+		rewards, err := d.epochRewards(chain, head, headState, addr, epoch, &budget)
+		if err != nil {
+			return nil, err
+		}
+		history.Epochs = append(history.Epochs, rewards...)
+	}
+	return history, nil
+}
@@ -0,0 +1,147 @@
+package dpos
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+	"github.com/DxChainNetwork/dxc/rlp"
+)
+
+func TestRewardHistoryMath(t *testing.T) {
+	d := &Dpos{config: &params.DposConfig{Period: 6, Epoch: 100, Forks: []params.DposForkConfig{{Block: big.NewInt(1000), Period: 3}}}}
+	for _, epoch := range []uint64{0, 9, 10, 11} {
+		first, last := d.epochBlocks(epoch)
+		if d.config.EpochNumber(first) != epoch || d.config.EpochNumber(last) != epoch || d.config.EpochNumber(last+1) != epoch+1 {
+			t.Errorf("epoch %d: blocks %d-%d out of the epoch", epoch, first, last)
+		}
+	}
+	// 10 blocks minting 100 each, 5% of which go to the foundation
+	minted, fees := splitReward(big.NewInt(1200), big.NewInt(100), 10)
+	if minted.Cmp(big.NewInt(950)) != 0 || fees.Cmp(big.NewInt(250)) != 0 {
+		t.Errorf("split mismatch: have %v/%v, want 950/250", minted, fees)
+	}
+	if minted, fees = splitReward(big.NewInt(500), big.NewInt(100), 10); minted.Cmp(big.NewInt(500)) != 0 || fees.Sign() != 0 {
+		t.Errorf("split exceeding total: have %v/%v", minted, fees)
+	}
+	if apr := annualize(big.NewInt(1), big.NewInt(100), secondsPerYear/2); apr < 0.0199 || apr > 0.0201 {
+		t.Errorf("apr mismatch: have %v, want 0.02", apr)
+	}
+	if apr := annualize(big.NewInt(1), new(big.Int), secondsPerYear); apr != 0 {
+		t.Errorf("apr of empty stake: have %v", apr)
+	}
+}
+
+func TestSealEpoch(t *testing.T) {
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+
+	var (
+		a, b  = common.HexToAddress("0x1001"), common.HexToAddress("0x1002")
+		chain = make(testHeaderChain, 30)
+	)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i)), Coinbase: a, Time: uint64(3 * i)}
+		if i%3 == 0 {
+			chain[i].Coinbase = b
+		}
+	}
+	// The liveness records of the snapshot are used as is
+	snap := newSnapshot(engine.config, engine.signatures, 19, chain[19].Hash(), []common.Address{a, b}, nil)
+	snap.Liveness = map[uint64]map[common.Address]*Liveness{1: {a: {Produced: 4}, b: {Produced: 6}}}
+	engine.recents.Add(chain[19].Hash(), snap)
+
+	budget := uint64(0)
+	seal, err := engine.sealEpoch(chain, 10, chain[19], &budget)
+	if err != nil {
+		t.Fatalf("failed to count the sealed blocks: %v", err)
+	}
+	if seal.sealers[a] != 4 || seal.sealers[b] != 6 || seal.seconds != 30 {
+		t.Errorf("seal mismatch: have %v/%d, want 4/6/30", seal.sealers, seal.seconds)
+	}
+	// Incomplete records are replaced by walking the headers within the budget
+	snap = newSnapshot(engine.config, engine.signatures, 29, chain[29].Hash(), []common.Address{a, b}, nil)
+	snap.Liveness = map[uint64]map[common.Address]*Liveness{2: {a: {Produced: 1}}}
+	engine.recents.Add(chain[29].Hash(), snap)
+
+	if _, err := engine.sealEpoch(chain, 20, chain[29], &budget); err != errRewardHistoryTooLong {
+		t.Fatalf("error mismatch: have %v, want %v", err, errRewardHistoryTooLong)
+	}
+	budget = 15
+	if seal, err = engine.sealEpoch(chain, 20, chain[29], &budget); err != nil {
+		t.Fatalf("failed to count the sealed blocks: %v", err)
+	}
+	if seal.sealers[a] != 7 || seal.sealers[b] != 3 || budget != 5 {
+		t.Errorf("walked seal mismatch: have %v, budget %d, want 7/3, budget 5", seal.sealers, budget)
+	}
+}
+
+func TestIndexedEpochSeal(t *testing.T) {
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+
+	var (
+		a, b  = common.HexToAddress("0x1001"), common.HexToAddress("0x1002")
+		chain = make(testHeaderChain, 21)
+	)
+	for i := range chain {
+		chain[i] = &types.Header{Number: big.NewInt(int64(i)), Coinbase: a, Time: uint64(3 * i)}
+		if i%2 == 0 {
+			chain[i].Coinbase = b
+		}
+	}
+	engine.recents.Add(chain[19].Hash(), newSnapshot(engine.config, engine.signatures, 19, chain[19].Hash(), []common.Address{a, b}, nil))
+
+	// The checkpoint indexes the blocks sealed in the epoch it closes, walking its
+	// headers if missing from the liveness records
+	index := &EpochIndex{Epoch: 2}
+	if err := engine.collectEpochSeal(chain, chain[20], index); err != nil {
+		t.Fatalf("failed to collect the sealed blocks: %v", err)
+	}
+	want := []EpochIndexSealer{{Address: a, Blocks: 5}, {Address: b, Blocks: 5}}
+	if !reflect.DeepEqual(index.Sealers, want) || index.Seconds != 30 {
+		t.Fatalf("sealers mismatch: have %v/%d, want %v/30", index.Sealers, index.Seconds, want)
+	}
+	blob, err := rlp.EncodeToBytes(index)
+	if err != nil {
+		t.Fatalf("failed to encode index: %v", err)
+	}
+	decoded := new(EpochIndex)
+	if err := rlp.DecodeBytes(blob, decoded); err != nil {
+		t.Fatalf("failed to decode index: %v", err)
+	}
+	if seal := indexedSeal(decoded); seal == nil || seal.sealers[a] != 5 || seal.sealers[b] != 5 || seal.seconds != 30 {
+		t.Errorf("indexed seal mismatch: have %+v", seal)
+	}
+	// The indexes stored without the seals are still decoded, and have none
+	blob, _ = rlp.EncodeToBytes(&EpochIndex{Epoch: 1, Info: systemcontract.EpochInfo{BlockReward: new(big.Int), Tvl: new(big.Int), ValidatorCount: new(big.Int), EffictiveValCount: new(big.Int)}, TotalDeposit: new(big.Int), TotalVotes: new(big.Int)})
+	decoded = new(EpochIndex)
+	if err := rlp.DecodeBytes(blob, decoded); err != nil {
+		t.Fatalf("failed to decode index without seals: %v", err)
+	}
+	if seal := indexedSeal(decoded); seal != nil {
+		t.Errorf("seal of an index without seals: %+v", seal)
+	}
+}
+
+func TestEpochRole(t *testing.T) {
+	engine := &Dpos{config: &params.DposConfig{Epoch: 10}}
+	var (
+		validator = common.HexToAddress("0x1001")
+		left      = common.HexToAddress("0x1002")
+		voter     = common.HexToAddress("0x1003")
+	)
+	start := &EpochIndex{Records: []EpochIndexRecord{
+		{Address: validator, Validator: systemcontract.Validator{Deposit: big.NewInt(100)}},
+		{Address: left, Validator: systemcontract.Validator{Deposit: new(big.Int)}},
+	}}
+	// The role is the one of the epoch's checkpoint, whatever the current state
+	for addr, want := range map[common.Address]bool{validator: true, left: false, voter: false} {
+		if have, err := engine.epochRole(nil, nil, nil, start, addr); err != nil || have != want {
+			t.Errorf("%x: role mismatch: have %v/%v, want %v", addr, have, err, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package dpos
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+const (
+	weightLength = 8 // Fixed number of bytes for a validator weight in a weighted checkpoint
+)
+
+// weightUnit is the amount of staked wei equal to one unit of scheduling weight (1 DX).
+var weightUnit = big.NewInt(1e18)
+
+// isWeightedCheckpoint returns whether the checkpoint header at the given number
+// carries validator weights besides the validator list. The genesis checkpoint
+// never carries weights.
+func isWeightedCheckpoint(config *params.DposConfig, number uint64) bool {
+	return number > 0 && config.IsWeightedSchedule(new(big.Int).SetUint64(number))
+}
+
+// isKeyedCheckpoint returns whether the checkpoint header at the given number
+// carries the signing key of each validator after the validator list.
+func isKeyedCheckpoint(config *params.DposConfig, number uint64) bool {
+	return number > 0 && config.IsSigningKeys(new(big.Int).SetUint64(number))
+}
+
+// checkpointEntryLength returns the number of bytes each validator takes in the
+// extra-data of the checkpoint header at the given number.
+func (d *Dpos) checkpointEntryLength(number uint64) int {
+	length := common.AddressLength
+	if isWeightedCheckpoint(d.config, number) {
+		length += weightLength
+	}
+	if isKeyedCheckpoint(d.config, number) {
+		length += common.AddressLength
+	}
+	return length
+}
+
+// encodeCheckpointValidators encodes the validator list of a checkpoint header,
+// each validator followed by its weight if weights are given.
+func encodeCheckpointValidators(validators []common.Address, weights []uint64) []byte {
+	entry := common.AddressLength
+	if weights != nil {
+		entry += weightLength
+	}
+	data := make([]byte, len(validators)*entry)
+	for i, validator := range validators {
+		copy(data[i*entry:], validator.Bytes())
+		if weights != nil {
+			binary.BigEndian.PutUint64(data[i*entry+common.AddressLength:], weights[i])
+		}
+	}
+	return data
+}
+
+// encodeCheckpointSigners encodes the signing keys of a checkpoint header, which
+// follow the validator list in the same order.
+func encodeCheckpointSigners(signers []common.Address) []byte {
+	data := make([]byte, 0, len(signers)*common.AddressLength)
+	for _, signer := range signers {
+		data = append(data, signer.Bytes()...)
+	}
+	return data
+}
+
+// parseCheckpointValidators extracts the validator list, the weights if the
+// checkpoint is a weighted one and the signing keys if it's a keyed one, from the
+// extra-data of a checkpoint header.
+func parseCheckpointValidators(header *types.Header, weighted bool, keyed bool) ([]common.Address, []uint64, []common.Address) {
+	entry := common.AddressLength
+	if weighted {
+		entry += weightLength
+	}
+	data := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+
+	count := len(data) / entry
+	if keyed {
+		count = len(data) / (entry + common.AddressLength)
+	}
+	validators := make([]common.Address, count)
+	var weights []uint64
+	if weighted {
+		weights = make([]uint64, len(validators))
+	}
+	for i := 0; i < len(validators); i++ {
+		copy(validators[i][:], data[i*entry:])
+		if weighted {
+			weights[i] = binary.BigEndian.Uint64(data[i*entry+common.AddressLength:])
+		}
+	}
+	var signers []common.Address
+	if keyed {
+		signers = make([]common.Address, len(validators))
+		for i, keys := 0, data[count*entry:]; i < len(signers); i++ {
+			copy(signers[i][:], keys[i*common.AddressLength:])
+		}
+	}
+	return validators, weights, signers
+}
+
+// getCurEpochWeights retrieves the scheduling weight of each validator, which is
+// its deposit plus the votes it received, in units of whole DX.
+func (d *Dpos) getCurEpochWeights(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB, validators []common.Address) ([]uint64, error) {
+	contract := systemcontract.NewValidators()
+
+	weights := make([]uint64, len(validators))
+	for i, validator := range validators {
+		info, err := contract.GetValidator(statedb, header, newChainContext(chain, d), d.chainConfig, validator)
+		if err != nil {
+			return nil, err
+		}
+		stake := new(big.Int)
+		if info.Deposit != nil {
+			stake.Add(stake, info.Deposit)
+		}
+		if info.Votes != nil {
+			stake.Add(stake, info.Votes)
+		}
+		stake.Div(stake, weightUnit)
+
+		switch {
+		case stake.Sign() == 0:
+			weights[i] = 1
+		case !stake.IsUint64():
+			weights[i] = math.MaxUint64
+		default:
+			weights[i] = stake.Uint64()
+		}
+	}
+	return weights, nil
+}
+
+// buildSchedule derives the in-turn slot sequence of an epoch from the validator
+// weights, using the smooth weighted round-robin algorithm over the validators in
+// ascending order. A validator is never scheduled again within the window of
+// blocks it's not allowed to sign in because of the recents rule, so that heavy
+// validators don't get slots they can't use.
+func buildSchedule(validators []common.Address, weights map[common.Address]uint64, length uint64) []common.Address {
+	if len(validators) == 0 {
+		return nil
+	}
+	var (
+		schedule = make([]common.Address, length)
+		current  = make([]*big.Int, len(validators))
+		weight   = make([]*big.Int, len(validators))
+		total    = new(big.Int)
+		last     = make(map[common.Address]uint64)
+		window   = uint64(len(validators)/2 + 1)
+	)
+	for i, validator := range validators {
+		weight[i] = new(big.Int).SetUint64(weights[validator])
+		if weight[i].Sign() == 0 {
+			weight[i].SetUint64(1)
+		}
+		current[i] = new(big.Int)
+		total.Add(total, weight[i])
+	}
+	for slot := uint64(0); slot < length; slot++ {
+		best := -1
+		for i, validator := range validators {
+			current[i].Add(current[i], weight[i])
+			if seen, ok := last[validator]; ok && slot-seen < window {
+				continue
+			}
+			if best < 0 || current[i].Cmp(current[best]) > 0 {
+				best = i
+			}
+		}
+		current[best].Sub(current[best], total)
+		schedule[slot] = validators[best]
+		last[validators[best]] = slot
+	}
+	return schedule
+}
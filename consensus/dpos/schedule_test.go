@@ -0,0 +1,62 @@
+package dpos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestBuildSchedule(t *testing.T) {
+	validators := []common.Address{{0x01}, {0x02}, {0x03}, {0x04}, {0x05}}
+	weights := map[common.Address]uint64{
+		validators[0]: 400,
+		validators[1]: 100,
+		validators[2]: 100,
+		validators[3]: 100,
+		validators[4]: 100,
+	}
+	schedule := buildSchedule(validators, weights, 1000)
+
+	// No validator may be scheduled within its recents window
+	window := len(validators)/2 + 1
+	for i := range schedule {
+		for j := i + 1; j < i+window && j < len(schedule); j++ {
+			if schedule[i] == schedule[j] {
+				t.Fatalf("validator %x scheduled at slots %d and %d", schedule[i], i, j)
+			}
+		}
+	}
+	// The heavy validator gets more slots than the light ones
+	slots := make(map[common.Address]int)
+	for _, validator := range schedule {
+		slots[validator]++
+	}
+	for _, validator := range validators[1:] {
+		if slots[validators[0]] <= slots[validator] {
+			t.Fatalf("slot count mismatch: heavy %d, light %d", slots[validators[0]], slots[validator])
+		}
+	}
+}
+
+func TestReloadSnapshotSchedule(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	config := &params.DposConfig{Epoch: 10}
+	validators := []common.Address{{0x01}, {0x02}, {0x03}}
+
+	// A snapshot stored within an epoch schedules like the one of its checkpoint
+	snap := newSnapshot(config, nil, 20, common.Hash{0x20}, validators, []uint64{300, 100, 100})
+	snap.Number, snap.Hash = 25, common.Hash{0x25}
+	if err := snap.store(db); err != nil {
+		t.Fatalf("failed to store snapshot: %v", err)
+	}
+	loaded, err := loadSnapshot(config, nil, db, snap.Hash)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.schedule, snap.schedule) {
+		t.Fatalf("schedule mismatch: have %x, want %x", loaded.schedule, snap.schedule)
+	}
+}
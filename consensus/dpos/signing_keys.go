@@ -0,0 +1,247 @@
+package dpos
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+// Storage layout of the signing keys contract, the first four slots are the
+// positions of mappings keyed by address.
+var (
+	signingKeySlot       = common.BigToHash(common.Big0)   // Owner to active signing key
+	signingKeyOwnerSlot  = common.BigToHash(common.Big1)   // Active signing key to owner
+	pendingKeySlot       = common.BigToHash(common.Big2)   // Owner to signing key requested for the next epoch
+	pendingKeyOwnerSlot  = common.BigToHash(common.Big3)   // Requested signing key to owner
+	pendingOwnersLenSlot = common.BigToHash(big.NewInt(4)) // Number of owners with a request, listed from keccak(slot)
+)
+
+// maxSigningKeyRequests is the maximum number of signing key requests pending for
+// the next checkpoint, further ones are refused by the contract.
+const maxSigningKeyRequests = 256
+
+// errSigningKeyUnstaked is returned if the signing key is requested by an owner
+// which is neither a validator nor a candidate.
+var errSigningKeyUnstaked = errors.New("signing key owner not staked")
+
+// signingKeyRequestedTopic is the topic of the SigningKeyRequested(owner, key) event.
+var signingKeyRequestedTopic = crypto.Keccak256Hash([]byte("SigningKeyRequested(address,address)"))
+
+// Memory layout of the setSigningKey method, past the scratch space of the mapping
+// slots and the calls.
+const (
+	signingKeyOwnerMem = 0x80 // Requesting owner
+	signingKeyMem      = 0xa0 // Requested key
+)
+
+// signingKeysCode is the runtime code of the signing keys contract. It records the
+// requests of the staked accounts, which the engine moves in place at the next
+// checkpoint:
+//
+//	function setSigningKey(address key) external;               // emits SigningKeyRequested(msg.sender, key), zero resets
+//	function signingKeyOf(address owner) external view returns (address);
+//	function ownerOf(address key) external view returns (address);
+//	function pendingSigningKeyOf(address owner) external view returns (address);
+var signingKeysCode = assembleContract(
+	contractMethod{"setSigningKey(address)", assembleSetSigningKey},
+	contractMethod{"signingKeyOf(address)", func(c *contractCode) {
+		c.addressArg(0).mappingSlot(signingKeySlot).op(vm.SLOAD).returnWord()
+	}},
+	contractMethod{"ownerOf(address)", func(c *contractCode) {
+		c.addressArg(0).mappingSlot(signingKeyOwnerSlot).op(vm.SLOAD).returnWord()
+	}},
+	contractMethod{"pendingSigningKeyOf(address)", func(c *contractCode) {
+		c.addressArg(0).mappingSlot(pendingKeySlot).op(vm.SLOAD).returnWord()
+	}},
+)
+
+// assembleSetSigningKey assembles the setSigningKey method, which records the
+// request of the caller to seal with the key from the next checkpoint on. It
+// reverts unless the caller is a validator or a candidate, if the caller is the
+// signing key of another owner, if the key is bound or requested to another owner,
+// if the key is a staked validator itself, or if too many requests are pending.
+func assembleSetSigningKey(c *contractCode) {
+	// entry pushes the mapping entry of the address stored at the memory offset
+	entry := func(mem uint64, position common.Hash) {
+		c.push(mem).op(vm.MLOAD).mappingSlot(position).op(vm.SLOAD)
+	}
+	// requireOwner reverts unless the address on the stack is zero or the owner
+	requireOwner := func() {
+		c.op(vm.DUP1, vm.ISZERO, vm.SWAP1).push(signingKeyOwnerMem).op(vm.MLOAD, vm.EQ, vm.OR, vm.ISZERO).revertIf()
+	}
+	// deposit pushes the deposit in the validators contract of the address stored
+	// at the memory offset, from validators(address) returning (status, deposit, ...)
+	deposit := func(mem uint64) {
+		selector := systemcontract.GetInteractiveABI()[systemcontract.ValidatorsContractName].Methods["validators"].ID
+		c.pushBytes(selector).push(0xe0).op(vm.SHL).push(0).op(vm.MSTORE)
+		c.push(mem).op(vm.MLOAD).push(0x04).op(vm.MSTORE)
+		c.push(0x60).push(0).push(0x24).push(0).pushBytes(systemcontract.ValidatorsContractAddr.Bytes()).op(vm.GAS, vm.STATICCALL, vm.ISZERO).revertIf()
+		c.op(vm.RETURNDATASIZE).push(0x60).op(vm.GT).revertIf()
+		c.push(0x40).op(vm.MLOAD)
+	}
+	c.nonPayable()
+	c.op(vm.CALLER).push(signingKeyOwnerMem).op(vm.MSTORE)
+
+	// key := key == 0 ? owner : key
+	c.addressArg(0).op(vm.DUP1).jumpi("keySet").op(vm.POP, vm.CALLER)
+	c.label("keySet").push(signingKeyMem).op(vm.MSTORE)
+
+	// require(validators(owner).deposit > 0)
+	deposit(signingKeyOwnerMem)
+	c.op(vm.ISZERO).revertIf()
+
+	// The owner must not be the signing key of another one
+	entry(signingKeyOwnerMem, signingKeyOwnerSlot)
+	requireOwner()
+	entry(signingKeyOwnerMem, pendingKeyOwnerSlot)
+	requireOwner()
+
+	// Unless reset, the key must be free and not staked
+	c.push(signingKeyMem).op(vm.MLOAD).push(signingKeyOwnerMem).op(vm.MLOAD, vm.EQ).jumpi("keyChecked")
+	entry(signingKeyMem, signingKeyOwnerSlot)
+	requireOwner()
+	entry(signingKeyMem, pendingKeyOwnerSlot)
+	requireOwner()
+	entry(signingKeyMem, signingKeySlot)
+	c.revertIf()
+	entry(signingKeyMem, pendingKeySlot)
+	c.revertIf()
+	deposit(signingKeyMem)
+	c.revertIf()
+	c.label("keyChecked")
+
+	// Replace the pending request of the owner, or list the owner
+	entry(signingKeyOwnerMem, pendingKeySlot)
+	c.op(vm.DUP1, vm.ISZERO).jumpi("list")
+	c.mappingSlot(pendingKeyOwnerSlot).push(0).op(vm.SWAP1, vm.SSTORE)
+	c.jump("listed")
+	c.label("list").op(vm.POP)
+	c.pushHash(pendingOwnersLenSlot).op(vm.SLOAD)
+	c.op(vm.DUP1).push(maxSigningKeyRequests).op(vm.SWAP1, vm.LT, vm.ISZERO).revertIf()
+	c.op(vm.DUP1).pushHash(pendingOwnerSlot(0)).op(vm.ADD).push(signingKeyOwnerMem).op(vm.MLOAD, vm.SWAP1, vm.SSTORE)
+	c.push(1).op(vm.ADD).pushHash(pendingOwnersLenSlot).op(vm.SSTORE)
+	c.label("listed")
+
+	// pendingSigningKeyOf[owner] = key; pendingOwnerOf[key] = owner
+	c.push(signingKeyMem).op(vm.MLOAD).push(signingKeyOwnerMem).op(vm.MLOAD).mappingSlot(pendingKeySlot).op(vm.SSTORE)
+	c.push(signingKeyOwnerMem).op(vm.MLOAD).push(signingKeyMem).op(vm.MLOAD).mappingSlot(pendingKeyOwnerSlot).op(vm.SSTORE)
+
+	// LOG3(0, 0, topic, owner, key)
+	c.push(signingKeyMem).op(vm.MLOAD).push(signingKeyOwnerMem).op(vm.MLOAD).pushHash(signingKeyRequestedTopic)
+	c.push(0).op(vm.DUP1, vm.LOG3, vm.STOP)
+}
+
+// signingKeysMappingSlot returns the storage slot of the address entry of the
+// mapping at the given position.
+func signingKeysMappingSlot(addr common.Address, position common.Hash) common.Hash {
+	return crypto.Keccak256Hash(addr.Hash().Bytes(), position.Bytes())
+}
+
+// pendingOwnerSlot returns the storage slot of the i-th owner with a pending request.
+func pendingOwnerSlot(i uint64) common.Hash {
+	base := crypto.Keccak256Hash(pendingOwnersLenSlot.Bytes()).Big()
+	return common.BigToHash(base.Add(base, new(big.Int).SetUint64(i)))
+}
+
+// readSigningKeyEntry reads the address entry of the mapping at the given position.
+func readSigningKeyEntry(state consensus.StateReader, addr common.Address, position common.Hash) common.Address {
+	return common.BytesToAddress(state.GetState(systemcontract.SigningKeysContractAddr, signingKeysMappingSlot(addr, position)).Bytes())
+}
+
+// writeSigningKeyEntry sets the address entry of the mapping at the given position.
+func writeSigningKeyEntry(state *state.StateDB, addr common.Address, position common.Hash, value common.Address) {
+	state.SetState(systemcontract.SigningKeysContractAddr, signingKeysMappingSlot(addr, position), value.Hash())
+}
+
+// nextSigningKey returns the key the validator seals with from the next checkpoint
+// on, which is the one requested if any, else the active one.
+func nextSigningKey(state consensus.StateReader, validator common.Address) common.Address {
+	if key := readSigningKeyEntry(state, validator, pendingKeySlot); key != (common.Address{}) {
+		return key
+	}
+	if key := readSigningKeyEntry(state, validator, signingKeySlot); key != (common.Address{}) {
+		return key
+	}
+	return validator
+}
+
+// checkpointSigners returns the signing keys of the validators taking effect at the
+// checkpoint, read from the state before the checkpoint.
+func checkpointSigners(state consensus.StateReader, validators []common.Address) []common.Address {
+	signers := make([]common.Address, len(validators))
+	for i, validator := range validators {
+		signers[i] = nextSigningKey(state, validator)
+	}
+	return signers
+}
+
+// activateSigningKeys moves the signing keys requested for the checkpoint in place,
+// dropping the requests of owners which are neither validators nor candidates
+// anymore. Those can't be part of the validator set the checkpoint announces the
+// keys of.
+func (d *Dpos) activateSigningKeys(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB) error {
+	var (
+		contract   = systemcontract.SigningKeysContractAddr
+		validators = systemcontract.NewValidators()
+		number     = header.Number.Uint64()
+	)
+	count := state.GetState(contract, pendingOwnersLenSlot).Big().Uint64()
+	if count > maxSigningKeyRequests {
+		count = maxSigningKeyRequests
+	}
+	for i := uint64(0); i < count; i++ {
+		owner := common.BytesToAddress(state.GetState(contract, pendingOwnerSlot(i)).Bytes())
+		key := readSigningKeyEntry(state, owner, pendingKeySlot)
+
+		writeSigningKeyEntry(state, owner, pendingKeySlot, common.Address{})
+		writeSigningKeyEntry(state, key, pendingKeyOwnerSlot, common.Address{})
+		state.SetState(contract, pendingOwnerSlot(i), common.Hash{})
+
+		val, err := validators.GetValidator(state, header, newChainContext(chain, d), d.chainConfig, owner)
+		if err != nil {
+			return err
+		}
+		if val.Deposit == nil || val.Deposit.Sign() == 0 {
+			log.Debug("Dropped dpos signing key", "number", number, "validator", owner, "key", key, "err", errSigningKeyUnstaked)
+			continue
+		}
+		if prev := readSigningKeyEntry(state, owner, signingKeySlot); prev != (common.Address{}) {
+			writeSigningKeyEntry(state, prev, signingKeyOwnerSlot, common.Address{})
+		}
+		if key == owner {
+			writeSigningKeyEntry(state, owner, signingKeySlot, common.Address{})
+		} else {
+			writeSigningKeyEntry(state, owner, signingKeySlot, key)
+			writeSigningKeyEntry(state, key, signingKeyOwnerSlot, owner)
+		}
+		log.Info("Dpos signing key changed", "number", number, "validator", owner, "key", key)
+	}
+	if count > 0 {
+		state.SetState(contract, pendingOwnersLenSlot, common.Hash{})
+	}
+	return nil
+}
+
+// applySigningKeys maintains the signing keys contract: it's installed at the fork
+// block, and the keys requested through it are moved in place at the checkpoints.
+// Code replacing it is left in place.
+func (d *Dpos) applySigningKeys(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB) error {
+	if !d.config.IsSigningKeys(header.Number) {
+		return nil
+	}
+	if state.GetCodeSize(systemcontract.SigningKeysContractAddr) == 0 {
+		state.SetCode(systemcontract.SigningKeysContractAddr, signingKeysCode)
+	}
+	if d.config.IsCheckpoint(header.Number.Uint64()) {
+		return d.activateSigningKeys(chain, header, state)
+	}
+	return nil
+}
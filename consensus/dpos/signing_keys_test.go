@@ -0,0 +1,135 @@
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/core/vm"
+	"github.com/DxChainNetwork/dxc/core/vm/runtime"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+// newValidatorsStubCode returns a Validators stub answering validators(address)
+// with the deposit stored in the slot of the address.
+func newValidatorsStubCode(t *testing.T) []byte {
+	abi := systemcontract.GetInteractiveABI()[systemcontract.ValidatorsContractName]
+	info, err := abi.Methods["validators"].Outputs.Pack(systemcontract.Validator{
+		Deposit: new(big.Int), Votes: new(big.Int), UnstakeLockingEndBlock: new(big.Int), RateSettLockingEndBlock: new(big.Int),
+	})
+	if err != nil {
+		t.Fatalf("failed to pack validator: %v", err)
+	}
+	// The encoded validator is laid out past the jump destination ending the code
+	code := assembleContract(contractMethod{"validators(address)", func(c *contractCode) {
+		c.push(uint64(len(info))).pushLabel("info").push(1).op(vm.ADD).push(0).op(vm.CODECOPY)
+		c.addressArg(0).op(vm.SLOAD).push(0x40).op(vm.MSTORE)
+		c.push(uint64(len(info))).push(0).op(vm.RETURN)
+		c.label("info")
+	}})
+	return append(code, info...)
+}
+
+func TestSigningKeys(t *testing.T) {
+	config := *params.TestChainConfig
+	config.BerlinBlock, config.LondonBlock = nil, nil
+	config.Dpos = &params.DposConfig{Epoch: 10, SigningKeysBlock: big.NewInt(0)}
+	engine := New(&config, rawdb.NewMemoryDatabase())
+
+	var (
+		owner, other, unstaked = common.Address{0x01}, common.Address{0x02}, common.Address{0x03}
+		validator              = common.Address{0x04}
+		key, otherKey          = common.Address{0xaa}, common.Address{0xbb}
+	)
+	statedb := newTestState()
+	statedb.SetCode(systemcontract.ValidatorsContractAddr, newValidatorsStubCode(t))
+	for _, staked := range []common.Address{owner, other, validator} {
+		statedb.SetState(systemcontract.ValidatorsContractAddr, staked.Hash(), common.BigToHash(common.Big1))
+	}
+	header := func(number int64) *types.Header {
+		return &types.Header{Number: big.NewInt(number), GasLimit: 8000000, Difficulty: diffInTurn}
+	}
+	if err := engine.applySigningKeys(nil, header(5), statedb); err != nil {
+		t.Fatalf("failed to install signing keys contract: %v", err)
+	}
+	request := func(from, key common.Address) error {
+		input, _ := systemcontract.GetInteractiveABI()[systemcontract.SigningKeysContractName].Pack("setSigningKey", key)
+		_, _, err := runtime.Call(systemcontract.SigningKeysContractAddr, input, &runtime.Config{State: statedb, Origin: from})
+		return err
+	}
+	if err := request(owner, key); err != nil {
+		t.Fatalf("failed to request signing key: %v", err)
+	}
+	for i, tt := range []struct{ from, key common.Address }{
+		{unstaked, otherKey}, // owner neither validator nor candidate
+		{other, key},         // key requested by another owner
+		{other, validator},   // key staked
+		{key, otherKey},      // owner is a signing key
+	} {
+		if err := request(tt.from, tt.key); err != vm.ErrExecutionReverted {
+			t.Errorf("request %d: error mismatch: have %v, want %v", i, err, vm.ErrExecutionReverted)
+		}
+	}
+	// The request of an owner unstaking before the checkpoint is dropped
+	if err := request(other, otherKey); err != nil {
+		t.Fatalf("failed to request signing key: %v", err)
+	}
+	if err := request(other, otherKey); err != nil {
+		t.Fatalf("failed to repeat signing key request: %v", err)
+	}
+	if have := statedb.GetState(systemcontract.SigningKeysContractAddr, pendingOwnersLenSlot).Big(); have.Uint64() != 2 {
+		t.Fatalf("pending requests mismatch: have %v, want 2", have)
+	}
+	statedb.SetState(systemcontract.ValidatorsContractAddr, other.Hash(), common.Hash{})
+
+	// The requested key is announced by the next checkpoint and activated by it
+	signers := checkpointSigners(statedb, []common.Address{owner, unstaked})
+	if signers[0] != key || signers[1] != unstaked {
+		t.Fatalf("checkpoint signers mismatch: have %x", signers)
+	}
+	if err := engine.applySigningKeys(nil, header(10), statedb); err != nil {
+		t.Fatalf("failed to apply signing keys: %v", err)
+	}
+	for _, tt := range []struct {
+		method string
+		arg    common.Address
+		want   common.Address
+	}{
+		{"signingKeyOf", owner, key},
+		{"ownerOf", key, owner},
+		{"pendingSigningKeyOf", owner, common.Address{}},
+		{"signingKeyOf", other, common.Address{}},
+		{"pendingSigningKeyOf", other, common.Address{}},
+	} {
+		ret := callContract(t, &runtime.Config{State: statedb}, systemcontract.SigningKeysContractName, systemcontract.SigningKeysContractAddr, tt.method, tt.arg)
+		if have := common.BytesToAddress(ret); have != tt.want {
+			t.Errorf("%s(%x): value mismatch: have %x, want %x", tt.method, tt.arg, have, tt.want)
+		}
+	}
+	// The pending requests are capped
+	statedb.SetState(systemcontract.SigningKeysContractAddr, pendingOwnersLenSlot, common.BigToHash(big.NewInt(maxSigningKeyRequests)))
+	if err := request(owner, otherKey); err != vm.ErrExecutionReverted {
+		t.Errorf("error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	}
+	// The validators of the keyed checkpoint are resolved from their signing keys
+	checkpoint := header(10)
+	checkpoint.Extra = append(make([]byte, extraVanity), encodeCheckpointValidators([]common.Address{owner, other}, nil)...)
+	checkpoint.Extra = append(checkpoint.Extra, encodeCheckpointSigners([]common.Address{key, other})...)
+	checkpoint.Extra = append(checkpoint.Extra, make([]byte, extraSeal)...)
+
+	validators, _, parsed := parseCheckpointValidators(checkpoint, false, true)
+	snap := newSnapshot(engine.config, nil, 10, common.Hash{}, validators, nil)
+	snap.setSigners(validators, parsed)
+	if have := snap.validatorOf(key); have != owner {
+		t.Errorf("validator of signing key mismatch: have %x, want %x", have, owner)
+	}
+	if have := snap.validatorOf(owner); have != (common.Address{}) {
+		t.Errorf("validator sealing with its own account resolved: %x", have)
+	}
+	if have := snap.signerOf(other); have != other {
+		t.Errorf("signer mismatch: have %x, want %x", have, other)
+	}
+}
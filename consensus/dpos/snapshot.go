@@ -34,10 +34,18 @@ type Snapshot struct {
 	config   *params.DposConfig // Consensus engine parameters to fine tune behavior
 	sigcache *lru.ARCCache      // Cache of recent block signatures to speed up ecrecover
 
-	Number     uint64                      `json:"number"`     // Block number where the snapshot was created
-	Hash       common.Hash                 `json:"hash"`       // Block hash where the snapshot was created
-	Validators map[common.Address]struct{} `json:"validators"` // Set of authorized validators at this moment
-	Recents    map[uint64]common.Address   `json:"recents"`    // Set of recent validators for spam protections
+	Number     uint64                      `json:"number"`            // Block number where the snapshot was created
+	Hash       common.Hash                 `json:"hash"`              // Block hash where the snapshot was created
+	Validators map[common.Address]struct{} `json:"validators"`        // Set of authorized validators at this moment
+	Recents    map[uint64]common.Address   `json:"recents"`           // Set of recent validators for spam protections
+	Weights    map[common.Address]uint64   `json:"weights,omitempty"` // Scheduling weights frozen at the last weighted checkpoint
+
+	Signers map[common.Address]common.Address `json:"signers,omitempty"` // Signing keys of the validators not sealing with their own account
+
+	Liveness map[uint64]map[common.Address]*Liveness `json:"liveness,omitempty"` // Block production records of the recent epochs, keyed by epoch number
+
+	schedule []common.Address           // In-turn slot sequence of the epoch, derived from the weights
+	changes  []*ValidatorSetChangeEvent // Validator set changes of the headers applied onto the snapshot, not persisted
 }
 
 // validatorsAscending implements the sort interface to allow sorting a list of addresses
@@ -50,7 +58,7 @@ func (s validatorsAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 // newSnapshot creates a new snapshot with the specified startup parameters. This
 // method does not initialize the set of recent validators, so only ever use if for
 // the genesis block.
-func newSnapshot(config *params.DposConfig, sigcache *lru.ARCCache, number uint64, hash common.Hash, validators []common.Address) *Snapshot {
+func newSnapshot(config *params.DposConfig, sigcache *lru.ARCCache, number uint64, hash common.Hash, validators []common.Address, weights []uint64) *Snapshot {
 	snap := &Snapshot{
 		config:     config,
 		sigcache:   sigcache,
@@ -62,6 +70,7 @@ func newSnapshot(config *params.DposConfig, sigcache *lru.ARCCache, number uint6
 	for _, validator := range validators {
 		snap.Validators[validator] = struct{}{}
 	}
+	snap.setWeights(validators, weights)
 	return snap
 }
 
@@ -77,6 +86,9 @@ func loadSnapshot(config *params.DposConfig, sigcache *lru.ARCCache, db ethdb.Da
 	}
 	snap.config = config
 	snap.sigcache = sigcache
+	if snap.Weights != nil {
+		snap.updateSchedule()
+	}
 
 	return snap, nil
 }
@@ -99,6 +111,7 @@ func (s *Snapshot) copy() *Snapshot {
 		Hash:       s.Hash,
 		Validators: make(map[common.Address]struct{}),
 		Recents:    make(map[uint64]common.Address),
+		schedule:   s.schedule,
 	}
 	for validator := range s.Validators {
 		cpy.Validators[validator] = struct{}{}
@@ -106,6 +119,19 @@ func (s *Snapshot) copy() *Snapshot {
 	for block, validator := range s.Recents {
 		cpy.Recents[block] = validator
 	}
+	cpy.Liveness = s.copyLiveness()
+	if s.Weights != nil {
+		cpy.Weights = make(map[common.Address]uint64)
+		for validator, weight := range s.Weights {
+			cpy.Weights[validator] = weight
+		}
+	}
+	if s.Signers != nil {
+		cpy.Signers = make(map[common.Address]common.Address)
+		for validator, signer := range s.Signers {
+			cpy.Signers[validator] = signer
+		}
+	}
 
 	return cpy
 }
@@ -137,10 +163,11 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 			delete(snap.Recents, number-limit)
 		}
 		// Resolve the authorization key and check against validators
-		validator, err := ecrecover(header, s.sigcache)
+		signer, err := ecrecover(header, s.sigcache)
 		if err != nil {
 			return nil, err
 		}
+		validator := snap.validatorOf(signer)
 		if _, ok := snap.Validators[validator]; !ok {
 			return nil, errUnauthorizedValidator
 		}
@@ -150,16 +177,14 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 			}
 		}
 		snap.Recents[number] = validator
+		snap.recordLiveness(number, validator)
 
 		// update validators at the first block at epoch
-		if number > 0 && number%s.config.Epoch == 0 {
+		if number > 0 && s.config.IsCheckpoint(number) {
 			checkpointHeader := header
 
-			// get validators from headers and use that for new validator set
-			validators := make([]common.Address, (len(checkpointHeader.Extra)-extraVanity-extraSeal)/common.AddressLength)
-			for i := 0; i < len(validators); i++ {
-				copy(validators[i][:], checkpointHeader.Extra[extraVanity+i*common.AddressLength:])
-			}
+			// get validators (weights and signing keys) from headers and use that for new validator set
+			validators, weights, signers := parseCheckpointValidators(checkpointHeader, isWeightedCheckpoint(s.config, number), isKeyedCheckpoint(s.config, number))
 
 			newValidators := make(map[common.Address]struct{})
 			for _, validator := range validators {
@@ -173,7 +198,13 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 				delete(snap.Recents, number-limit-uint64(i))
 			}
 
+			if change := newValidatorSetChange(s.config.EpochNumber(number), snap.Validators, validators); change.changed() {
+				change.Number, change.Hash = number, header.Hash()
+				snap.changes = append(snap.changes, change)
+			}
 			snap.Validators = newValidators
+			snap.setWeights(validators, weights)
+			snap.setSigners(validators, signers)
 		}
 	}
 
@@ -193,11 +224,90 @@ func (s *Snapshot) validators() []common.Address {
 	return sigs
 }
 
+// setWeights freezes the scheduling weights of the validators at a checkpoint
+// and derives the in-turn slot sequence of the epoch from them. Nil weights
+// switch back to round-robin.
+func (s *Snapshot) setWeights(validators []common.Address, weights []uint64) {
+	if weights == nil {
+		s.Weights, s.schedule = nil, nil
+		return
+	}
+	s.Weights = make(map[common.Address]uint64)
+	for i, validator := range validators {
+		s.Weights[validator] = weights[i]
+	}
+	s.updateSchedule()
+}
+
+// updateSchedule derives the in-turn slot sequence of the epoch from the frozen
+// scheduling weights.
+func (s *Snapshot) updateSchedule() {
+	s.schedule = buildSchedule(s.validators(), s.Weights, s.config.Epoch)
+}
+
+// setSigners binds the validators to the signing keys taking effect after the
+// checkpoint. Only the keys apart from the validator accounts are kept.
+func (s *Snapshot) setSigners(validators []common.Address, signers []common.Address) {
+	s.Signers = nil
+	for i, validator := range validators {
+		if signers == nil || signers[i] == validator {
+			continue
+		}
+		if s.Signers == nil {
+			s.Signers = make(map[common.Address]common.Address)
+		}
+		s.Signers[validator] = signers[i]
+	}
+}
+
+// signerOf returns the key the validator seals its blocks with.
+func (s *Snapshot) signerOf(validator common.Address) common.Address {
+	if signer, ok := s.Signers[validator]; ok {
+		return signer
+	}
+	return validator
+}
+
+// validatorOf returns the validator sealing with the given key. A key bound to no
+// validator is taken as a validator account, unless it's the account of a validator
+// sealing with another key.
+func (s *Snapshot) validatorOf(signer common.Address) common.Address {
+	for validator, key := range s.Signers {
+		if key == signer {
+			return validator
+		}
+	}
+	if _, ok := s.Signers[signer]; ok {
+		return common.Address{}
+	}
+	return signer
+}
+
+// inturnValidator returns the validator which is in-turn at a given block height.
+func (s *Snapshot) inturnValidator(number uint64) common.Address {
+	if len(s.schedule) > 0 {
+		return s.schedule[(number-s.config.EpochStart(number))%uint64(len(s.schedule))]
+	}
+	validators := s.validators()
+	return validators[number%uint64(len(validators))]
+}
+
 // inturn returns if a validator at a given block height is in-turn or not.
 func (s *Snapshot) inturn(number uint64, validator common.Address) bool {
-	validators, offset := s.validators(), 0
-	for offset < len(validators) && validators[offset] != validator {
-		offset++
+	return s.inturnValidator(number) == validator
+}
+
+// nextTurn returns the number of blocks after the given height until the next
+// in-turn slot of the validator, capped at the length of the slot sequence.
+func (s *Snapshot) nextTurn(number uint64, validator common.Address) uint64 {
+	length := uint64(len(s.schedule))
+	if length == 0 {
+		length = uint64(len(s.Validators))
+	}
+	for distance := uint64(1); distance < length; distance++ {
+		if s.inturn(number+distance, validator) {
+			return distance
+		}
 	}
-	return (number % uint64(len(validators))) == uint64(offset)
+	return length
 }
@@ -24,7 +24,17 @@ const ProposalsABI = `[{"inputs":[{"internalType":"address[]","name":"vals","typ
 
 const SysGovABI = `[{"inputs":[{"internalType":"uint256","name":"id","type":"uint256"}],"name":"finishProposalById","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"uint32","name":"index","type":"uint32"}],"name":"getPassedProposalByIndex","outputs":[{"internalType":"uint256","name":"id","type":"uint256"},{"internalType":"uint256","name":"action","type":"uint256"},{"internalType":"address","name":"from","type":"address"},{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"},{"internalType":"bytes","name":"data","type":"bytes"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"getPassedProposalCount","outputs":[{"internalType":"uint32","name":"","type":"uint32"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"_admin","type":"address"}],"name":"initialize","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
 
-const AddressListABI = `[{"inputs":[],"name":"blackLastUpdatedNumber","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"devVerifyEnabled","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"getBlacksFrom","outputs":[{"internalType":"address[]","name":"","type":"address[]"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"getBlacksTo","outputs":[{"internalType":"address[]","name":"","type":"address[]"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"uint32","name":"i","type":"uint32"}],"name":"getRuleByIndex","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"},{"internalType":"uint128","name":"","type":"uint128"},{"internalType":"enum AddressList.CheckType","name":"","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"initializeV2","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"_admin","type":"address"}],"name":"initialize","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"addr","type":"address"}],"name":"isDeveloper","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"rulesLastUpdatedNumber","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"rulesLen","outputs":[{"internalType":"uint32","name":"","type":"uint32"}],"stateMutability":"view","type":"function"}]`
+const AddressListABI = `[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"addr","type":"address"}],"name":"DeveloperAdded","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"addr","type":"address"}],"name":"DeveloperRemoved","type":"event"},{"inputs":[{"internalType":"address","name":"addr","type":"address"}],"name":"addDeveloper","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[],"name":"blackLastUpdatedNumber","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"devVerifyEnabled","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"getBlacksFrom","outputs":[{"internalType":"address[]","name":"","type":"address[]"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"getBlacksTo","outputs":[{"internalType":"address[]","name":"","type":"address[]"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"uint32","name":"i","type":"uint32"}],"name":"getRuleByIndex","outputs":[{"internalType":"bytes32","name":"","type":"bytes32"},{"internalType":"uint128","name":"","type":"uint128"},{"internalType":"enum AddressList.CheckType","name":"","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"initializeV2","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"_admin","type":"address"}],"name":"initialize","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"addr","type":"address"}],"name":"isDeveloper","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"addr","type":"address"}],"name":"removeDeveloper","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[],"name":"rulesLastUpdatedNumber","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"rulesLen","outputs":[{"internalType":"uint32","name":"","type":"uint32"}],"stateMutability":"view","type":"function"}]`
+
+// ConsensusParamsABI contains methods to read the dpos period and epoch length in effect.
+const ConsensusParamsABI = `[{"inputs":[],"name":"currentEpoch","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"epoch","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"forkBlock","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"period","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`
+
+// AddressListV2ABI contains methods to manage the blacklist entries expiring at a
+// block height or scoped to the events of a single contract.
+const AddressListV2ABI = `[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"admin","type":"address"},{"indexed":true,"internalType":"address","name":"addr","type":"address"},{"indexed":true,"internalType":"address","name":"scope","type":"address"}],"name":"BlacklistEntryRemovalRequested","type":"event"},{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"admin","type":"address"},{"indexed":true,"internalType":"address","name":"addr","type":"address"},{"indexed":true,"internalType":"address","name":"scope","type":"address"},{"indexed":false,"internalType":"uint8","name":"direction","type":"uint8"},{"indexed":false,"internalType":"uint64","name":"expiry","type":"uint64"}],"name":"BlacklistEntryRequested","type":"event"},{"inputs":[{"internalType":"uint256","name":"i","type":"uint256"}],"name":"entryAt","outputs":[{"internalType":"address","name":"addr","type":"address"},{"internalType":"address","name":"scope","type":"address"},{"internalType":"uint8","name":"direction","type":"uint8"},{"internalType":"uint64","name":"expiry","type":"uint64"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"entriesLength","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"lastUpdatedNumber","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"addr","type":"address"},{"internalType":"address","name":"scope","type":"address"}],"name":"removeBlacklistEntry","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"addr","type":"address"},{"internalType":"uint8","name":"direction","type":"uint8"},{"internalType":"uint64","name":"expiry","type":"uint64"},{"internalType":"address","name":"scope","type":"address"}],"name":"setBlacklistEntry","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// SigningKeysABI contains methods to bind validators to the keys sealing their blocks.
+const SigningKeysABI = `[{"anonymous":false,"inputs":[{"indexed":true,"internalType":"address","name":"owner","type":"address"},{"indexed":true,"internalType":"address","name":"key","type":"address"}],"name":"SigningKeyRequested","type":"event"},{"inputs":[{"internalType":"address","name":"key","type":"address"}],"name":"ownerOf","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"owner","type":"address"}],"name":"pendingSigningKeyOf","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"key","type":"address"}],"name":"setSigningKey","outputs":[],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"owner","type":"address"}],"name":"signingKeyOf","outputs":[{"internalType":"address","name":"","type":"address"}],"stateMutability":"view","type":"function"}]`
 
 // DevMappingPosition is the position of the state variable `devs`.
 // Since the state variables are as follows:
@@ -68,6 +78,10 @@ var (
 	AddressListContractName = "address_list"
 	SysGovContractName      = "governance"
 
+	ConsensusParamsContractName = "ConsensusParams"
+	SigningKeysContractName     = "SigningKeys"
+	AddressListV2ContractName   = "address_list_v2"
+
 	ValidatorsContractAddr         = common.HexToAddress("0x0000000000000000000000000000000000fff001")
 	ValidatorProposalsContractAddr = common.HexToAddress("0x0000000000000000000000000000000000fff002")
 	NodeVotesContractAddr          = common.HexToAddress("0x0000000000000000000000000000000000fff003")
@@ -78,8 +92,25 @@ var (
 	AddressListContractAddr = common.HexToAddress("0x0000000000000000000000000000000000fff007")
 	SysGovContractAddr      = common.HexToAddress("0x0000000000000000000000000000000000fff008")
 
+	// ConsensusParamsContractAddr holds the dpos period and epoch length in effect, it's
+	// maintained by the consensus engine at each scheduled change.
+	ConsensusParamsContractAddr = common.HexToAddress("0x0000000000000000000000000000000000fff009")
+	// SigningKeysContractAddr binds the validators to the keys sealing their blocks, the
+	// requested keys are applied by the consensus engine at the next checkpoint.
+	SigningKeysContractAddr = common.HexToAddress("0x0000000000000000000000000000000000fff00a")
+	// AddressListV2ContractAddr holds the expiring and scoped blacklist entries, the
+	// requests of the address list admin are applied by the consensus engine.
+	AddressListV2ContractAddr = common.HexToAddress("0x0000000000000000000000000000000000fff00b")
+
 	// SysGovToAddr is the To address for the system governance transaction, NOT contract address
 	SysGovToAddr = common.HexToAddress("0x000000000000000000000000000000000000ffff")
+	// DoubleSignEvidenceToAddr is the To address for the double sign evidence transaction,
+	// it also holds the records of slashed offences.
+	DoubleSignEvidenceToAddr = common.HexToAddress("0x000000000000000000000000000000000000fffe")
+	// FactoryDeployersAddr holds the developers the factory contracts inherit the
+	// allowlisting of in the factory developer verification mode, it's maintained by
+	// the consensus engine and has no code.
+	FactoryDeployersAddr = common.HexToAddress("0x000000000000000000000000000000000000fffd")
 
 	abiMap map[string]abi.ABI
 )
@@ -103,6 +134,12 @@ func init() {
 	abiMap[AddressListContractName] = tmpABI
 	tmpABI, _ = abi.JSON(strings.NewReader(SysGovABI))
 	abiMap[SysGovContractName] = tmpABI
+	tmpABI, _ = abi.JSON(strings.NewReader(ConsensusParamsABI))
+	abiMap[ConsensusParamsContractName] = tmpABI
+	tmpABI, _ = abi.JSON(strings.NewReader(SigningKeysABI))
+	abiMap[SigningKeysContractName] = tmpABI
+	tmpABI, _ = abi.JSON(strings.NewReader(AddressListV2ABI))
+	abiMap[AddressListV2ContractName] = tmpABI
 
 }
 
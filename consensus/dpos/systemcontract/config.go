@@ -0,0 +1,198 @@
+package systemcontract
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/DxChainNetwork/dxc/accounts/abi"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+// GenesisContractNames are the system contracts deployed in the genesis allocation.
+var GenesisContractNames = []string{
+	ValidatorsContractName,
+	ValidatorProposalsContractName,
+	NodeVotesContractName,
+	SystemRewardsContractName,
+	MigrateContractName,
+	ProposalsContractName,
+	AddressListContractName,
+	SysGovContractName,
+}
+
+// contractAddrs maps the names of the configurable system contracts to their
+// address variables.
+var contractAddrs = map[string]*common.Address{
+	ValidatorsContractName:         &ValidatorsContractAddr,
+	ValidatorProposalsContractName: &ValidatorProposalsContractAddr,
+	NodeVotesContractName:          &NodeVotesContractAddr,
+	SystemRewardsContractName:      &SystemRewardsContractAddr,
+	MigrateContractName:            &MigrateContractAddr,
+	ProposalsContractName:          &ProposalsContractAddr,
+	AddressListContractName:        &AddressListContractAddr,
+	SysGovContractName:             &SysGovContractAddr,
+}
+
+// defaultAddrs holds the built-in addresses of the system contracts.
+var defaultAddrs = make(map[string]common.Address)
+
+func init() {
+	for name, addr := range contractAddrs {
+		defaultAddrs[name] = *addr
+	}
+}
+
+// DefaultAddress returns the built-in address of the named system contract.
+func DefaultAddress(name string) common.Address {
+	return defaultAddrs[name]
+}
+
+// ContractAddress returns the address of the named system contract in effect.
+func ContractAddress(name string) (common.Address, bool) {
+	addr, ok := contractAddrs[name]
+	if !ok {
+		return common.Address{}, false
+	}
+	return *addr, true
+}
+
+// CheckConfig validates the system contract overrides, returning the addresses
+// and ABIs they change.
+func CheckConfig(contracts []params.DposSystemContract) (map[string]common.Address, map[string]abi.ABI, error) {
+	var (
+		addrs = make(map[string]common.Address)
+		abis  = make(map[string]abi.ABI)
+	)
+	for _, contract := range contracts {
+		if _, ok := contractAddrs[contract.Name]; !ok {
+			return nil, nil, fmt.Errorf("unknown system contract %q", contract.Name)
+		}
+		if _, ok := addrs[contract.Name]; ok {
+			return nil, nil, fmt.Errorf("duplicate system contract %q", contract.Name)
+		}
+		if contract.CodeFile != "" || contract.ABIFile != "" {
+			return nil, nil, fmt.Errorf("unresolved file reference of system contract %q", contract.Name)
+		}
+		addr := defaultAddrs[contract.Name]
+		if contract.Address != (common.Address{}) {
+			addr = contract.Address
+		}
+		addrs[contract.Name] = addr
+
+		if len(contract.ABI) > 0 {
+			parsed, err := abi.JSON(bytes.NewReader(contract.ABI))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid ABI of system contract %q: %v", contract.Name, err)
+			}
+			abis[contract.Name] = parsed
+		}
+	}
+	// The contracts may not overlap with each other after relocation
+	seen := make(map[common.Address]string)
+	for _, name := range GenesisContractNames {
+		addr, ok := addrs[name]
+		if !ok {
+			addr = defaultAddrs[name]
+		}
+		if other, ok := seen[addr]; ok {
+			return nil, nil, fmt.Errorf("system contracts %q and %q share address %s", other, name, addr.Hex())
+		}
+		seen[addr] = name
+	}
+	return addrs, abis, nil
+}
+
+var (
+	configLock sync.Mutex
+	configured bool              // Whether a config was applied already
+	appliedABI map[string]string // Raw ABIs of the config in effect, to compare later ones with
+)
+
+// ApplyConfig overrides the addresses and ABIs of the system contracts with the
+// configured ones. It must be called before the system contracts are accessed.
+//
+// The addresses and ABIs are shared by the whole process, so the config is only
+// applied once. Applying the same config again is a no-op, a different one fails.
+func ApplyConfig(contracts []params.DposSystemContract) error {
+	addrs, abis, err := CheckConfig(contracts)
+	if err != nil {
+		return err
+	}
+	rawABIs := make(map[string]string)
+	for _, contract := range contracts {
+		if len(contract.ABI) > 0 {
+			rawABIs[contract.Name] = string(contract.ABI)
+		}
+	}
+	configLock.Lock()
+	defer configLock.Unlock()
+
+	if configured {
+		for name, addr := range contractAddrs {
+			want, ok := addrs[name]
+			if !ok {
+				want = defaultAddrs[name]
+			}
+			if *addr != want {
+				return fmt.Errorf("conflicting address of system contract %q: %s applied already, have %s", name, addr.Hex(), want.Hex())
+			}
+		}
+		if !reflect.DeepEqual(appliedABI, rawABIs) {
+			return errors.New("conflicting system contract ABIs applied already")
+		}
+		return nil
+	}
+	for name, addr := range addrs {
+		*contractAddrs[name] = addr
+	}
+	for name, parsed := range abis {
+		abiMap[name] = parsed
+	}
+	configured, appliedABI = true, rawABIs
+	return nil
+}
+
+// LoadContractFiles reads the code and ABI file references of the system contracts
+// into the inline fields. Relative paths are resolved against dir.
+func LoadContractFiles(contracts []params.DposSystemContract, dir string) error {
+	read := func(path string) ([]byte, error) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		return ioutil.ReadFile(path)
+	}
+	for i := range contracts {
+		contract := &contracts[i]
+		if contract.CodeFile != "" {
+			blob, err := read(contract.CodeFile)
+			if err != nil {
+				return err
+			}
+			code, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+			if err != nil {
+				return fmt.Errorf("invalid code file of system contract %q: %v", contract.Name, err)
+			}
+			contract.Code, contract.CodeFile = code, ""
+		}
+		if contract.ABIFile != "" {
+			blob, err := read(contract.ABIFile)
+			if err != nil {
+				return err
+			}
+			if !json.Valid(blob) {
+				return fmt.Errorf("invalid ABI file of system contract %q", contract.Name)
+			}
+			contract.ABI, contract.ABIFile = blob, ""
+		}
+	}
+	return nil
+}
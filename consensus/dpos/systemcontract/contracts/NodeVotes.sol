@@ -1054,13 +1054,15 @@ pragma solidity ^0.8.0;
       }
 
 contract Base {
+    /// @notice seconds between blocks at genesis, only informative: the contracts
+    /// count blocks, and the period in effect is read from ConsensusParams
     uint256 public constant BLOCK_SECONDS = 6;
     /// @notice min rate. base on 100
     uint8 public constant MIN_RATE = 70;
     /// @notice max rate. base on 100
     uint8 public constant MAX_RATE = 100;
 
-    /// @notice 10 * 60 / BLOCK_SECONDS
+    /// @notice epoch length in blocks, dpos forks only change the period
     uint256 public constant EPOCH_BLOCKS = 14400;
     /// @notice min deposit for validator
     uint256 public constant MIN_DEPOSIT = 4e7 ether;
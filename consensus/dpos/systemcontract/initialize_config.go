@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/DxChainNetwork/dxc/common"
 	"github.com/DxChainNetwork/dxc/common/math"
+	"github.com/DxChainNetwork/dxc/params"
 	"math/big"
 	"strings"
 )
@@ -11,7 +12,7 @@ import (
 // using for Validators contract's initialize
 var (
 	InitRate    = uint8(100)
-	InitDeposit = new(big.Int).Mul(big.NewInt(1e18), big.NewInt(40000000))
+	InitDeposit = new(big.Int).Set(params.DposDefaultDeposit)
 	InitName    = "dxc-validator-1"      // max bytes length: 100
 	InitDetails = "initialize validator" // max bytes length: 10000
 )
@@ -15,6 +15,23 @@ import (
 	"math/big"
 )
 
+// ErrInvalidProposalID is returned if a validator proposal id is not a 4 bytes hex string.
+var ErrInvalidProposalID = errors.New("invalid proposal id")
+
+// ParseProposalID decodes the hex id of a validator proposal.
+func ParseProposalID(id string) ([4]byte, error) {
+	var pid [4]byte
+	blob, err := hexutil.Decode(id)
+	if err != nil {
+		return pid, err
+	}
+	if len(blob) != len(pid) {
+		return pid, ErrInvalidProposalID
+	}
+	copy(pid[:], blob)
+	return pid, nil
+}
+
 type Proposals struct {
 	abi          abi.ABI
 	contractAddr common.Address
@@ -207,12 +224,10 @@ func (p *Proposals) ProposalCount(statedb *state.StateDB, header *types.Header,
 // GetProposal function GetProposal
 func (p *Proposals) GetProposal(statedb *state.StateDB, header *types.Header, chainContext core.ChainContext, config *params.ChainConfig, id string) (*ProposalInfo, error) {
 	method := "proposalInfos"
-	idBytes, err := hexutil.Decode(id)
+	idByte4, err := ParseProposalID(id)
 	if err != nil {
 		return &ProposalInfo{}, err
 	}
-	var idByte4 [4]byte
-	copy(idByte4[:], idBytes[:4])
 	data, err := p.abi.Pack(method, idByte4)
 
 	if err != nil {
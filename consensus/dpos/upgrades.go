@@ -0,0 +1,79 @@
+package dpos
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/log"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+// UpgradeMigration is a storage migration hook run right after the code of a system
+// contract is replaced by a scheduled upgrade. It must be deterministic.
+type UpgradeMigration func(state *state.StateDB, header *types.Header, addr common.Address) error
+
+var (
+	upgradeMigrations     = make(map[string]UpgradeMigration)
+	upgradeMigrationsLock sync.RWMutex
+)
+
+// RegisterUpgradeMigration registers a storage migration hook under the name the
+// system contract upgrades of the chain config refer to it by.
+func RegisterUpgradeMigration(name string, migration UpgradeMigration) {
+	upgradeMigrationsLock.Lock()
+	defer upgradeMigrationsLock.Unlock()
+
+	if _, ok := upgradeMigrations[name]; ok {
+		panic(fmt.Sprintf("duplicate upgrade migration %q", name))
+	}
+	upgradeMigrations[name] = migration
+}
+
+// lookupUpgradeMigration retrieves the storage migration hook registered by name.
+func lookupUpgradeMigration(name string) (UpgradeMigration, bool) {
+	upgradeMigrationsLock.RLock()
+	defer upgradeMigrationsLock.RUnlock()
+
+	migration, ok := upgradeMigrations[name]
+	return migration, ok
+}
+
+// checkUpgradeMigrations ensures every migration hook referred to by the system
+// contract upgrades is registered.
+func checkUpgradeMigrations(upgrades []params.SystemContractUpgrade) error {
+	for _, upgrade := range upgrades {
+		if upgrade.Migration == "" {
+			continue
+		}
+		if _, ok := lookupUpgradeMigration(upgrade.Migration); !ok {
+			return fmt.Errorf("unknown migration %q of system contract upgrade at block %v", upgrade.Migration, upgrade.Block)
+		}
+	}
+	return nil
+}
+
+// applySystemContractUpgrades replaces the code of the system contracts upgraded
+// at the header and runs their storage migrations, in the configured order.
+func (d *Dpos) applySystemContractUpgrades(header *types.Header, state *state.StateDB) error {
+	for _, upgrade := range d.chainConfig.SystemContractUpgradesAt(header.Number) {
+		state.SetCode(upgrade.Address, upgrade.Code)
+		for slot, value := range upgrade.Storage {
+			state.SetState(upgrade.Address, slot, value)
+		}
+		if upgrade.Migration != "" {
+			migration, ok := lookupUpgradeMigration(upgrade.Migration)
+			if !ok {
+				return fmt.Errorf("unknown upgrade migration %q", upgrade.Migration)
+			}
+			if err := migration(state, header, upgrade.Address); err != nil {
+				return err
+			}
+		}
+		log.Info("System contract upgraded", "number", header.Number, "address", upgrade.Address, "codeHash", crypto.Keccak256Hash(upgrade.Code))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package dpos
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/state"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestSystemContractUpgrades(t *testing.T) {
+	statedb := newTestState()
+
+	addr := systemcontract.ValidatorsContractAddr
+	RegisterUpgradeMigration("test-bump-slot", func(state *state.StateDB, header *types.Header, addr common.Address) error {
+		state.SetState(addr, common.Hash{0x02}, common.BigToHash(header.Number))
+		return nil
+	})
+	config := &params.ChainConfig{SystemContractUpgrades: []params.SystemContractUpgrade{{
+		Block:     big.NewInt(100),
+		Address:   addr,
+		Code:      []byte{0x60, 0x01},
+		Storage:   map[common.Hash]common.Hash{{0x01}: {0xff}},
+		Migration: "test-bump-slot",
+	}}}
+	engine := &Dpos{chainConfig: config}
+
+	// Nothing happens outside of the upgrade block
+	if err := engine.applySystemContractUpgrades(&types.Header{Number: big.NewInt(99)}, statedb); err != nil {
+		t.Fatalf("failed to apply upgrades: %v", err)
+	}
+	if code := statedb.GetCode(addr); len(code) != 0 {
+		t.Fatalf("code upgraded early: %x", code)
+	}
+	if err := engine.applySystemContractUpgrades(&types.Header{Number: big.NewInt(100)}, statedb); err != nil {
+		t.Fatalf("failed to apply upgrades: %v", err)
+	}
+	if code := statedb.GetCode(addr); !bytes.Equal(code, []byte{0x60, 0x01}) {
+		t.Errorf("code mismatch: have %x", code)
+	}
+	if value := statedb.GetState(addr, common.Hash{0x01}); value != (common.Hash{0xff}) {
+		t.Errorf("storage mismatch: have %x", value)
+	}
+	if value := statedb.GetState(addr, common.Hash{0x02}); value != common.BytesToHash([]byte{100}) {
+		t.Errorf("migrated storage mismatch: have %x", value)
+	}
+}
@@ -12,8 +12,14 @@ import (
 
 // ExecuteMsg executes transaction sent to system contracts.
 func ExecuteMsg(msg core.Message, state *state.StateDB, header *types.Header, chainContext core.ChainContext, chainConfig *params.ChainConfig) (ret []byte, err error) {
+	return ExecuteMsgWithConfig(msg, state, header, chainContext, chainConfig, vm.Config{})
+}
+
+// ExecuteMsgWithConfig executes transaction sent to system contracts with the given evm config,
+// e.g. to trace the execution.
+func ExecuteMsgWithConfig(msg core.Message, state *state.StateDB, header *types.Header, chainContext core.ChainContext, chainConfig *params.ChainConfig, vmConfig vm.Config) (ret []byte, err error) {
 	blockContext := core.NewEVMBlockContext(header, chainContext, nil)
-	vmenv := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), state, chainConfig, vm.Config{})
+	vmenv := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), state, chainConfig, vmConfig)
 
 	ret, _, err = vmenv.Call(vm.AccountRef(msg.From()), *msg.To(), msg.Data(), msg.Gas(), msg.Value())
 	// Finalise the statedb so any changes can take effect,
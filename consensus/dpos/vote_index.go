@@ -0,0 +1,240 @@
+package dpos
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/ethdb"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+const (
+	defaultVotePageLimit = 100  // Number of votes returned per page if not requested otherwise
+	maxVotePageLimit     = 1000 // Maximum number of votes returned per page
+)
+
+// The orders the votes can be paginated in.
+const (
+	VoteOrderAddress = "address" // Ascending address of the counterparty
+	VoteOrderVotes   = "votes"   // Descending votes, then ascending address
+)
+
+var (
+	// errVoteIndexDisabled is returned if the votes are queried while the vote
+	// index is not maintained.
+	errVoteIndexDisabled = errors.New("dpos vote index is disabled")
+
+	// errInvalidVoteCursor is returned if the pagination cursor doesn't match the
+	// requested order.
+	errInvalidVoteCursor = errors.New("invalid vote page cursor")
+
+	// errUnknownVoteOrder is returned if the votes are requested in an unknown order.
+	errUnknownVoteOrder = errors.New("unknown vote order")
+)
+
+// StartVoteIndex starts maintaining the validator to voters and voter to validators
+// maps from the NodeVotes events of the canonical blocks, until the engine is closed.
+func (d *Dpos) StartVoteIndex(feed chainHeadSubscriber) {
+	d.voteIndex = true
+	d.startLogIndex(d.newVoteIndex(), feed)
+}
+
+// newVoteIndex returns the vote index maintained from the NodeVotes events.
+func (d *Dpos) newVoteIndex() *logIndex {
+	return &logIndex{
+		name:      "vote",
+		readHead:  rawdb.ReadDposVoteIndexHead,
+		writeHead: rawdb.WriteDposVoteIndexHead,
+		changes:   &voteChanges{db: d.db, votes: make(map[[2]common.Address]*big.Int)},
+	}
+}
+
+// voteChanges accumulates the vote changes of a batch of blocks on top of the index.
+type voteChanges struct {
+	db    ethdb.KeyValueReader
+	votes map[[2]common.Address]*big.Int // Votes keyed by validator and voter
+}
+
+func (c *voteChanges) add(validator, voter common.Address, delta *big.Int) {
+	key := [2]common.Address{validator, voter}
+	votes, ok := c.votes[key]
+	if !ok {
+		votes = rawdb.ReadDposVotes(c.db, validator, voter)
+		c.votes[key] = votes
+	}
+	votes.Add(votes, delta)
+	if votes.Sign() < 0 {
+		log.Error("Negative dpos votes indexed", "validator", validator, "voter", voter)
+		votes.SetUint64(0)
+	}
+}
+
+// apply accumulates the votes cast and canceled in the given block, reverting them
+// if the block left the canonical chain.
+func (c *voteChanges) apply(receipts types.Receipts, revert bool) {
+	var (
+		contract, _ = systemcontract.ContractAddress(systemcontract.NodeVotesContractName)
+		events      = systemcontract.GetInteractiveABI()[systemcontract.NodeVotesContractName].Events
+	)
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if l.Address != contract || len(l.Topics) != 3 || len(l.Data) != common.HashLength {
+				continue
+			}
+			delta := new(big.Int).SetBytes(l.Data)
+			switch l.Topics[0] {
+			case events["LogVote"].ID:
+			case events["LogCancelVote"].ID:
+				delta.Neg(delta)
+			default:
+				continue
+			}
+			if revert {
+				delta.Neg(delta)
+			}
+			c.add(common.BytesToAddress(l.Topics[2].Bytes()), common.BytesToAddress(l.Topics[1].Bytes()), delta)
+		}
+	}
+}
+
+// flush writes the accumulated changes.
+func (c *voteChanges) flush(batch ethdb.Batch) {
+	for key, votes := range c.votes {
+		rawdb.WriteDposVotes(batch, key[0], key[1], votes)
+	}
+	c.votes = make(map[[2]common.Address]*big.Int)
+}
+
+// VotePosition is the votes of a voter for a validator.
+type VotePosition struct {
+	Voter     common.Address `json:"voter"`
+	Validator common.Address `json:"validator"`
+	Votes     *hexutil.Big   `json:"votes"`
+}
+
+// VotePageOptions selects a page of votes.
+type VotePageOptions struct {
+	Cursor hexutil.Bytes `json:"cursor"` // The next cursor of the previous page, empty for the first one
+	Limit  int           `json:"limit"`  // Maximum number of votes in the page
+	Order  string        `json:"order"`  // Order of the votes, address or votes
+}
+
+// VotePage is a page of votes read from the vote index.
+type VotePage struct {
+	Number    hexutil.Uint64  `json:"number"` // Latest block indexed
+	Positions []*VotePosition `json:"positions"`
+	Next      hexutil.Bytes   `json:"next,omitempty"` // Cursor of the next page, empty on the last one
+}
+
+// votesPage returns a page of the votes for the validator or of the voter.
+func (d *Dpos) votesPage(addr common.Address, ofValidator bool, opts *VotePageOptions) (*VotePage, error) {
+	if !d.voteIndex {
+		return nil, errVoteIndexDisabled
+	}
+	if opts == nil {
+		opts = new(VotePageOptions)
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultVotePageLimit
+	}
+	if limit > maxVotePageLimit {
+		limit = maxVotePageLimit
+	}
+	// position builds the vote from the iterated key ending with the counterparty
+	iterate := rawdb.IterateDposVoterVotes
+	position := func(key, value []byte) *VotePosition {
+		pos := &VotePosition{Voter: addr, Validator: common.BytesToAddress(key[len(key)-common.AddressLength:])}
+		pos.Votes = (*hexutil.Big)(new(big.Int).SetBytes(value))
+		return pos
+	}
+	if ofValidator {
+		iterate = rawdb.IterateDposValidatorVotes
+		position = func(key, value []byte) *VotePosition {
+			pos := &VotePosition{Voter: common.BytesToAddress(key[len(key)-common.AddressLength:]), Validator: addr}
+			pos.Votes = (*hexutil.Big)(new(big.Int).SetBytes(value))
+			return pos
+		}
+	}
+	counterparty := func(pos *VotePosition) common.Address {
+		if ofValidator {
+			return pos.Voter
+		}
+		return pos.Validator
+	}
+	number, _, _ := rawdb.ReadDposVoteIndexHead(d.db)
+	page := &VotePage{Number: hexutil.Uint64(number), Positions: []*VotePosition{}}
+
+	switch opts.Order {
+	case "", VoteOrderAddress:
+		var start common.Address
+		if len(opts.Cursor) > 0 {
+			if len(opts.Cursor) != common.AddressLength {
+				return nil, errInvalidVoteCursor
+			}
+			start = common.BytesToAddress(opts.Cursor)
+		}
+		it := iterate(d.db, addr, start)
+		defer it.Release()
+		for it.Next() {
+			pos := position(it.Key(), it.Value())
+			if len(opts.Cursor) > 0 && counterparty(pos) == start {
+				continue
+			}
+			if len(page.Positions) == limit {
+				last := counterparty(page.Positions[limit-1])
+				page.Next = last.Bytes()
+				break
+			}
+			page.Positions = append(page.Positions, pos)
+		}
+		return page, it.Error()
+
+	case VoteOrderVotes:
+		if len(opts.Cursor) > 0 && len(opts.Cursor) != common.HashLength+common.AddressLength {
+			return nil, errInvalidVoteCursor
+		}
+		// Ordering by votes needs all of them, still way cheaper than the evm paging.
+		// The votes are inverted in the keys so that they sort the same as the positions.
+		type sortedVote struct {
+			key []byte
+			pos *VotePosition
+		}
+		var all []sortedVote
+		it := iterate(d.db, addr, common.Address{})
+		for it.Next() {
+			pos := position(it.Key(), it.Value())
+			key := common.BigToHash(pos.Votes.ToInt()).Bytes()
+			for i := range key {
+				key[i] = ^key[i]
+			}
+			all = append(all, sortedVote{key: append(key, counterparty(pos).Bytes()...), pos: pos})
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+		sort.Slice(all, func(i, j int) bool {
+			return bytes.Compare(all[i].key, all[j].key) < 0
+		})
+		from := sort.Search(len(all), func(i int) bool {
+			return bytes.Compare(all[i].key, opts.Cursor) > 0
+		})
+		for i, vote := range all[from:] {
+			if i == limit {
+				page.Next = all[from+i-1].key
+				break
+			}
+			page.Positions = append(page.Positions, vote.pos)
+		}
+		return page, nil
+	}
+	return nil, errUnknownVoteOrder
+}
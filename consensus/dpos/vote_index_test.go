@@ -0,0 +1,109 @@
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/rawdb"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+func TestVoteIndex(t *testing.T) {
+	engine := New(&params.ChainConfig{ChainID: big.NewInt(1), Dpos: &params.DposConfig{Epoch: 10}}, rawdb.NewMemoryDatabase())
+	engine.voteIndex = true
+
+	var (
+		db        = engine.db
+		events    = systemcontract.GetInteractiveABI()[systemcontract.NodeVotesContractName].Events
+		val1      = common.HexToAddress("0x1001")
+		val2      = common.HexToAddress("0x1002")
+		voter1    = common.HexToAddress("0x2001")
+		voter2    = common.HexToAddress("0x2002")
+		nodeVotes = systemcontract.NodeVotesContractAddr
+	)
+	voteLog := func(event string, voter, val common.Address, votes int64) *types.Log {
+		return &types.Log{
+			Address: nodeVotes,
+			Topics:  []common.Hash{events[event].ID, voter.Hash(), val.Hash()},
+			Data:    common.BigToHash(big.NewInt(votes)).Bytes(),
+		}
+	}
+	genesis := insertTestBlock(db, nil, 0)
+	block1 := insertTestBlock(db, genesis, 0,
+		voteLog("LogVote", voter1, val1, 10),
+		voteLog("LogVote", voter2, val1, 30),
+		voteLog("LogVote", voter1, val2, 5),
+	)
+	insertTestBlock(db, block1, 0, voteLog("LogCancelVote", voter1, val1, 10), voteLog("LogVote", voter2, val2, 1))
+	if err := engine.updateLogIndex(engine.newVoteIndex(), nil); err != nil {
+		t.Fatalf("failed to index votes: %v", err)
+	}
+	check := func(page *VotePage, err error, want ...int64) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("failed to read votes: %v", err)
+		}
+		if len(page.Positions) != len(want) {
+			t.Fatalf("positions mismatch: have %d, want %d", len(page.Positions), len(want))
+		}
+		for i, pos := range page.Positions {
+			if pos.Votes.ToInt().Int64() != want[i] {
+				t.Errorf("position %d: votes mismatch: have %v, want %d", i, pos.Votes, want[i])
+			}
+		}
+	}
+	// The fully canceled votes are dropped from the index
+	page, err := engine.votesPage(val1, true, nil)
+	check(page, err, 30)
+	page, err = engine.votesPage(voter2, false, &VotePageOptions{Order: VoteOrderVotes})
+	check(page, err, 30, 1)
+
+	// Walk the voters of both validators one by one in both orders
+	for _, order := range []string{VoteOrderAddress, VoteOrderVotes} {
+		var have []int64
+		opts := &VotePageOptions{Limit: 1, Order: order}
+		for {
+			page, err := engine.votesPage(voter1, false, opts)
+			if err != nil {
+				t.Fatalf("failed to read votes: %v", err)
+			}
+			for _, pos := range page.Positions {
+				have = append(have, pos.Votes.ToInt().Int64())
+			}
+			if len(page.Next) == 0 {
+				break
+			}
+			opts.Cursor = page.Next
+		}
+		if len(have) != 1 || have[0] != 5 {
+			t.Errorf("order %s: votes mismatch: have %v", order, have)
+		}
+	}
+	opts := &VotePageOptions{Limit: 1, Order: VoteOrderVotes}
+	page, err = engine.votesPage(voter2, false, opts)
+	check(page, err, 30)
+	opts.Cursor = page.Next
+	page, err = engine.votesPage(voter2, false, opts)
+	check(page, err, 1)
+	if len(page.Next) != 0 {
+		t.Errorf("cursor returned on the last page")
+	}
+	if _, err := engine.votesPage(voter2, false, &VotePageOptions{Order: VoteOrderAddress, Cursor: page.Next}); err != nil {
+		t.Errorf("empty cursor rejected: %v", err)
+	}
+	if _, err := engine.votesPage(voter2, false, &VotePageOptions{Order: VoteOrderAddress, Cursor: opts.Cursor}); err != errInvalidVoteCursor {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidVoteCursor)
+	}
+	// Reorg out the second block, its changes are reverted
+	insertTestBlock(db, block1, 1, voteLog("LogVote", voter1, val1, 1))
+	if err := engine.updateLogIndex(engine.newVoteIndex(), nil); err != nil {
+		t.Fatalf("failed to index votes: %v", err)
+	}
+	page, err = engine.votesPage(val1, true, &VotePageOptions{Order: VoteOrderVotes})
+	check(page, err, 30, 11)
+	page, err = engine.votesPage(val2, true, nil)
+	check(page, err, 5)
+}
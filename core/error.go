@@ -94,7 +94,10 @@ var (
 
 	ErrMetaTrans = errors.New("ErrMetaTrans")
 
-	ErrUnauthorizedDeveloper = errors.New("unauthorized developer")
+	// ErrUnauthorizedDeveloper is returned if the sender of a contract creation is
+	// not in the developer allowlist while the developer verification is enabled.
+	ErrUnauthorizedDeveloper = errors.New("creator not in developer allowlist")
+
 	// ErrSenderNoEOA is returned if the sender of a transaction is a contract.
 	ErrSenderNoEOA = errors.New("sender not an eoa")
 )
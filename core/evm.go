@@ -52,16 +52,17 @@ func NewEVMBlockContext(header *types.Header, chain ChainContext, author *common
 		baseFee = new(big.Int).Set(header.BaseFee)
 	}
 	return vm.BlockContext{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		GetHash:     GetHashFn(header, chain),
-		Coinbase:    beneficiary,
-		BlockNumber: new(big.Int).Set(header.Number),
-		Time:        new(big.Int).SetUint64(header.Time),
-		Difficulty:  new(big.Int).Set(header.Difficulty),
-		BaseFee:     baseFee,
-		GasLimit:    header.GasLimit,
-		CanCreate:   GetCanCreateFn(chain),
+		CanTransfer:     CanTransfer,
+		Transfer:        Transfer,
+		GetHash:         GetHashFn(header, chain),
+		Coinbase:        beneficiary,
+		BlockNumber:     new(big.Int).Set(header.Number),
+		Time:            new(big.Int).SetUint64(header.Time),
+		Difficulty:      new(big.Int).Set(header.Difficulty),
+		BaseFee:         baseFee,
+		GasLimit:        header.GasLimit,
+		CanCreate:       GetCanCreateFn(chain),
+		ContractCreated: GetContractCreatedFn(chain),
 	}
 }
 
@@ -121,17 +122,31 @@ func Transfer(db vm.StateDB, sender, recipient common.Address, amount *big.Int)
 
 func GetCanCreateFn(chain ChainContext) vm.CanCreateFunc {
 	if chain == nil || chain.Engine() == nil {
-		return func(db vm.StateDB, address common.Address, height *big.Int) bool {
+		return func(db vm.StateDB, origin common.Address, caller common.Address, height *big.Int) bool {
 			return true
 		}
 	}
 	posa, isPoSA := chain.Engine().(consensus.PoSA)
 	if isPoSA {
-		return func(db vm.StateDB, address common.Address, height *big.Int) bool {
-			return posa.CanCreate(db, address, height)
+		return func(db vm.StateDB, origin common.Address, caller common.Address, height *big.Int) bool {
+			return posa.CanCreate(db, origin, caller, height)
 		}
 	}
-	return func(db vm.StateDB, address common.Address, height *big.Int) bool {
+	return func(db vm.StateDB, origin common.Address, caller common.Address, height *big.Int) bool {
 		return true
 	}
 }
+
+// GetContractCreatedFn returns the hook recording the contracts created, nil if
+// the engine doesn't keep track of them.
+func GetContractCreatedFn(chain ChainContext) vm.ContractCreatedFunc {
+	if chain == nil || chain.Engine() == nil {
+		return nil
+	}
+	if posa, isPoSA := chain.Engine().(consensus.PoSA); isPoSA {
+		return func(db vm.StateDB, caller common.Address, contract common.Address, height *big.Int) uint64 {
+			return posa.ContractCreated(db, caller, contract, height)
+		}
+	}
+	return nil
+}
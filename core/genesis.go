@@ -273,7 +273,7 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 		Nonce:      types.EncodeNonce(g.Nonce),
 		Time:       g.Timestamp,
 		ParentHash: g.ParentHash,
-		Extra:      g.ExtraData,
+		Extra:      g.extraData(),
 		GasLimit:   g.GasLimit,
 		GasUsed:    g.GasUsed,
 		BaseFee:    g.BaseFee,
@@ -315,6 +315,9 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	if err := config.CheckConfigForkOrder(); err != nil {
 		return nil, err
 	}
+	if err := g.checkDposValidators(); err != nil {
+		return nil, err
+	}
 	rawdb.WriteTd(db, block.Hash(), block.NumberU64(), g.Difficulty)
 	rawdb.WriteBlock(db, block)
 	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
@@ -326,6 +329,62 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	return block, nil
 }
 
+// extraData returns the extra-data of the genesis header. If a dpos genesis lists
+// its initial validators but no extra-data, the validator list of the extra-data
+// is filled from them.
+func (g *Genesis) extraData() []byte {
+	if len(g.ExtraData) > 0 || g.Config == nil || g.Config.Dpos == nil || len(g.Config.Dpos.InitialValidators) == 0 {
+		return g.ExtraData
+	}
+	extra := make([]byte, 32)
+	for _, val := range g.Config.Dpos.InitialValidators {
+		extra = append(extra, val.Address[:]...)
+	}
+	return append(extra, make([]byte, crypto.SignatureLength)...)
+}
+
+// checkDposValidators checks that the initial validators of a dpos genesis are the
+// validators listed in the extra-data, and that each genesis validator is allocated
+// enough balance to pay its deposit, the default one if none are configured.
+func (g *Genesis) checkDposValidators() error {
+	if g.Config == nil || g.Config.Dpos == nil {
+		return nil
+	}
+	initials := g.Config.Dpos.InitialValidators
+	if err := g.Config.Dpos.CheckInitialValidators(); err != nil {
+		return err
+	}
+	extra := g.extraData()
+	if len(extra) < 32+crypto.SignatureLength || (len(extra)-32-crypto.SignatureLength)%common.AddressLength != 0 {
+		return errors.New("invalid dpos genesis extra-data")
+	}
+	var validators []common.Address
+	for i := 32; i < len(extra)-crypto.SignatureLength; i += common.AddressLength {
+		validators = append(validators, common.BytesToAddress(extra[i:i+common.AddressLength]))
+	}
+	deposits := make(map[common.Address]*big.Int)
+	for _, val := range validators {
+		deposits[val] = params.DposDefaultDeposit
+	}
+	if len(initials) > 0 {
+		if len(deposits) != len(initials) {
+			return fmt.Errorf("dpos initial validators mismatch: %d in extra-data, %d configured", len(deposits), len(initials))
+		}
+		for _, val := range initials {
+			if _, ok := deposits[val.Address]; !ok {
+				return fmt.Errorf("dpos initial validator %x missing from extra-data", val.Address)
+			}
+			deposits[val.Address] = val.Deposit
+		}
+	}
+	for _, val := range validators {
+		if balance := g.Alloc[val].Balance; balance == nil || balance.Cmp(deposits[val]) < 0 {
+			return fmt.Errorf("dpos genesis validator %x can't pay its deposit of %v", val, deposits[val])
+		}
+	}
+	return nil
+}
+
 // MustCommit writes the genesis block and state to db, panicking on error.
 // The block is committed as the canonical head block.
 func (g *Genesis) MustCommit(db ethdb.Database) *types.Block {
@@ -171,3 +171,26 @@ func TestGenesisHashes(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckDposValidators(t *testing.T) {
+	val := common.HexToAddress("0x1001")
+	config := *params.TestChainConfig
+	config.Dpos = &params.DposConfig{Epoch: 10}
+	genesis := &Genesis{
+		Config:    &config,
+		ExtraData: append(append(make([]byte, 32), val[:]...), make([]byte, 65)...),
+		Alloc:     GenesisAlloc{val: {Balance: big.NewInt(1)}},
+	}
+	// Without configured initial validators the default deposit is paid
+	if err := genesis.checkDposValidators(); err == nil {
+		t.Errorf("default deposit exceeding the balance accepted")
+	}
+	genesis.Alloc[val] = GenesisAccount{Balance: params.DposDefaultDeposit}
+	if err := genesis.checkDposValidators(); err != nil {
+		t.Errorf("default deposit rejected: %v", err)
+	}
+	config.Dpos.InitialValidators = []params.DposInitialValidator{{Address: val, Deposit: new(big.Int).Add(params.DposDefaultDeposit, common.Big1)}}
+	if err := genesis.checkDposValidators(); err == nil {
+		t.Errorf("configured deposit exceeding the balance accepted")
+	}
+}
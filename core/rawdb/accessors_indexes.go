@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/DxChainNetwork/dxc/common"
@@ -175,3 +176,117 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// ReadDposEpochIndex retrieves the encoded dpos staking state indexed at the epoch
+// checkpoint with the given number and state root.
+func ReadDposEpochIndex(db ethdb.KeyValueReader, number uint64, root common.Hash) []byte {
+	data, _ := db.Get(dposEpochKey(number, root))
+	return data
+}
+
+// WriteDposEpochIndex stores the encoded dpos staking state of an epoch checkpoint.
+func WriteDposEpochIndex(db ethdb.KeyValueWriter, number uint64, root common.Hash, data []byte) {
+	if err := db.Put(dposEpochKey(number, root), data); err != nil {
+		log.Crit("Failed to store dpos epoch index", "err", err)
+	}
+}
+
+// DeleteDposEpochIndex removes the dpos staking state of an epoch checkpoint.
+func DeleteDposEpochIndex(db ethdb.KeyValueWriter, number uint64, root common.Hash) {
+	if err := db.Delete(dposEpochKey(number, root)); err != nil {
+		log.Crit("Failed to delete dpos epoch index", "err", err)
+	}
+}
+
+// ReadDposVoteIndexHead retrieves the number and hash of the latest block whose
+// votes have been indexed.
+func ReadDposVoteIndexHead(db ethdb.KeyValueReader) (uint64, common.Hash, bool) {
+	data, _ := db.Get(dposVoteIndexHeadKey)
+	if len(data) != 8+common.HashLength {
+		return 0, common.Hash{}, false
+	}
+	return binary.BigEndian.Uint64(data[:8]), common.BytesToHash(data[8:]), true
+}
+
+// WriteDposVoteIndexHead stores the number and hash of the latest block whose
+// votes have been indexed.
+func WriteDposVoteIndexHead(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Put(dposVoteIndexHeadKey, append(encodeBlockNumber(number), hash.Bytes()...)); err != nil {
+		log.Crit("Failed to store dpos vote index head", "err", err)
+	}
+}
+
+// ReadDposVotes retrieves the votes of the voter for the validator.
+func ReadDposVotes(db ethdb.KeyValueReader, validator, voter common.Address) *big.Int {
+	data, _ := db.Get(dposValidatorVotesKey(validator, voter))
+	return new(big.Int).SetBytes(data)
+}
+
+// WriteDposVotes stores the votes of the voter for the validator in both
+// directions, removing them once they reach zero.
+func WriteDposVotes(db ethdb.KeyValueWriter, validator, voter common.Address, votes *big.Int) {
+	if votes.Sign() == 0 {
+		if err := db.Delete(dposValidatorVotesKey(validator, voter)); err != nil {
+			log.Crit("Failed to delete dpos votes", "err", err)
+		}
+		if err := db.Delete(dposVoterVotesKey(voter, validator)); err != nil {
+			log.Crit("Failed to delete dpos votes", "err", err)
+		}
+		return
+	}
+	if err := db.Put(dposValidatorVotesKey(validator, voter), votes.Bytes()); err != nil {
+		log.Crit("Failed to store dpos votes", "err", err)
+	}
+	if err := db.Put(dposVoterVotesKey(voter, validator), votes.Bytes()); err != nil {
+		log.Crit("Failed to store dpos votes", "err", err)
+	}
+}
+
+// IterateDposValidatorVotes returns an iterator over the votes for the validator,
+// ordered by voter from the given one on. The iterated keys end with the voter.
+func IterateDposValidatorVotes(db ethdb.Iteratee, validator, start common.Address) ethdb.Iterator {
+	return db.NewIterator(append(dposValidatorVotesPrefix, validator.Bytes()...), start.Bytes())
+}
+
+// IterateDposVoterVotes returns an iterator over the votes of the voter, ordered
+// by validator from the given one on. The iterated keys end with the validator.
+func IterateDposVoterVotes(db ethdb.Iteratee, voter, start common.Address) ethdb.Iterator {
+	return db.NewIterator(append(dposVoterVotesPrefix, voter.Bytes()...), start.Bytes())
+}
+
+// ReadDposDevIndexHead retrieves the number and hash of the latest block whose
+// developer changes have been indexed.
+func ReadDposDevIndexHead(db ethdb.KeyValueReader) (uint64, common.Hash, bool) {
+	data, _ := db.Get(dposDevIndexHeadKey)
+	if len(data) != 8+common.HashLength {
+		return 0, common.Hash{}, false
+	}
+	return binary.BigEndian.Uint64(data[:8]), common.BytesToHash(data[8:]), true
+}
+
+// WriteDposDevIndexHead stores the number and hash of the latest block whose
+// developer changes have been indexed.
+func WriteDposDevIndexHead(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Put(dposDevIndexHeadKey, append(encodeBlockNumber(number), hash.Bytes()...)); err != nil {
+		log.Crit("Failed to store dpos developer index head", "err", err)
+	}
+}
+
+// WriteDposDeveloper adds the developer to the indexed allowlist, or removes it.
+func WriteDposDeveloper(db ethdb.KeyValueWriter, developer common.Address, allowed bool) {
+	if !allowed {
+		if err := db.Delete(dposDeveloperKey(developer)); err != nil {
+			log.Crit("Failed to delete dpos developer", "err", err)
+		}
+		return
+	}
+	if err := db.Put(dposDeveloperKey(developer), []byte{0x01}); err != nil {
+		log.Crit("Failed to store dpos developer", "err", err)
+	}
+}
+
+// IterateDposDevelopers returns an iterator over the indexed developers, ordered
+// by address from the given one on. The iterated keys end with the developer.
+func IterateDposDevelopers(db ethdb.Iteratee, start common.Address) ethdb.Iterator {
+	return db.NewIterator(dposDeveloperPrefix, start.Bytes())
+}
@@ -309,6 +309,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		bloomBits       stat
 		cliqueSnaps     stat
 		dposSnaps       stat
+		dposEpochs      stat
+		dposVotes       stat
+		dposDevelopers  stat
 
 		// Ancient store statistics
 		ancientHeadersSize  common.StorageSize
@@ -368,6 +371,12 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
+		case bytes.HasPrefix(key, dposEpochPrefix) && len(key) == (len(dposEpochPrefix)+8+common.HashLength):
+			dposEpochs.Add(size)
+		case (bytes.HasPrefix(key, dposValidatorVotesPrefix) || bytes.HasPrefix(key, dposVoterVotesPrefix)) && len(key) == len(dposVoterVotesPrefix)+2*common.AddressLength:
+			dposVotes.Add(size)
+		case bytes.HasPrefix(key, dposDeveloperPrefix) && len(key) == len(dposDeveloperPrefix)+common.AddressLength:
+			dposDevelopers.Add(size)
 		case bytes.HasPrefix(key, []byte("dpos-")) && len(key) == 7+common.HashLength:
 			dposSnaps.Add(size)
 		case bytes.HasPrefix(key, []byte("cht-")) ||
@@ -384,7 +393,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, lastPivotKey,
 				fastTrieProgressKey, snapshotDisabledKey, snapshotRootKey, snapshotJournalKey,
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
-				uncleanShutdownKey, badBlockKey,
+				uncleanShutdownKey, badBlockKey, dposVoteIndexHeadKey, dposDevIndexHeadKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -432,6 +441,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
 		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
 		{"Key-Value store", "Dpos snapshots", dposSnaps.Size(), dposSnaps.Count()},
+		{"Key-Value store", "Dpos epoch index", dposEpochs.Size(), dposEpochs.Count()},
+		{"Key-Value store", "Dpos vote index", dposVotes.Size(), dposVotes.Count()},
+		{"Key-Value store", "Dpos developer index", dposDevelopers.Size(), dposDevelopers.Count()},
 		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
 		{"Ancient store", "Headers", ancientHeadersSize.String(), ancients.String()},
 		{"Ancient store", "Bodies", ancientBodiesSize.String(), ancients.String()},
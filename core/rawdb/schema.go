@@ -39,6 +39,9 @@ var (
 	// headFastBlockKey tracks the latest known incomplete block's hash during fast sync.
 	headFastBlockKey = []byte("LastFast")
 
+	// finalizedBlockKey tracks the latest block finalized by validator attestations.
+	finalizedBlockKey = []byte("LastFinalized")
+
 	// lastPivotKey tracks the last pivot block used by fast sync (to reenable on sethead).
 	lastPivotKey = []byte("LastPivot")
 
@@ -69,6 +72,12 @@ var (
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
+	// dposVoteIndexHeadKey tracks the latest block whose votes have been indexed.
+	dposVoteIndexHeadKey = []byte("DposVoteIndexHead")
+
+	// dposDevIndexHeadKey tracks the latest block whose developer changes have been indexed.
+	dposDevIndexHeadKey = []byte("DposDevIndexHead")
+
 	// badBlockKey tracks the list of bad blocks seen by local
 	badBlockKey = []byte("InvalidBlock")
 
@@ -90,6 +99,11 @@ var (
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
 
+	dposEpochPrefix          = []byte("dpos-epoch-")  // dposEpochPrefix + num (uint64 big endian) + state root -> dpos staking state at the epoch checkpoint
+	dposValidatorVotesPrefix = []byte("dpos-votes-c") // dposValidatorVotesPrefix + validator + voter -> votes
+	dposVoterVotesPrefix     = []byte("dpos-votes-v") // dposVoterVotesPrefix + voter + validator -> votes
+	dposDeveloperPrefix      = []byte("dpos-dev-")    // dposDeveloperPrefix + developer -> allowlisted flag
+
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
@@ -207,6 +221,26 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// dposEpochKey = dposEpochPrefix + num (uint64 big endian) + state root
+func dposEpochKey(number uint64, root common.Hash) []byte {
+	return append(append(dposEpochPrefix, encodeBlockNumber(number)...), root.Bytes()...)
+}
+
+// dposValidatorVotesKey = dposValidatorVotesPrefix + validator + voter
+func dposValidatorVotesKey(validator, voter common.Address) []byte {
+	return append(append(dposValidatorVotesPrefix, validator.Bytes()...), voter.Bytes()...)
+}
+
+// dposVoterVotesKey = dposVoterVotesPrefix + voter + validator
+func dposVoterVotesKey(voter, validator common.Address) []byte {
+	return append(append(dposVoterVotesPrefix, voter.Bytes()...), validator.Bytes()...)
+}
+
+// dposDeveloperKey = dposDeveloperPrefix + developer
+func dposDeveloperKey(developer common.Address) []byte {
+	return append(dposDeveloperPrefix, developer.Bytes()...)
+}
+
 // preimageKey = preimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(preimagePrefix, hash.Bytes()...)
@@ -369,8 +369,8 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 	}
 	// Check if can create
 	if contractCreation && st.evm.Context.CanCreate != nil {
-		if !st.evm.Context.CanCreate(st.evm.StateDB, msg.From(), st.evm.Context.BlockNumber) {
-			return nil, ErrUnauthorizedDeveloper
+		if !st.evm.Context.CanCreate(st.evm.StateDB, msg.From(), msg.From(), st.evm.Context.BlockNumber) {
+			return nil, fmt.Errorf("%w: address %v", ErrUnauthorizedDeveloper, msg.From().Hex())
 		}
 	}
 
@@ -664,7 +664,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	// do some extra validation if needed
 	if pool.txValidator != nil && !pool.disableExValidate {
 		err := pool.txValidator.ValidateTx(from, tx, pool.nextFakeHeader, pool.currentState)
-		if err == types.ErrAddressDenied {
+		if errors.Is(err, types.ErrAddressDenied) {
 			return err
 		}
 		if err != nil {
@@ -1,12 +1,49 @@
 package types
 
-import "github.com/DxChainNetwork/dxc/common"
+import (
+	"fmt"
+
+	"github.com/DxChainNetwork/dxc/common"
+)
 
 // EvmExtraValidator contains some extra validations to a transaction,
 // and the validator is used inside the evm.
 type EvmExtraValidator interface {
-	// IsAddressDenied returns whether an address is denied.
-	IsAddressDenied(address common.Address, cType common.AddressCheckType) bool
-	// IsLogDenied returns whether a log (contract event) is denied.
-	IsLogDenied(log *Log) bool
+	// CheckAddress returns an *AddressDeniedError if the address is denied.
+	CheckAddress(address common.Address, cType common.AddressCheckType) error
+	// CheckLog returns an *AddressDeniedError if the log (contract event) is denied.
+	CheckLog(log *Log) error
+}
+
+// AddressDeniedError explains why a transaction or a log was denied. It matches
+// ErrAddressDenied with errors.Is, and is returned as the error data over RPC.
+type AddressDeniedError struct {
+	Address   common.Address `json:"address"`
+	Direction string         `json:"direction"` // Direction the address is blacklisted in: from, to or both
+	Check     string         `json:"check"`     // Direction the address was checked in: from, to or any
+
+	Expiry uint64          `json:"expiry,omitempty"` // First block the blacklist entry doesn't apply to, if expiring
+	Scope  *common.Address `json:"scope,omitempty"`  // Contract whose events the blacklist entry is scoped to, if any
+
+	// Event rule denying the log, if the address was taken from a log topic
+	EventSig   *common.Hash `json:"eventSig,omitempty"`
+	CheckIndex int          `json:"checkIndex,omitempty"` // Index of the topic holding the address
+}
+
+// Error implements error.
+func (e *AddressDeniedError) Error() string {
+	if e.EventSig != nil {
+		return fmt.Sprintf("%v: %v in topic %d of event %v, blacklisted %s", ErrAddressDenied, e.Address.Hex(), e.CheckIndex, e.EventSig.Hex(), e.Direction)
+	}
+	return fmt.Sprintf("%v: %v as %s, blacklisted %s", ErrAddressDenied, e.Address.Hex(), e.Check, e.Direction)
+}
+
+// Is reports whether the target is ErrAddressDenied.
+func (e *AddressDeniedError) Is(target error) bool {
+	return target == ErrAddressDenied
+}
+
+// ErrorData returns the denial details as the data of the RPC error.
+func (e *AddressDeniedError) ErrorData() interface{} {
+	return e
 }
@@ -35,7 +35,7 @@ var (
 	ErrReturnDataOutOfBounds    = errors.New("return data out of bounds")
 	ErrGasUintOverflow          = errors.New("gas uint64 overflow")
 	ErrInvalidCode              = errors.New("invalid code: must not begin with 0xef")
-	ErrUnauthorizedDeveloper    = errors.New("unauthorized developer")
+	ErrUnauthorizedDeveloper    = errors.New("creator not in developer allowlist")
 )
 
 // ErrStackUnderflow wraps an evm error when the items on the stack less
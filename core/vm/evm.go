@@ -41,7 +41,10 @@ type (
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
 	// CanCreateFunc is the signature of a contract creation guard function
-	CanCreateFunc func(db StateDB, address common.Address, height *big.Int) bool
+	CanCreateFunc func(db StateDB, origin common.Address, caller common.Address, height *big.Int) bool
+	// ContractCreatedFunc is the signature of a contract creation hook, returning
+	// the gas charged to the creation for the state it writes
+	ContractCreatedFunc func(db StateDB, caller common.Address, contract common.Address, height *big.Int) uint64
 )
 
 func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
@@ -72,6 +75,8 @@ type BlockContext struct {
 	GetHash GetHashFunc
 	// CanCreate returns whether a given address can create a new contract
 	CanCreate CanCreateFunc
+	// ContractCreated records the creator of a new contract
+	ContractCreated ContractCreatedFunc
 	// ExtraValidator do some extra validation to a message during it's execution
 	ExtraValidator types.EvmExtraValidator
 
@@ -182,9 +187,11 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 
 	// Check whether the involved addresses are denied if needed
 	if evm.Context.ExtraValidator != nil && evm.depth > 0 {
-		if evm.Context.ExtraValidator.IsAddressDenied(caller.Address(), common.CheckFrom) ||
-			evm.Context.ExtraValidator.IsAddressDenied(addr, common.CheckTo) {
-			return nil, gas, types.ErrAddressDenied
+		if err := evm.Context.ExtraValidator.CheckAddress(caller.Address(), common.CheckFrom); err != nil {
+			return nil, gas, err
+		}
+		if err := evm.Context.ExtraValidator.CheckAddress(addr, common.CheckTo); err != nil {
+			return nil, gas, err
 		}
 	}
 
@@ -267,9 +274,11 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 
 	// Check whether the involved addresses are denied if needed
 	if evm.Context.ExtraValidator != nil {
-		if evm.Context.ExtraValidator.IsAddressDenied(caller.Address(), common.CheckFrom) ||
-			evm.Context.ExtraValidator.IsAddressDenied(addr, common.CheckTo) {
-			return nil, gas, types.ErrAddressDenied
+		if err := evm.Context.ExtraValidator.CheckAddress(caller.Address(), common.CheckFrom); err != nil {
+			return nil, gas, err
+		}
+		if err := evm.Context.ExtraValidator.CheckAddress(addr, common.CheckTo); err != nil {
+			return nil, gas, err
 		}
 	}
 
@@ -319,9 +328,11 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 
 	// Check whether the involved addresses are denied if needed
 	if evm.Context.ExtraValidator != nil {
-		if evm.Context.ExtraValidator.IsAddressDenied(caller.Address(), common.CheckFrom) ||
-			evm.Context.ExtraValidator.IsAddressDenied(addr, common.CheckTo) {
-			return nil, gas, types.ErrAddressDenied
+		if err := evm.Context.ExtraValidator.CheckAddress(caller.Address(), common.CheckFrom); err != nil {
+			return nil, gas, err
+		}
+		if err := evm.Context.ExtraValidator.CheckAddress(addr, common.CheckTo); err != nil {
+			return nil, gas, err
 		}
 	}
 
@@ -362,9 +373,11 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 
 	// Check whether the involved addresses are denied if needed
 	if evm.Context.ExtraValidator != nil {
-		if evm.Context.ExtraValidator.IsAddressDenied(caller.Address(), common.CheckFrom) ||
-			evm.Context.ExtraValidator.IsAddressDenied(addr, common.CheckTo) {
-			return nil, gas, types.ErrAddressDenied
+		if err := evm.Context.ExtraValidator.CheckAddress(caller.Address(), common.CheckFrom); err != nil {
+			return nil, gas, err
+		}
+		if err := evm.Context.ExtraValidator.CheckAddress(addr, common.CheckTo); err != nil {
+			return nil, gas, err
 		}
 	}
 
@@ -428,7 +441,7 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	}
 	// check developer if needed
 	if evm.Context.CanCreate != nil {
-		if !evm.Context.CanCreate(evm.StateDB, caller.Address(), evm.Context.BlockNumber) {
+		if !evm.Context.CanCreate(evm.StateDB, evm.Origin, caller.Address(), evm.Context.BlockNumber) {
 			return nil, common.Address{}, gas, ErrUnauthorizedDeveloper
 		}
 	}
@@ -454,6 +467,14 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	if evm.chainRules.IsEIP158 {
 		evm.StateDB.SetNonce(address, 1)
 	}
+	if evm.Context.ContractCreated != nil {
+		cost := evm.Context.ContractCreated(evm.StateDB, caller.Address(), address, evm.Context.BlockNumber)
+		if gas < cost {
+			evm.StateDB.RevertToSnapshot(snapshot)
+			return nil, address, 0, ErrOutOfGas
+		}
+		gas -= cost
+	}
 	evm.Context.Transfer(evm.StateDB, caller.Address(), address, value)
 
 	// Initialise a new contract and set the code that is to be used by the EVM.
@@ -817,8 +817,8 @@ func makeLog(size int) executionFunc {
 			BlockNumber: interpreter.evm.Context.BlockNumber.Uint64(),
 		}
 		if interpreter.evm.Context.ExtraValidator != nil {
-			if interpreter.evm.Context.ExtraValidator.IsLogDenied(evLog) {
-				return nil, types.ErrAddressDenied
+			if err := interpreter.evm.Context.ExtraValidator.CheckLog(evLog); err != nil {
+				return nil, err
 			}
 		}
 		interpreter.evm.StateDB.AddLog(evLog)
@@ -42,6 +42,7 @@ import (
 	"github.com/DxChainNetwork/dxc/eth/ethconfig"
 	"github.com/DxChainNetwork/dxc/eth/filters"
 	"github.com/DxChainNetwork/dxc/eth/gasprice"
+	"github.com/DxChainNetwork/dxc/eth/protocols/attest"
 	"github.com/DxChainNetwork/dxc/eth/protocols/eth"
 	"github.com/DxChainNetwork/dxc/eth/protocols/snap"
 	"github.com/DxChainNetwork/dxc/ethdb"
@@ -218,6 +219,16 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		eth.txPool.InitExTxValidator(dposEngine)
 		//
 		dposEngine.SetChain(eth.blockchain)
+		dposEngine.StartBlockEvents(eth.blockchain)
+		if config.DposEpochIndex {
+			dposEngine.StartEpochIndex(eth.blockchain, eth.blockchain)
+		}
+		if config.DposVoteIndex {
+			dposEngine.StartVoteIndex(eth.blockchain)
+		}
+		if config.DposDevIndex {
+			dposEngine.StartDevIndex(eth.blockchain)
+		}
 	}
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -529,12 +540,18 @@ func (s *Ethereum) StartMining(threads int) error {
 			return fmt.Errorf("etherbase missing: %v", err)
 		}
 		if dpos, ok := s.engine.(*dpos.Dpos); ok {
-			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			// The validator may seal with a signing key bound to it, keeping the
+			// staking account offline.
+			key := eb
+			if s.config.DposSigningKey != (common.Address{}) {
+				key = s.config.DposSigningKey
+			}
+			wallet, err := s.accountManager.Find(accounts.Account{Address: key})
 			if wallet == nil || err != nil {
-				log.Error("Etherbase account unavailable locally", "err", err)
+				log.Error("Signing key unavailable locally", "key", key, "err", err)
 				return fmt.Errorf("signer missing: %v", err)
 			}
-			dpos.Authorize(eb, wallet.SignData, wallet.SignTx)
+			dpos.AuthorizeSigningKey(eb, key, wallet.SignData, wallet.SignTx)
 		}
 		if clique, ok := s.engine.(*clique.Clique); ok {
 			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
@@ -589,6 +606,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	if s.handler.dposEngine != nil {
+		protos = append(protos, attest.MakeProtocols((*attestHandler)(s.handler))...)
+	}
 	return protos
 }
 
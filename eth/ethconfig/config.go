@@ -154,6 +154,12 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	DposEpochIndex bool `toml:",omitempty"` // Whether to index the dpos staking state at every epoch checkpoint
+	DposVoteIndex  bool `toml:",omitempty"` // Whether to index the dpos votes by validator and by voter
+	DposDevIndex   bool `toml:",omitempty"` // Whether to index the developer allowlist of the address list contract
+
+	DposSigningKey common.Address `toml:",omitempty"` // Key sealing the blocks of the etherbase validator, if bound to another account
+
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
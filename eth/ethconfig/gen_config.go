@@ -26,6 +26,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NoPruning               bool
 		NoPrefetch              bool
 		TxLookupLimit           uint64                 `toml:",omitempty"`
+		DposEpochIndex          bool                   `toml:",omitempty"`
+		DposVoteIndex           bool                   `toml:",omitempty"`
+		DposDevIndex            bool                   `toml:",omitempty"`
+		DposSigningKey          common.Address         `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -69,6 +73,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.DposEpochIndex = c.DposEpochIndex
+	enc.DposVoteIndex = c.DposVoteIndex
+	enc.DposDevIndex = c.DposDevIndex
+	enc.DposSigningKey = c.DposSigningKey
 	enc.Whitelist = c.Whitelist
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -116,6 +124,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NoPruning               *bool
 		NoPrefetch              *bool
 		TxLookupLimit           *uint64                `toml:",omitempty"`
+		DposEpochIndex          *bool                  `toml:",omitempty"`
+		DposVoteIndex           *bool                  `toml:",omitempty"`
+		DposDevIndex            *bool                  `toml:",omitempty"`
+		DposSigningKey          *common.Address        `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -178,6 +190,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.DposEpochIndex != nil {
+		c.DposEpochIndex = *dec.DposEpochIndex
+	}
+	if dec.DposVoteIndex != nil {
+		c.DposVoteIndex = *dec.DposVoteIndex
+	}
+	if dec.DposDevIndex != nil {
+		c.DposDevIndex = *dec.DposDevIndex
+	}
+	if dec.DposSigningKey != nil {
+		c.DposSigningKey = *dec.DposSigningKey
+	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
@@ -25,11 +25,13 @@ import (
 	"time"
 
 	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos"
 	"github.com/DxChainNetwork/dxc/core"
 	"github.com/DxChainNetwork/dxc/core/forkid"
 	"github.com/DxChainNetwork/dxc/core/types"
 	"github.com/DxChainNetwork/dxc/eth/downloader"
 	"github.com/DxChainNetwork/dxc/eth/fetcher"
+	"github.com/DxChainNetwork/dxc/eth/protocols/attest"
 	"github.com/DxChainNetwork/dxc/eth/protocols/eth"
 	"github.com/DxChainNetwork/dxc/eth/protocols/snap"
 	"github.com/DxChainNetwork/dxc/ethdb"
@@ -44,6 +46,9 @@ const (
 	// txChanSize is the size of channel listening to NewTxsEvent.
 	// The number is referenced from the size of tx pool.
 	txChanSize = 4096
+
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
 )
 
 var (
@@ -114,6 +119,13 @@ type handler struct {
 	txsSub        event.Subscription
 	minedBlockSub *event.TypeMuxSubscription
 
+	// attestation gossip, only enabled if the consensus engine is dpos
+	dposEngine   *dpos.Dpos
+	attestPeers  map[string]*attest.Peer
+	attestLock   sync.RWMutex
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+
 	whitelist map[uint64]common.Hash
 
 	// channels for fetcher, syncer, txsyncLoop
@@ -143,6 +155,10 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		txsyncCh:   make(chan *txsync),
 		quitSync:   make(chan struct{}),
 	}
+	if engine, ok := config.Chain.Engine().(*dpos.Dpos); ok {
+		h.dposEngine = engine
+		h.attestPeers = make(map[string]*attest.Peer)
+	}
 	if config.Sync == downloader.FullSync {
 		// The database seems empty as the current block is the genesis. Yet the fast
 		// block is ahead, so fast sync was enabled for this node at a certain point.
@@ -407,6 +423,14 @@ func (h *handler) Start(maxPeers int) {
 	h.minedBlockSub = h.eventMux.Subscribe(core.NewMinedBlockEvent{})
 	go h.minedBroadcastLoop()
 
+	// attest and gossip new chain heads
+	if h.dposEngine != nil {
+		h.wg.Add(1)
+		h.chainHeadCh = make(chan core.ChainHeadEvent, chainHeadChanSize)
+		h.chainHeadSub = h.chain.SubscribeChainHeadEvent(h.chainHeadCh)
+		go h.attestLoop()
+	}
+
 	// start sync handlers
 	h.wg.Add(2)
 	go h.chainSync.loop()
@@ -416,6 +440,9 @@ func (h *handler) Start(maxPeers int) {
 func (h *handler) Stop() {
 	h.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	h.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
+	if h.chainHeadSub != nil {
+		h.chainHeadSub.Unsubscribe() // quits attestLoop
+	}
 
 	// Quit chainSync and txsync64.
 	// After this is done, no new peers will be accepted.
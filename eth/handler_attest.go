@@ -0,0 +1,134 @@
+package eth
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/DxChainNetwork/dxc/consensus/dpos"
+	"github.com/DxChainNetwork/dxc/core"
+	"github.com/DxChainNetwork/dxc/eth/protocols/attest"
+	"github.com/DxChainNetwork/dxc/log"
+	"github.com/DxChainNetwork/dxc/p2p/enode"
+)
+
+// attestHandler implements the attest.Backend interface to handle the block
+// attestations gossiped by the validators.
+type attestHandler handler
+
+func (h *attestHandler) Chain() *core.BlockChain { return h.chain }
+
+// RunPeer is invoked when a peer joins on the `attest` protocol.
+func (h *attestHandler) RunPeer(peer *attest.Peer, hand attest.Handler) error {
+	return (*handler)(h).runAttestPeer(peer, hand)
+}
+
+// PeerInfo retrieves all known `attest` information about a peer.
+func (h *attestHandler) PeerInfo(id enode.ID) interface{} {
+	h.attestLock.RLock()
+	defer h.attestLock.RUnlock()
+
+	if p := h.attestPeers[id.String()]; p != nil {
+		return &struct {
+			Version uint `json:"version"`
+		}{p.Version()}
+	}
+	return nil
+}
+
+// Handle is invoked from a peer's message handler when it receives a new remote
+// message that the handler couldn't consume and serve itself.
+func (h *attestHandler) Handle(peer *attest.Peer, packet attest.Packet) error {
+	switch packet := packet.(type) {
+	case *attest.AttestationsPacket:
+		var fresh []*dpos.Attestation
+		for _, att := range *packet {
+			added, err := h.dposEngine.AddAttestation(h.chain, att)
+			if err != nil {
+				peer.Log().Trace("Discarded block attestation", "number", att.Number, "hash", att.Hash, "err", err)
+				continue
+			}
+			if added {
+				fresh = append(fresh, att)
+			}
+		}
+		(*handler)(h).BroadcastAttestations(fresh)
+		return nil
+
+	default:
+		return fmt.Errorf("unexpected attest packet type: %T", packet)
+	}
+}
+
+// runAttestPeer registers an `attest` peer and starts handling inbound messages.
+// The peer is dropped from the attestation gossip once the handler returns.
+func (h *handler) runAttestPeer(peer *attest.Peer, handler attest.Handler) error {
+	h.peerWG.Add(1)
+	defer h.peerWG.Done()
+
+	h.attestLock.Lock()
+	if _, ok := h.attestPeers[peer.ID()]; ok {
+		h.attestLock.Unlock()
+		return errPeerAlreadyRegistered
+	}
+	h.attestPeers[peer.ID()] = peer
+	h.attestLock.Unlock()
+
+	defer func() {
+		h.attestLock.Lock()
+		delete(h.attestPeers, peer.ID())
+		h.attestLock.Unlock()
+	}()
+	return handler(peer)
+}
+
+// BroadcastAttestations propagates a batch of attestations to all `attest` peers
+// which are not known to already have them.
+func (h *handler) BroadcastAttestations(atts []*dpos.Attestation) {
+	if len(atts) == 0 {
+		return
+	}
+	h.attestLock.RLock()
+	defer h.attestLock.RUnlock()
+
+	for _, peer := range h.attestPeers {
+		var batch []*dpos.Attestation
+		for _, att := range atts {
+			if !peer.KnownAttestation(att.ID()) {
+				batch = append(batch, att)
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		if err := peer.SendAttestations(batch); err != nil {
+			peer.Log().Debug("Failed to send block attestations", "err", err)
+		}
+	}
+}
+
+// attestLoop signs an attestation for every new chain head once the node is in
+// sync, and gossips it to the connected validators.
+func (h *handler) attestLoop() {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case ev := <-h.chainHeadCh:
+			if atomic.LoadUint32(&h.acceptTxs) == 0 {
+				continue
+			}
+			att, err := h.dposEngine.Attest(h.chain, ev.Block.Header())
+			if err != nil {
+				log.Warn("Failed to attest block", "number", ev.Block.Number(), "hash", ev.Block.Hash(), "err", err)
+				continue
+			}
+			if att != nil {
+				h.BroadcastAttestations([]*dpos.Attestation{att})
+			}
+
+		// Err() channel will be closed when unsubscribing.
+		case <-h.chainHeadSub.Err():
+			return
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package attest
+
+import (
+	"github.com/DxChainNetwork/dxc/rlp"
+)
+
+// enrEntry is the ENR entry which advertises `attest` protocol on the discovery.
+type enrEntry struct {
+	// Ignore additional fields (for forward compatibility).
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// ENRKey implements enr.Entry.
+func (e enrEntry) ENRKey() string {
+	return "attest"
+}
@@ -0,0 +1,80 @@
+package attest
+
+import (
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos"
+	"github.com/DxChainNetwork/dxc/log"
+	"github.com/DxChainNetwork/dxc/p2p"
+	mapset "github.com/deckarep/golang-set"
+)
+
+const (
+	// maxKnownAttestations is the maximum attestation IDs to keep in the known list
+	// before starting to randomly evict them.
+	maxKnownAttestations = 32768
+)
+
+// Peer is a collection of relevant information we have about a `attest` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for attest
+	version   uint              // Protocol version negotiated
+
+	knownAttestations mapset.Set // Set of attestation IDs known to be known by this peer
+
+	logger log.Logger // Contextual logger with the peer id injected
+}
+
+// newPeer create a wrapper for a network connection and negotiated  protocol
+// version.
+func newPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID().String()
+	return &Peer{
+		id:                id,
+		Peer:              p,
+		rw:                rw,
+		version:           version,
+		knownAttestations: mapset.NewSet(),
+		logger:            log.New("peer", id[:8]),
+	}
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negoatiated `attest` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logget with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// KnownAttestation returns whether peer is known to already have an attestation.
+func (p *Peer) KnownAttestation(id common.Hash) bool {
+	return p.knownAttestations.Contains(id)
+}
+
+// markAttestation marks an attestation as known for the peer, ensuring that it
+// will never be propagated to this particular peer.
+func (p *Peer) markAttestation(id common.Hash) {
+	// If we reached the memory allowance, drop a previously known attestation
+	for p.knownAttestations.Cardinality() >= maxKnownAttestations {
+		p.knownAttestations.Pop()
+	}
+	p.knownAttestations.Add(id)
+}
+
+// SendAttestations propagates a batch of attestations to the remote peer.
+func (p *Peer) SendAttestations(atts []*dpos.Attestation) error {
+	for _, att := range atts {
+		p.markAttestation(att.ID())
+	}
+	return p2p.Send(p.rw, AttestationsMsg, atts)
+}
@@ -0,0 +1,50 @@
+package attest
+
+import (
+	"errors"
+
+	"github.com/DxChainNetwork/dxc/consensus/dpos"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	attest1 = 1
+)
+
+// ProtocolName is the official short name of the `attest` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "attest"
+
+// ProtocolVersions are the supported versions of the `attest` protocol (first
+// is primary).
+var ProtocolVersions = []uint{attest1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{attest1: 1}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 1 * 1024 * 1024
+
+const (
+	AttestationsMsg = 0x00
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+)
+
+// Packet represents a p2p message in the `attest` protocol.
+type Packet interface {
+	Name() string // Name returns a string corresponding to the message type.
+	Kind() byte   // Kind returns the message type.
+}
+
+// AttestationsPacket is the network packet for propagating block attestations
+// signed by validators.
+type AttestationsPacket []*dpos.Attestation
+
+func (*AttestationsPacket) Name() string { return "Attestations" }
+func (*AttestationsPacket) Kind() byte   { return AttestationsMsg }
@@ -0,0 +1,474 @@
+// Package dposclient provides an RPC client for the dpos consensus specific APIs,
+// along with helpers to build and sign the staking and governance transactions
+// locally.
+package dposclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus/dpos"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/ethclient"
+	"github.com/DxChainNetwork/dxc/rpc"
+)
+
+// Client is a wrapper around rpc.Client that implements the dpos specific functionality.
+//
+// The read methods take the block number to query the state at, nil for the latest
+// block. The special block numbers of package rpc, e.g. rpc.FinalizedBlockNumber,
+// are accepted as well.
+//
+// The transaction methods interact with the system contracts at the addresses
+// returned by systemcontract.ContractAddress, a chain overriding the built-in ones
+// requires systemcontract.ApplyConfig to be called with its dpos config first.
+type Client struct {
+	c   *rpc.Client
+	eth *ethclient.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL with the given context.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return New(c), nil
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	return &Client{c: c, eth: ethclient.NewClient(c)}
+}
+
+// Close closes the underlying RPC connection.
+func (dc *Client) Close() {
+	dc.c.Close()
+}
+
+// Snapshot and consensus state
+
+// GetSnapshot retrieves the state snapshot at the given block.
+func (dc *Client) GetSnapshot(ctx context.Context, number *big.Int) (*dpos.Snapshot, error) {
+	var snap *dpos.Snapshot
+	err := dc.c.CallContext(ctx, &snap, "dpos_getSnapshot", toBlockNumArg(number))
+	return snap, err
+}
+
+// GetSnapshotAtHash retrieves the state snapshot at the given block hash.
+func (dc *Client) GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*dpos.Snapshot, error) {
+	var snap *dpos.Snapshot
+	err := dc.c.CallContext(ctx, &snap, "dpos_getSnapshotAtHash", hash)
+	return snap, err
+}
+
+// GetConsensusParams retrieves the block period and epoch schedule in effect at the given block.
+func (dc *Client) GetConsensusParams(ctx context.Context, number *big.Int) (*dpos.ConsensusParams, error) {
+	var result *dpos.ConsensusParams
+	err := dc.c.CallContext(ctx, &result, "dpos_getConsensusParams", toBlockNumArg(number))
+	return result, err
+}
+
+// GetValidatorSetProof retrieves the proof of the validator set transitions following
+// the checkpoint at from, up to the given block.
+func (dc *Client) GetValidatorSetProof(ctx context.Context, from uint64, to *big.Int) (*dpos.ValidatorSetProof, error) {
+	var result *dpos.ValidatorSetProof
+	err := dc.c.CallContext(ctx, &result, "dpos_getValidatorSetProof", hexutil.Uint64(from), toBlockNumArg(to))
+	return result, err
+}
+
+// GetEpochLiveness retrieves the block production records of the validators in
+// the epoch of the given block.
+func (dc *Client) GetEpochLiveness(ctx context.Context, number *big.Int) (*dpos.EpochLiveness, error) {
+	var result *dpos.EpochLiveness
+	err := dc.c.CallContext(ctx, &result, "dpos_getEpochLiveness", toBlockNumArg(number))
+	return result, err
+}
+
+// GetValidatorLiveness retrieves the block production records of a validator in the
+// epochs tracked at the given block.
+func (dc *Client) GetValidatorLiveness(ctx context.Context, validator common.Address, number *big.Int) ([]*dpos.ValidatorLiveness, error) {
+	var result []*dpos.ValidatorLiveness
+	err := dc.c.CallContext(ctx, &result, "dpos_getValidatorLiveness", validator, toBlockNumArg(number))
+	return result, err
+}
+
+// GetValidators retrieves the list of validators sealing the given block.
+func (dc *Client) GetValidators(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	var result []common.Address
+	err := dc.c.CallContext(ctx, &result, "dpos_getValidators", toBlockNumArg(number))
+	return result, err
+}
+
+// GetValidatorsAtHash retrieves the list of validators sealing the given block hash.
+func (dc *Client) GetValidatorsAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	var result []common.Address
+	err := dc.c.CallContext(ctx, &result, "dpos_getValidatorsAtHash", hash)
+	return result, err
+}
+
+// Status is the sealing status of the recent blocks.
+type Status struct {
+	InturnPercent float64                `json:"inturnPercent"`
+	SigningStatus map[common.Address]int `json:"sealerActivity"`
+	NumBlocks     uint64                 `json:"numBlocks"`
+}
+
+// Status retrieves the sealing status of the recent blocks.
+func (dc *Client) Status(ctx context.Context) (*Status, error) {
+	var result *Status
+	err := dc.c.CallContext(ctx, &result, "dpos_status")
+	return result, err
+}
+
+// Validators
+
+// GetBaseInfos retrieves the base information of the system contracts.
+func (dc *Client) GetBaseInfos(ctx context.Context, number *big.Int) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := dc.c.CallContext(ctx, &result, "dpos_getBaseInfos", toBlockNumArg(number))
+	return result, err
+}
+
+// GetValidator retrieves the validator info of the given address.
+func (dc *Client) GetValidator(ctx context.Context, addr common.Address, number *big.Int) (*systemcontract.Validator, error) {
+	var result *systemcontract.Validator
+	err := dc.c.CallContext(ctx, &result, "dpos_getValidator", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// GetSigningKey retrieves the signing key bound to the validator at the given block.
+func (dc *Client) GetSigningKey(ctx context.Context, addr common.Address, number *big.Int) (*dpos.SigningKey, error) {
+	var result *dpos.SigningKey
+	err := dc.c.CallContext(ctx, &result, "dpos_getSigningKey", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// GetBlacklist retrieves the blacklisted addresses along with their entries.
+func (dc *Client) GetBlacklist(ctx context.Context, number *big.Int) (map[common.Address][]dpos.BlacklistEntry, error) {
+	var result map[common.Address][]dpos.BlacklistEntry
+	err := dc.c.CallContext(ctx, &result, "dpos_getBlacklist", toBlockNumArg(number))
+	return result, err
+}
+
+// GetEventCheckRules retrieves the event rules checking the log topics against the
+// blacklist.
+func (dc *Client) GetEventCheckRules(ctx context.Context, number *big.Int) (map[common.Hash]*dpos.EventCheckRule, error) {
+	var result map[common.Hash]*dpos.EventCheckRule
+	err := dc.c.CallContext(ctx, &result, "dpos_getEventCheckRules", toBlockNumArg(number))
+	return result, err
+}
+
+// GetTotalDeposit retrieves the total deposit of the validators.
+func (dc *Client) GetTotalDeposit(ctx context.Context, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_getTotalDeposit", toBlockNumArg(number))
+}
+
+// GetTotalVotes retrieves the total votes of the validators.
+func (dc *Client) GetTotalVotes(ctx context.Context, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_getTotalVotes", toBlockNumArg(number))
+}
+
+// GetCurrentEpochValidators retrieves the validators elected for the current epoch.
+func (dc *Client) GetCurrentEpochValidators(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	return dc.callAddresses(ctx, "dpos_getCurrentEpochValidators", toBlockNumArg(number))
+}
+
+// GetEffictiveValidators retrieves the effective validators.
+func (dc *Client) GetEffictiveValidators(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	return dc.callAddresses(ctx, "dpos_getEffictiveValidators", toBlockNumArg(number))
+}
+
+// GetInvalidValidators retrieves the invalid validators.
+func (dc *Client) GetInvalidValidators(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	return dc.callAddresses(ctx, "dpos_getInvalidValidators", toBlockNumArg(number))
+}
+
+// GetCancelQueueValidators retrieves the validators queued to unstake.
+func (dc *Client) GetCancelQueueValidators(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	return dc.callAddresses(ctx, "dpos_getCancelQueueValidators", toBlockNumArg(number))
+}
+
+// GetValidatorVoters retrieves the voters of the given validator.
+func (dc *Client) GetValidatorVoters(ctx context.Context, addr common.Address, number *big.Int) ([]common.Address, error) {
+	return dc.callAddresses(ctx, "dpos_getValidatorVoters", addr, toBlockNumArg(number))
+}
+
+// EffictiveValsLength retrieves the number of the effective validators.
+func (dc *Client) EffictiveValsLength(ctx context.Context, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_effictiveValsLength", toBlockNumArg(number))
+}
+
+// InvalidValsLength retrieves the number of the invalid validators.
+func (dc *Client) InvalidValsLength(ctx context.Context, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_invalidValsLength", toBlockNumArg(number))
+}
+
+// CancelQueueValidatorsLength retrieves the number of the validators queued to unstake.
+func (dc *Client) CancelQueueValidatorsLength(ctx context.Context, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_cancelQueueValidatorsLength", toBlockNumArg(number))
+}
+
+// ValidatorVotersLength retrieves the number of the voters of the given validator.
+func (dc *Client) ValidatorVotersLength(ctx context.Context, addr common.Address, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_validatorVotersLength", addr, toBlockNumArg(number))
+}
+
+// IsEffictiveValidator reports whether the given address is an effective validator.
+func (dc *Client) IsEffictiveValidator(ctx context.Context, addr common.Address, number *big.Int) (bool, error) {
+	var result bool
+	err := dc.c.CallContext(ctx, &result, "dpos_isEffictiveValidator", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// Validator proposals
+
+// GetAddressProposalSets retrieves the ids of the proposals initiated by the given address.
+func (dc *Client) GetAddressProposalSets(ctx context.Context, addr common.Address, number *big.Int) ([]string, error) {
+	var result []string
+	err := dc.c.CallContext(ctx, &result, "dpos_getAddressProposalSets", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// GetAllProposalSets retrieves the ids of all the proposals.
+func (dc *Client) GetAllProposalSets(ctx context.Context, number *big.Int) ([]string, error) {
+	var result []string
+	err := dc.c.CallContext(ctx, &result, "dpos_getAllProposalSets", toBlockNumArg(number))
+	return result, err
+}
+
+// GetAllProposals retrieves all the proposals.
+func (dc *Client) GetAllProposals(ctx context.Context, number *big.Int) ([]dpos.ProposalInfo, error) {
+	var result []dpos.ProposalInfo
+	err := dc.c.CallContext(ctx, &result, "dpos_getAllProposals", toBlockNumArg(number))
+	return result, err
+}
+
+// GetProposal retrieves the proposal of the given id.
+func (dc *Client) GetProposal(ctx context.Context, id string, number *big.Int) (*dpos.ProposalInfo, error) {
+	var result *dpos.ProposalInfo
+	err := dc.c.CallContext(ctx, &result, "dpos_getProposal", id, toBlockNumArg(number))
+	return result, err
+}
+
+// GetAddressProposals retrieves the proposals initiated by the given address.
+func (dc *Client) GetAddressProposals(ctx context.Context, addr common.Address, number *big.Int) ([]dpos.ProposalInfo, error) {
+	var result []dpos.ProposalInfo
+	err := dc.c.CallContext(ctx, &result, "dpos_getAddressProposals", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// GetProposalCount retrieves the number of all the proposals.
+func (dc *Client) GetProposalCount(ctx context.Context, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_getProposalCount", toBlockNumArg(number))
+}
+
+// GetAddressProposalCount retrieves the number of the proposals initiated by the given address.
+func (dc *Client) GetAddressProposalCount(ctx context.Context, addr common.Address, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_getAddressProposalCount", addr, toBlockNumArg(number))
+}
+
+// Votes and rewards
+
+// PendingVoteReward retrieves the pending reward of the voter on the given validator.
+func (dc *Client) PendingVoteReward(ctx context.Context, val common.Address, voter common.Address, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_pendingVoteReward", val, voter, toBlockNumArg(number))
+}
+
+// PendingVoteRedeem retrieves the redeemable votes of the voter on the given validator.
+func (dc *Client) PendingVoteRedeem(ctx context.Context, val common.Address, voter common.Address, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_pendingVoteRedeem", val, voter, toBlockNumArg(number))
+}
+
+// VoteListLength retrieves the number of the validators voted by the given address.
+func (dc *Client) VoteListLength(ctx context.Context, addr common.Address, number *big.Int) (*big.Int, error) {
+	return dc.callBig(ctx, "dpos_voteListLength", addr, toBlockNumArg(number))
+}
+
+// VotesRewardRedeemInfo retrieves the votes, reward and redeem info of the voter on
+// the given validator.
+func (dc *Client) VotesRewardRedeemInfo(ctx context.Context, val common.Address, voter common.Address, number *big.Int) (*systemcontract.VotesRewardRedeemInfo, error) {
+	var result *systemcontract.VotesRewardRedeemInfo
+	err := dc.c.CallContext(ctx, &result, "dpos_votesRewardRedeemInfo", val, voter, toBlockNumArg(number))
+	return result, err
+}
+
+// VotesRewardRedeemInfos retrieves the votes, reward and redeem info of the voter on
+// all the validators it voted.
+func (dc *Client) VotesRewardRedeemInfos(ctx context.Context, voter common.Address, number *big.Int) ([]systemcontract.VotesRewardRedeemInfo, error) {
+	var result []systemcontract.VotesRewardRedeemInfo
+	err := dc.c.CallContext(ctx, &result, "dpos_votesRewardRedeemInfos", voter, toBlockNumArg(number))
+	return result, err
+}
+
+// EpochInfo retrieves the reward info of the given epoch.
+func (dc *Client) EpochInfo(ctx context.Context, epoch *big.Int, number *big.Int) (*systemcontract.EpochInfo, error) {
+	var result *systemcontract.EpochInfo
+	err := dc.c.CallContext(ctx, &result, "dpos_epochInfo", epoch, toBlockNumArg(number))
+	return result, err
+}
+
+// KickoutInfo retrieves the validators kicked out in the given epoch.
+func (dc *Client) KickoutInfo(ctx context.Context, epoch *big.Int, number *big.Int) ([]common.Address, error) {
+	return dc.callAddresses(ctx, "dpos_kickoutInfo", epoch, toBlockNumArg(number))
+}
+
+// ValidatorRewardsInfo retrieves the rewards of the given validator.
+func (dc *Client) ValidatorRewardsInfo(ctx context.Context, addr common.Address, number *big.Int) (*dpos.SysRewardsInfo, error) {
+	var result *dpos.SysRewardsInfo
+	err := dc.c.CallContext(ctx, &result, "dpos_validatorRewardsInfo", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// ValidatorRewardInfoByEpoch retrieves the reward of the given validator in the given epoch.
+func (dc *Client) ValidatorRewardInfoByEpoch(ctx context.Context, addr common.Address, epoch *big.Int, number *big.Int) (*systemcontract.Reward, error) {
+	var result *systemcontract.Reward
+	err := dc.c.CallContext(ctx, &result, "dpos_validatorRewardInfoByEpoch", addr, epoch, toBlockNumArg(number))
+	return result, err
+}
+
+// PendingValidatorReward retrieves the pending rewards of the given validator.
+func (dc *Client) PendingValidatorReward(ctx context.Context, addr common.Address, number *big.Int) (map[string]*big.Int, error) {
+	var result map[string]*big.Int
+	err := dc.c.CallContext(ctx, &result, "dpos_pendingValidatorReward", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// PunishInfo retrieves the punishments of the given validator in the given epoch.
+func (dc *Client) PunishInfo(ctx context.Context, addr common.Address, epoch *big.Int, number *big.Int) (*systemcontract.Punish, error) {
+	var result *systemcontract.Punish
+	err := dc.c.CallContext(ctx, &result, "dpos_punishInfo", addr, epoch, toBlockNumArg(number))
+	return result, err
+}
+
+// Double sign evidences and system governance
+
+// SubmitDoubleSignEvidence submits a double sign evidence to be packed by the node.
+func (dc *Client) SubmitDoubleSignEvidence(ctx context.Context, ev *dpos.DoubleSignEvidence) (common.Hash, error) {
+	var hash common.Hash
+	err := dc.c.CallContext(ctx, &hash, "dpos_submitDoubleSignEvidence", ev)
+	return hash, err
+}
+
+// GetPendingDoubleSignEvidences retrieves the double sign evidences waiting to be packed.
+func (dc *Client) GetPendingDoubleSignEvidences(ctx context.Context) ([]*dpos.DoubleSignEvidence, error) {
+	var result []*dpos.DoubleSignEvidence
+	err := dc.c.CallContext(ctx, &result, "dpos_getPendingDoubleSignEvidences")
+	return result, err
+}
+
+// ValidatorVotes retrieves a page of the votes for the given validator from the
+// vote index of the node.
+func (dc *Client) ValidatorVotes(ctx context.Context, validator common.Address, opts *dpos.VotePageOptions) (*dpos.VotePage, error) {
+	var result *dpos.VotePage
+	err := dc.c.CallContext(ctx, &result, "dpos_validatorVotes", validator, opts)
+	return result, err
+}
+
+// VoterVotes retrieves a page of the votes of the given voter from the vote index
+// of the node.
+func (dc *Client) VoterVotes(ctx context.Context, voter common.Address, opts *dpos.VotePageOptions) (*dpos.VotePage, error) {
+	var result *dpos.VotePage
+	err := dc.c.CallContext(ctx, &result, "dpos_voterVotes", voter, opts)
+	return result, err
+}
+
+// IsDeveloper reports whether the address is in the developer allowlist at the
+// given block.
+func (dc *Client) IsDeveloper(ctx context.Context, addr common.Address, number *big.Int) (bool, error) {
+	var result bool
+	err := dc.c.CallContext(ctx, &result, "dpos_isDeveloper", addr, toBlockNumArg(number))
+	return result, err
+}
+
+// ListDevelopers retrieves a page of the allowlisted developers from the developer
+// index of the node.
+func (dc *Client) ListDevelopers(ctx context.Context, opts *dpos.DeveloperPageOptions) (*dpos.DeveloperPage, error) {
+	var result *dpos.DeveloperPage
+	err := dc.c.CallContext(ctx, &result, "dpos_listDevelopers", opts)
+	return result, err
+}
+
+// RewardHistory retrieves the rewards the given account earned as a validator and as
+// a voter in each epoch from fromEpoch to toEpoch inclusive.
+func (dc *Client) RewardHistory(ctx context.Context, addr common.Address, fromEpoch, toEpoch uint64) (*dpos.RewardHistory, error) {
+	var result *dpos.RewardHistory
+	err := dc.c.CallContext(ctx, &result, "dpos_rewardHistory", addr, hexutil.Uint64(fromEpoch), hexutil.Uint64(toEpoch))
+	return result, err
+}
+
+// SimulateGovernanceProposal executes the system governance proposal against the
+// latest state without changing it.
+func (dc *Client) SimulateGovernanceProposal(ctx context.Context, prop *dpos.GovernanceProposal) (*dpos.GovernanceSimulation, error) {
+	var result *dpos.GovernanceSimulation
+	err := dc.c.CallContext(ctx, &result, "dpos_simulateGovernanceProposal", prop)
+	return result, err
+}
+
+// PendingGovernanceProposals retrieves the passed system governance proposals to be
+// executed by the block following the given one.
+func (dc *Client) PendingGovernanceProposals(ctx context.Context, number *big.Int) ([]*dpos.GovernanceProposal, error) {
+	var result []*dpos.GovernanceProposal
+	err := dc.c.CallContext(ctx, &result, "dpos_pendingGovernanceProposals", toBlockNumArg(number))
+	return result, err
+}
+
+// PreviewNextEpoch runs the election of the next epoch against the latest state and
+// retrieves its predicted outcome.
+func (dc *Client) PreviewNextEpoch(ctx context.Context) (*dpos.EpochPreview, error) {
+	var result *dpos.EpochPreview
+	err := dc.c.CallContext(ctx, &result, "dpos_previewNextEpoch")
+	return result, err
+}
+
+// Subscriptions
+
+// SubscribeValidatorSetChanges subscribes to the validator set changes of the checkpoint blocks.
+func (dc *Client) SubscribeValidatorSetChanges(ctx context.Context, ch chan<- *dpos.ValidatorSetChangeEvent) (ethereum.Subscription, error) {
+	return dc.c.Subscribe(ctx, "dpos", ch, "validatorSetChanges")
+}
+
+// SubscribeProposals subscribes to the executions of the passed governance proposals.
+func (dc *Client) SubscribeProposals(ctx context.Context, ch chan<- *dpos.ProposalEvent) (ethereum.Subscription, error) {
+	return dc.c.Subscribe(ctx, "dpos", ch, "proposals")
+}
+
+// SubscribePunishments subscribes to the punishments of the validators.
+func (dc *Client) SubscribePunishments(ctx context.Context, ch chan<- *dpos.PunishmentEvent) (ethereum.Subscription, error) {
+	return dc.c.Subscribe(ctx, "dpos", ch, "punishments")
+}
+
+func (dc *Client) callBig(ctx context.Context, method string, args ...interface{}) (*big.Int, error) {
+	var result *big.Int
+	err := dc.c.CallContext(ctx, &result, method, args...)
+	return result, err
+}
+
+func (dc *Client) callAddresses(ctx context.Context, method string, args ...interface{}) ([]common.Address, error) {
+	var result []common.Address
+	err := dc.c.CallContext(ctx, &result, method, args...)
+	return result, err
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	switch rpc.BlockNumber(number.Int64()) {
+	case rpc.LatestBlockNumber:
+		return "latest"
+	case rpc.PendingBlockNumber:
+		return "pending"
+	case rpc.FinalizedBlockNumber:
+		return "finalized"
+	}
+	return hexutil.EncodeBig(number)
+}
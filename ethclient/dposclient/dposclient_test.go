@@ -0,0 +1,73 @@
+package dposclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/rpc"
+)
+
+// testService serves a few dpos methods, echoing the requested block number.
+type testService struct{}
+
+func (s *testService) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+	return []common.Address{common.BigToAddress(big.NewInt(number.Int64()))}, nil
+}
+
+func (s *testService) GetValidator(addr common.Address, number *rpc.BlockNumber) (*systemcontract.Validator, error) {
+	return &systemcontract.Validator{Status: 1, Deposit: big.NewInt(100), Rate: 70, Name: "dxc", Votes: new(big.Int)}, nil
+}
+
+func (s *testService) GetTotalDeposit(number *rpc.BlockNumber) (*big.Int, error) {
+	return big.NewInt(12345), nil
+}
+
+func newTestClient(t *testing.T) *Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("dpos", new(testService)); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	t.Cleanup(server.Stop)
+	return New(rpc.DialInProc(server))
+}
+
+func TestReadMethods(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ctx := context.Background()
+	tests := []struct {
+		number *big.Int
+		want   int64
+	}{
+		{nil, int64(rpc.LatestBlockNumber)},
+		{big.NewInt(int64(rpc.FinalizedBlockNumber)), int64(rpc.FinalizedBlockNumber)},
+		{big.NewInt(7), 7},
+	}
+	for _, tt := range tests {
+		validators, err := client.GetValidators(ctx, tt.number)
+		if err != nil {
+			t.Fatalf("number %v: failed to get validators: %v", tt.number, err)
+		}
+		if len(validators) != 1 || validators[0] != common.BigToAddress(big.NewInt(tt.want)) {
+			t.Errorf("number %v: block number mismatch: have %v, want %d", tt.number, validators, tt.want)
+		}
+	}
+	validator, err := client.GetValidator(ctx, common.Address{0x01}, nil)
+	if err != nil {
+		t.Fatalf("failed to get validator: %v", err)
+	}
+	if validator.Rate != 70 || validator.Name != "dxc" || validator.Deposit.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("validator mismatch: %+v", validator)
+	}
+	deposit, err := client.GetTotalDeposit(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to get total deposit: %v", err)
+	}
+	if deposit.Cmp(big.NewInt(12345)) != 0 {
+		t.Errorf("total deposit mismatch: have %v", deposit)
+	}
+}
@@ -0,0 +1,117 @@
+package dposclient
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/accounts/abi/bind"
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/types"
+)
+
+// transact builds, signs and sends the transaction calling the method of the named
+// system contract. The sender, signer, nonce, gas and value are taken from opts, the
+// unset ones are filled from the node, same as the generated contract bindings.
+func (dc *Client) transact(opts *bind.TransactOpts, contract string, method string, args ...interface{}) (*types.Transaction, error) {
+	addr, ok := systemcontract.ContractAddress(contract)
+	if !ok {
+		return nil, errors.New("unknown system contract " + contract)
+	}
+	bound := bind.NewBoundContract(addr, systemcontract.GetInteractiveABI()[contract], dc.eth, dc.eth, dc.eth)
+	return bound.Transact(opts, method, args...)
+}
+
+// Validator proposals
+
+// InitProposal initiates a validator proposal, the deposit is the value of opts.
+func (dc *Client) InitProposal(opts *bind.TransactOpts, pType uint8, rate uint8, name string, details string) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.ValidatorProposalsContractName, "initProposal", pType, rate, name, details)
+}
+
+// UpdateProposal updates a validator proposal initiated by the sender.
+func (dc *Client) UpdateProposal(opts *bind.TransactOpts, id string, rate uint8, deposit *big.Int, name string, details string) (*types.Transaction, error) {
+	pid, err := systemcontract.ParseProposalID(id)
+	if err != nil {
+		return nil, err
+	}
+	return dc.transact(opts, systemcontract.ValidatorProposalsContractName, "updateProposal", pid, rate, deposit, name, details)
+}
+
+// CancelProposal cancels a validator proposal initiated by the sender.
+func (dc *Client) CancelProposal(opts *bind.TransactOpts, id string) (*types.Transaction, error) {
+	pid, err := systemcontract.ParseProposalID(id)
+	if err != nil {
+		return nil, err
+	}
+	return dc.transact(opts, systemcontract.ValidatorProposalsContractName, "cancelProposal", pid)
+}
+
+// Guarantee guarantees a validator proposal, the guarantee deposit is the value of opts.
+func (dc *Client) Guarantee(opts *bind.TransactOpts, id string) (*types.Transaction, error) {
+	pid, err := systemcontract.ParseProposalID(id)
+	if err != nil {
+		return nil, err
+	}
+	return dc.transact(opts, systemcontract.ValidatorProposalsContractName, "guarantee", pid)
+}
+
+// Validators
+
+// UpdateValidatorDeposit changes the deposit of the sending validator, an increase
+// is paid by the value of opts.
+func (dc *Client) UpdateValidatorDeposit(opts *bind.TransactOpts, deposit *big.Int) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.ValidatorsContractName, "updateValidatorDeposit", deposit)
+}
+
+// UpdateValidatorRate changes the reward rate of the sending validator.
+func (dc *Client) UpdateValidatorRate(opts *bind.TransactOpts, rate uint8) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.ValidatorsContractName, "updateValidatorRate", rate)
+}
+
+// UpdateValidatorNameDetails changes the name and details of the sending validator.
+func (dc *Client) UpdateValidatorNameDetails(opts *bind.TransactOpts, name string, details string) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.ValidatorsContractName, "updateValidatorNameDetails", name, details)
+}
+
+// Unstake queues the sending validator to leave the validator set.
+func (dc *Client) Unstake(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.ValidatorsContractName, "unstake")
+}
+
+// Restore restores the sending validator kicked out of the validator set.
+func (dc *Client) Restore(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.ValidatorsContractName, "restore")
+}
+
+// ValidatorRedeem redeems the unlocked deposit of the sending validator.
+func (dc *Client) ValidatorRedeem(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.ValidatorsContractName, "redeem")
+}
+
+// EarnValidatorReward withdraws the rewards of the sending validator.
+func (dc *Client) EarnValidatorReward(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.SystemRewardsContractName, "earnValidatorReward")
+}
+
+// Votes
+
+// Vote votes the validator with the value of opts.
+func (dc *Client) Vote(opts *bind.TransactOpts, val common.Address) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.NodeVotesContractName, "vote", val)
+}
+
+// CancelVote cancels the given amount of the votes of the sender on the validator.
+func (dc *Client) CancelVote(opts *bind.TransactOpts, val common.Address, amount *big.Int) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.NodeVotesContractName, "cancelVote", val, amount)
+}
+
+// EarnVoteReward withdraws the rewards of the votes of the sender on the validator.
+func (dc *Client) EarnVoteReward(opts *bind.TransactOpts, val common.Address) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.NodeVotesContractName, "earn", val)
+}
+
+// VoterRedeem redeems the unlocked votes of the sender on the validator.
+func (dc *Client) VoterRedeem(opts *bind.TransactOpts, val common.Address) (*types.Transaction, error) {
+	return dc.transact(opts, systemcontract.NodeVotesContractName, "redeem", val)
+}
@@ -1011,6 +1011,18 @@ func DoCall(ctx context.Context, b Backend, args TransactionArgs, blockNrOrHash
 	if err != nil {
 		return nil, err
 	}
+	// A transaction from or to a blacklisted address is rejected before running it,
+	// explain the denial the same way instead of executing it.
+	if validator := evm.Context.ExtraValidator; validator != nil {
+		if err := validator.CheckAddress(msg.From(), common.CheckFrom); err != nil {
+			return nil, err
+		}
+		if to := msg.To(); to != nil {
+			if err := validator.CheckAddress(*to, common.CheckTo); err != nil {
+				return nil, err
+			}
+		}
+	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
 	go func() {
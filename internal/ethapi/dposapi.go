@@ -117,12 +117,10 @@ func (pd *PublicDposTxAPI) UpdateProposal(id string, rate uint8, deposit *hexuti
 	method := "updateProposal"
 	abiMap := systemcontract.GetInteractiveABI()
 
-	idBytes, err := hexutil.Decode(id)
+	idByte4, err := systemcontract.ParseProposalID(id)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	var idByte4 [4]byte
-	copy(idByte4[:], idBytes[:4])
 
 	data, err := abiMap[systemcontract.ValidatorProposalsContractName].Pack(method, idByte4, rate, (*big.Int)(deposit), name, details)
 	if err != nil {
@@ -155,12 +153,10 @@ func (pd *PublicDposTxAPI) CancelProposal(id string, args *TransactionArgs) (com
 	method := "cancelProposal"
 	abiMap := systemcontract.GetInteractiveABI()
 
-	idBytes, err := hexutil.Decode(id)
+	idByte4, err := systemcontract.ParseProposalID(id)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	var idByte4 [4]byte
-	copy(idByte4[:], idBytes[:4])
 
 	data, err := abiMap[systemcontract.ValidatorProposalsContractName].Pack(method, idByte4)
 	if err != nil {
@@ -193,12 +189,10 @@ func (pd *PublicDposTxAPI) Guarantee(id string, args *TransactionArgs) (common.H
 	method := "guarantee"
 	abiMap := systemcontract.GetInteractiveABI()
 
-	idBytes, err := hexutil.Decode(id)
+	idByte4, err := systemcontract.ParseProposalID(id)
 	if err != nil {
 		return common.Hash{}, err
 	}
-	var idByte4 [4]byte
-	copy(idByte4[:], idBytes[:4])
 
 	data, err := abiMap[systemcontract.ValidatorProposalsContractName].Pack(method, idByte4)
 	if err != nil {
@@ -554,3 +548,35 @@ func (pd *PublicDposTxAPI) VoterRedeem(val common.Address, args *TransactionArgs
 
 	return txHash, nil
 }
+
+// SetSigningKey setSigningKey function of SigningKeys contract, the key takes over
+// sealing the blocks of the validator at the next checkpoint.
+func (pd *PublicDposTxAPI) SetSigningKey(key common.Address, args *TransactionArgs) (common.Hash, error) {
+	ctx := context.Background()
+	args.To = &systemcontract.SigningKeysContractAddr
+
+	if err := pd.prepareAccount(args); err != nil {
+		return common.Hash{}, err
+	}
+
+	pd.nonceLock.LockAddr(*args.From)
+	defer pd.nonceLock.UnlockAddr(*args.From)
+
+	log.Info("set signing key", "validator", args.From, "key", key)
+
+	method := "setSigningKey"
+	abiMap := systemcontract.GetInteractiveABI()
+
+	data, err := abiMap[systemcontract.SigningKeysContractName].Pack(method, key)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	args.Data = (*hexutil.Bytes)(&data)
+
+	txHash, err := pd.sendDposTx(ctx, args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	return txHash, nil
+}
@@ -0,0 +1,229 @@
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/log"
+)
+
+// stakingMethods lists the methods of the system contracts users call to stake,
+// vote and manage validator proposals, the only ones sendRawStakingTx forwards.
+var stakingMethods = map[string][]string{
+	systemcontract.ValidatorProposalsContractName: {"initProposal", "updateProposal", "cancelProposal", "guarantee"},
+	systemcontract.ValidatorsContractName:         {"updateValidatorDeposit", "updateValidatorRate", "updateValidatorNameDetails", "unstake", "restore", "redeem"},
+	systemcontract.SystemRewardsContractName:      {"earnValidatorReward"},
+	systemcontract.NodeVotesContractName:          {"earn", "vote", "cancelVote", "redeem"},
+}
+
+// StakingCall is the decoded call data of a staking transaction.
+type StakingCall struct {
+	Contract string                 `json:"contract"`
+	Method   string                 `json:"method"`
+	Args     map[string]interface{} `json:"args"`
+}
+
+// StakingTxResult is an unsigned staking transaction, to be signed offline and sent
+// by dpos_sendRawStakingTx.
+type StakingTxResult struct {
+	Raw  hexutil.Bytes      `json:"raw"`
+	Tx   *types.Transaction `json:"tx"`
+	Call *StakingCall       `json:"call"`
+}
+
+// decodeStakingCall checks that the call data is a well-formed call of a staking
+// method of the system contract at the given address and decodes it.
+func decodeStakingCall(to *common.Address, data []byte) (*StakingCall, error) {
+	if to == nil {
+		return nil, errors.New("contract creation is not a staking transaction")
+	}
+	for contract, methods := range stakingMethods {
+		if addr, _ := systemcontract.ContractAddress(contract); addr != *to {
+			continue
+		}
+		if len(data) < 4 {
+			return nil, errors.New("missing method selector")
+		}
+		contractABI := systemcontract.GetInteractiveABI()[contract]
+		method, err := contractABI.MethodById(data[:4])
+		if err != nil {
+			return nil, err
+		}
+		allowed := false
+		for _, name := range methods {
+			if name == method.Name {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("method %s of %s is not a staking method", method.Name, contract)
+		}
+		values, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid arguments of %s: %v", method.Name, err)
+		}
+		// Reject any trailing or non-canonical encoding
+		packed, err := method.Inputs.Pack(values...)
+		if err != nil || !bytes.Equal(packed, data[4:]) {
+			return nil, fmt.Errorf("non-canonical arguments of %s", method.Name)
+		}
+		args := make(map[string]interface{}, len(values))
+		for i, input := range method.Inputs {
+			switch value := values[i].(type) {
+			case [4]byte:
+				// proposal ids are hex encoded everywhere else
+				args[input.Name] = hexutil.Bytes(value[:])
+			default:
+				args[input.Name] = value
+			}
+		}
+		return &StakingCall{Contract: contract, Method: method.Name, Args: args}, nil
+	}
+	return nil, fmt.Errorf("%s is not a staking contract", to.Hex())
+}
+
+// buildDposTx assembles the unsigned transaction calling the method of the system
+// contract. Unlike sendDposTx, the sender isn't looked up in the local wallets, it
+// must be given explicitly.
+func (pd *PublicDposTxAPI) buildDposTx(ctx context.Context, args *TransactionArgs, contract string, method string, params ...interface{}) (*StakingTxResult, error) {
+	if args == nil || args.From == nil {
+		return nil, errors.New("missing from address")
+	}
+	addr, _ := systemcontract.ContractAddress(contract)
+	data, err := systemcontract.GetInteractiveABI()[contract].Pack(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	args.To = &addr
+	args.Data, args.Input = (*hexutil.Bytes)(&data), nil
+
+	// Set some sanity defaults and terminate on failure
+	if err := args.setDefaults(ctx, pd.b); err != nil {
+		return nil, err
+	}
+	tx := args.toTransaction()
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	call, err := decodeStakingCall(tx.To(), tx.Data())
+	if err != nil {
+		return nil, err
+	}
+	return &StakingTxResult{Raw: raw, Tx: tx, Call: call}, nil
+}
+
+// BuildInitProposal builds the unsigned transaction of InitProposal
+func (pd *PublicDposTxAPI) BuildInitProposal(ctx context.Context, pType uint8, rate uint8, name string, details string, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorProposalsContractName, "initProposal", pType, rate, name, details)
+}
+
+// BuildUpdateProposal builds the unsigned transaction of UpdateProposal
+func (pd *PublicDposTxAPI) BuildUpdateProposal(ctx context.Context, id string, rate uint8, deposit *hexutil.Big, name string, details string, args *TransactionArgs) (*StakingTxResult, error) {
+	idByte4, err := systemcontract.ParseProposalID(id)
+	if err != nil {
+		return nil, err
+	}
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorProposalsContractName, "updateProposal", idByte4, rate, (*big.Int)(deposit), name, details)
+}
+
+// BuildCancelProposal builds the unsigned transaction of CancelProposal
+func (pd *PublicDposTxAPI) BuildCancelProposal(ctx context.Context, id string, args *TransactionArgs) (*StakingTxResult, error) {
+	idByte4, err := systemcontract.ParseProposalID(id)
+	if err != nil {
+		return nil, err
+	}
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorProposalsContractName, "cancelProposal", idByte4)
+}
+
+// BuildGuarantee builds the unsigned transaction of Guarantee
+func (pd *PublicDposTxAPI) BuildGuarantee(ctx context.Context, id string, args *TransactionArgs) (*StakingTxResult, error) {
+	idByte4, err := systemcontract.ParseProposalID(id)
+	if err != nil {
+		return nil, err
+	}
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorProposalsContractName, "guarantee", idByte4)
+}
+
+// BuildUpdateValidatorDeposit builds the unsigned transaction of UpdateValidatorDeposit
+func (pd *PublicDposTxAPI) BuildUpdateValidatorDeposit(ctx context.Context, deposit *hexutil.Big, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorsContractName, "updateValidatorDeposit", (*big.Int)(deposit))
+}
+
+// BuildUpdateValidatorRate builds the unsigned transaction of UpdateValidatorRate
+func (pd *PublicDposTxAPI) BuildUpdateValidatorRate(ctx context.Context, rate uint8, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorsContractName, "updateValidatorRate", rate)
+}
+
+// BuildUpdateValidatorNameDetails builds the unsigned transaction of UpdateValidatorNameDetails
+func (pd *PublicDposTxAPI) BuildUpdateValidatorNameDetails(ctx context.Context, name string, details string, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorsContractName, "updateValidatorNameDetails", name, details)
+}
+
+// BuildUnstake builds the unsigned transaction of Unstake
+func (pd *PublicDposTxAPI) BuildUnstake(ctx context.Context, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorsContractName, "unstake")
+}
+
+// BuildRestore builds the unsigned transaction of Restore
+func (pd *PublicDposTxAPI) BuildRestore(ctx context.Context, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorsContractName, "restore")
+}
+
+// BuildValidatorRedeem builds the unsigned transaction of ValidatorRedeem
+func (pd *PublicDposTxAPI) BuildValidatorRedeem(ctx context.Context, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.ValidatorsContractName, "redeem")
+}
+
+// BuildEarnValidatorReward builds the unsigned transaction of EarnValidatorReward
+func (pd *PublicDposTxAPI) BuildEarnValidatorReward(ctx context.Context, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.SystemRewardsContractName, "earnValidatorReward")
+}
+
+// BuildEarnVoteReward builds the unsigned transaction of EarnVoteReward
+func (pd *PublicDposTxAPI) BuildEarnVoteReward(ctx context.Context, val common.Address, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.NodeVotesContractName, "earn", val)
+}
+
+// BuildVote builds the unsigned transaction of Vote
+func (pd *PublicDposTxAPI) BuildVote(ctx context.Context, val common.Address, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.NodeVotesContractName, "vote", val)
+}
+
+// BuildCancelVote builds the unsigned transaction of CancelVote
+func (pd *PublicDposTxAPI) BuildCancelVote(ctx context.Context, val common.Address, amount *hexutil.Big, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.NodeVotesContractName, "cancelVote", val, (*big.Int)(amount))
+}
+
+// BuildVoterRedeem builds the unsigned transaction of VoterRedeem
+func (pd *PublicDposTxAPI) BuildVoterRedeem(ctx context.Context, val common.Address, args *TransactionArgs) (*StakingTxResult, error) {
+	return pd.buildDposTx(ctx, args, systemcontract.NodeVotesContractName, "redeem", val)
+}
+
+// SendRawStakingTx checks that the signed transaction is a well-formed call of a
+// staking method of the system contracts and adds it to the transaction pool.
+func (pd *PublicDposTxAPI) SendRawStakingTx(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	call, err := decodeStakingCall(tx.To(), tx.Data())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	signer := types.MakeSigner(pd.b.ChainConfig(), pd.b.CurrentBlock().Number())
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitting staking transaction", "from", from, "contract", call.Contract, "method", call.Method)
+	return SubmitTransaction(ctx, pd.b, tx)
+}
@@ -0,0 +1,293 @@
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
+	"github.com/DxChainNetwork/dxc/consensus/dpos/systemcontract"
+	"github.com/DxChainNetwork/dxc/core/types"
+	"github.com/DxChainNetwork/dxc/crypto"
+	"github.com/DxChainNetwork/dxc/params"
+)
+
+// stakingTestBackend is the part of the backend used to build and send the staking
+// transactions, recording the sent ones.
+type stakingTestBackend struct {
+	Backend
+	sent []*types.Transaction
+}
+
+func (b *stakingTestBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+func (b *stakingTestBackend) CurrentHeader() *types.Header {
+	return &types.Header{Number: big.NewInt(1)}
+}
+func (b *stakingTestBackend) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(b.CurrentHeader())
+}
+func (b *stakingTestBackend) RPCTxFeeCap() float64     { return 0 }
+func (b *stakingTestBackend) UnprotectedAllowed() bool { return false }
+func (b *stakingTestBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+// newStakingTxArgs returns the arguments of a staking transaction whose defaults
+// don't need to be looked up.
+func newStakingTxArgs(from common.Address) *TransactionArgs {
+	var (
+		gas   = hexutil.Uint64(100000)
+		nonce = hexutil.Uint64(3)
+	)
+	return &TransactionArgs{From: &from, Gas: &gas, GasPrice: (*hexutil.Big)(big.NewInt(1)), Nonce: &nonce}
+}
+
+func TestBuildStakingTx(t *testing.T) {
+	var (
+		api     = NewPublicDposTxAPI(&stakingTestBackend{}, nil)
+		ctx     = context.Background()
+		from    = common.HexToAddress("0x1001")
+		val     = common.HexToAddress("0x2001")
+		id      = [4]byte{0x01, 0x02, 0x03, 0x0a}
+		deposit = big.NewInt(100)
+	)
+	tests := []struct {
+		build    func(args *TransactionArgs) (*StakingTxResult, error)
+		contract string
+		method   string
+		params   []interface{}
+	}{
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildInitProposal(ctx, 1, 70, "name", "details", args)
+			},
+			systemcontract.ValidatorProposalsContractName, "initProposal", []interface{}{uint8(1), uint8(70), "name", "details"},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildUpdateProposal(ctx, "0x0102030a", 80, (*hexutil.Big)(deposit), "name", "details", args)
+			},
+			systemcontract.ValidatorProposalsContractName, "updateProposal", []interface{}{id, uint8(80), deposit, "name", "details"},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildCancelProposal(ctx, "0x0102030a", args)
+			},
+			systemcontract.ValidatorProposalsContractName, "cancelProposal", []interface{}{id},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildGuarantee(ctx, "0x0102030a", args)
+			},
+			systemcontract.ValidatorProposalsContractName, "guarantee", []interface{}{id},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildUpdateValidatorDeposit(ctx, (*hexutil.Big)(deposit), args)
+			},
+			systemcontract.ValidatorsContractName, "updateValidatorDeposit", []interface{}{deposit},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildUpdateValidatorRate(ctx, 90, args)
+			},
+			systemcontract.ValidatorsContractName, "updateValidatorRate", []interface{}{uint8(90)},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildUpdateValidatorNameDetails(ctx, "name", "details", args)
+			},
+			systemcontract.ValidatorsContractName, "updateValidatorNameDetails", []interface{}{"name", "details"},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) { return api.BuildUnstake(ctx, args) },
+			systemcontract.ValidatorsContractName, "unstake", nil,
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) { return api.BuildRestore(ctx, args) },
+			systemcontract.ValidatorsContractName, "restore", nil,
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) { return api.BuildValidatorRedeem(ctx, args) },
+			systemcontract.ValidatorsContractName, "redeem", nil,
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) { return api.BuildEarnValidatorReward(ctx, args) },
+			systemcontract.SystemRewardsContractName, "earnValidatorReward", nil,
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) { return api.BuildEarnVoteReward(ctx, val, args) },
+			systemcontract.NodeVotesContractName, "earn", []interface{}{val},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) { return api.BuildVote(ctx, val, args) },
+			systemcontract.NodeVotesContractName, "vote", []interface{}{val},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) {
+				return api.BuildCancelVote(ctx, val, (*hexutil.Big)(deposit), args)
+			},
+			systemcontract.NodeVotesContractName, "cancelVote", []interface{}{val, deposit},
+		},
+		{
+			func(args *TransactionArgs) (*StakingTxResult, error) { return api.BuildVoterRedeem(ctx, val, args) },
+			systemcontract.NodeVotesContractName, "redeem", []interface{}{val},
+		},
+	}
+	for _, tt := range tests {
+		result, err := tt.build(newStakingTxArgs(from))
+		if err != nil {
+			t.Errorf("%s: failed to build: %v", tt.method, err)
+			continue
+		}
+		addr, _ := systemcontract.ContractAddress(tt.contract)
+		want, err := systemcontract.GetInteractiveABI()[tt.contract].Pack(tt.method, tt.params...)
+		if err != nil {
+			t.Fatalf("%s: failed to pack: %v", tt.method, err)
+		}
+		if to := result.Tx.To(); to == nil || *to != addr {
+			t.Errorf("%s: recipient mismatch: have %v, want %x", tt.method, to, addr)
+		}
+		if !bytes.Equal(result.Tx.Data(), want) {
+			t.Errorf("%s: data mismatch: have %x, want %x", tt.method, result.Tx.Data(), want)
+		}
+		if result.Tx.Nonce() != 3 || result.Tx.Gas() != 100000 {
+			t.Errorf("%s: defaults overridden: nonce %d, gas %d", tt.method, result.Tx.Nonce(), result.Tx.Gas())
+		}
+		if result.Call.Contract != tt.contract || result.Call.Method != tt.method || len(result.Call.Args) != len(tt.params) {
+			t.Errorf("%s: call mismatch: have %+v", tt.method, result.Call)
+		}
+		raw, _ := result.Tx.MarshalBinary()
+		if !bytes.Equal(result.Raw, raw) {
+			t.Errorf("%s: raw transaction mismatch", tt.method)
+		}
+	}
+	// The proposal ids are hex encoded
+	if result, err := api.BuildGuarantee(ctx, "0x0102030a", newStakingTxArgs(from)); err != nil || !bytes.Equal(result.Call.Args["id"].(hexutil.Bytes), id[:]) {
+		t.Errorf("proposal id argument mismatch: %v", err)
+	}
+	// The sender must be given
+	if _, err := api.BuildUnstake(ctx, &TransactionArgs{}); err == nil {
+		t.Errorf("transaction built without sender")
+	}
+}
+
+func TestParseProposalID(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    [4]byte
+		wantErr error
+	}{
+		{id: "0x0102030a", want: [4]byte{0x01, 0x02, 0x03, 0x0a}},
+		{id: "0x01", wantErr: systemcontract.ErrInvalidProposalID},
+		{id: "0x0102030405", wantErr: systemcontract.ErrInvalidProposalID},
+		{id: "0x", wantErr: systemcontract.ErrInvalidProposalID},
+		{id: "01020304", wantErr: hexutil.ErrMissingPrefix},
+		{id: "0x010203zz", wantErr: hexutil.ErrSyntax},
+	}
+	for _, tt := range tests {
+		have, err := systemcontract.ParseProposalID(tt.id)
+		if err != tt.wantErr || have != tt.want {
+			t.Errorf("%s: have %x/%v, want %x/%v", tt.id, have, err, tt.want, tt.wantErr)
+		}
+	}
+	// The builders reject the invalid ids before packing them
+	api := NewPublicDposTxAPI(&stakingTestBackend{}, nil)
+	if _, err := api.BuildCancelProposal(context.Background(), "0x01", newStakingTxArgs(common.HexToAddress("0x1001"))); err != systemcontract.ErrInvalidProposalID {
+		t.Errorf("error mismatch: have %v, want %v", err, systemcontract.ErrInvalidProposalID)
+	}
+}
+
+func TestDecodeStakingCall(t *testing.T) {
+	var (
+		validatorsABI = systemcontract.GetInteractiveABI()[systemcontract.ValidatorsContractName]
+		validators    = systemcontract.ValidatorsContractAddr
+		voteData, _   = systemcontract.GetInteractiveABI()[systemcontract.NodeVotesContractName].Pack("vote", common.HexToAddress("0x2001"))
+		rateData, _   = validatorsABI.Pack("updateValidatorRate", uint8(90))
+		nodeVotes, _  = systemcontract.ContractAddress(systemcontract.NodeVotesContractName)
+		stranger      = common.HexToAddress("0x3001")
+	)
+	// Any method of the Validators contract which isn't a staking one
+	var viewData []byte
+	for name, method := range validatorsABI.Methods {
+		staking := false
+		for _, allowed := range stakingMethods[systemcontract.ValidatorsContractName] {
+			staking = staking || allowed == name
+		}
+		if !staking && len(method.Inputs) == 0 {
+			viewData = method.ID
+			break
+		}
+	}
+	tests := []struct {
+		to     *common.Address
+		data   []byte
+		method string // Empty if rejected
+	}{
+		{&nodeVotes, voteData, "vote"},
+		{&validators, rateData, "updateValidatorRate"},
+		{nil, rateData, ""},                                            // Contract creation
+		{&stranger, rateData, ""},                                      // Not a system contract
+		{&validators, rateData[:3], ""},                                // Missing selector
+		{&validators, []byte{0xde, 0xad, 0xbe, 0xef}, ""},              // Unknown selector
+		{&validators, viewData, ""},                                    // Not a staking method
+		{&validators, rateData[:20], ""},                               // Truncated arguments
+		{&validators, append(append([]byte{}, rateData...), 0x00), ""}, // Trailing bytes
+		{&validators, append(append([]byte{}, rateData[:4]...), bytes.Repeat([]byte{0xff}, 32)...), ""}, // Out of range uint8
+	}
+	for i, tt := range tests {
+		call, err := decodeStakingCall(tt.to, tt.data)
+		if tt.method == "" {
+			if err == nil {
+				t.Errorf("test %d: call accepted: %+v", i, call)
+			}
+			continue
+		}
+		if err != nil || call.Method != tt.method {
+			t.Errorf("test %d: call mismatch: have %+v/%v, want %s", i, call, err, tt.method)
+		}
+	}
+}
+
+func TestSendRawStakingTx(t *testing.T) {
+	var (
+		backend   = &stakingTestBackend{}
+		api       = NewPublicDposTxAPI(backend, nil)
+		key, _    = crypto.GenerateKey()
+		signer    = types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+		validator = systemcontract.ValidatorsContractAddr
+		stranger  = common.HexToAddress("0x3001")
+	)
+	rateData, _ := systemcontract.GetInteractiveABI()[systemcontract.ValidatorsContractName].Pack("updateValidatorRate", uint8(90))
+	send := func(to common.Address, data []byte) error {
+		tx, err := types.SignTx(types.NewTransaction(0, to, new(big.Int), 100000, big.NewInt(1), data), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		raw, _ := tx.MarshalBinary()
+		hash, err := api.SendRawStakingTx(context.Background(), raw)
+		if err == nil && hash != tx.Hash() {
+			t.Errorf("hash mismatch: have %x, want %x", hash, tx.Hash())
+		}
+		return err
+	}
+	if err := send(validator, rateData); err != nil {
+		t.Fatalf("failed to send staking transaction: %v", err)
+	}
+	// Plain transfers and calls of other contracts are not staking transactions
+	if err := send(stranger, nil); err == nil {
+		t.Errorf("transfer accepted")
+	}
+	if err := send(stranger, rateData); err == nil {
+		t.Errorf("call of a non system contract accepted")
+	}
+	if len(backend.sent) != 1 {
+		t.Errorf("sent transactions mismatch: have %d, want 1", len(backend.sent))
+	}
+	if _, err := api.SendRawStakingTx(context.Background(), []byte{0x01}); err == nil {
+		t.Errorf("malformed transaction accepted")
+	}
+}
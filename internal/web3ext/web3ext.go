@@ -111,6 +111,30 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getConsensusParams',
+			call: 'dpos_getConsensusParams',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorSetProof',
+			call: 'dpos_getValidatorSetProof',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEpochLiveness',
+			call: 'dpos_getEpochLiveness',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorLiveness',
+			call: 'dpos_getValidatorLiveness',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getValidatorsAtHash',
 			call: 'dpos_getValidatorsAtHash',
@@ -128,6 +152,24 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'signingKey',
+			call: 'dpos_getSigningKey',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBlacklist',
+			call: 'dpos_getBlacklist',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEventCheckRules',
+			call: 'dpos_getEventCheckRules',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'totalDeposit',
 			call: 'dpos_getTotalDeposit',
@@ -308,6 +350,62 @@ web3._extend({
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter,web3._extend.formatters.inputBlockNumberFormatter],
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'submitDoubleSignEvidence',
+			call: 'dpos_submitDoubleSignEvidence',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'pendingDoubleSignEvidences',
+			call: 'dpos_getPendingDoubleSignEvidences',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'validatorVotes',
+			call: 'dpos_validatorVotes',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'voterVotes',
+			call: 'dpos_voterVotes',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'isDeveloper',
+			call: 'dpos_isDeveloper',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'listDevelopers',
+			call: 'dpos_listDevelopers',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'rewardHistory',
+			call: 'dpos_rewardHistory',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'simulateGovernanceProposal',
+			call: 'dpos_simulateGovernanceProposal',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'pendingGovernanceProposals',
+			call: 'dpos_pendingGovernanceProposals',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'previewNextEpoch',
+			call: 'dpos_previewNextEpoch',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'initProposal',
 			call: 'dpos_initProposal',
@@ -443,8 +541,356 @@ web3._extend({
 			}],
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'setSigningKey',
+			call: 'dpos_setSigningKey',
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'buildInitProposal',
+			call: 'dpos_buildInitProposal',
+			inputFormatter: [null,null,null,null,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 5
+		}),
+		new web3._extend.Method({
+			name: 'buildUpdateProposal',
+			call: 'dpos_buildUpdateProposal',
+			inputFormatter: [null,null,web3.fromDecimal, null, null, function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 6
+		}),
+		new web3._extend.Method({
+			name: 'buildCancelProposal',
+			call: 'dpos_buildCancelProposal',
+			inputFormatter: [null,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'buildGuarantee',
+			call: 'dpos_buildGuarantee',
+			inputFormatter: [null,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'buildUpdateValidatorDeposit',
+			call: 'dpos_buildUpdateValidatorDeposit',
+			inputFormatter: [web3.fromDecimal,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'buildUpdateValidatorRate',
+			call: 'dpos_buildUpdateValidatorRate',
+			inputFormatter: [null,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'buildUpdateValidatorNameDetails',
+			call: 'dpos_buildUpdateValidatorNameDetails',
+			inputFormatter: [null,null,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'buildUnstake',
+			call: 'dpos_buildUnstake',
+			inputFormatter: [function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'buildRestore',
+			call: 'dpos_buildRestore',
+			inputFormatter: [function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'buildValidatorRedeem',
+			call: 'dpos_buildValidatorRedeem',
+			inputFormatter: [function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'buildEarnValidatorReward',
+			call: 'dpos_buildEarnValidatorReward',
+			inputFormatter: [function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'buildEarnVoteReward',
+			call: 'dpos_buildEarnVoteReward',
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'buildVote',
+			call: 'dpos_buildVote',
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'buildCancelVote',
+			call: 'dpos_buildCancelVote',
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter,web3.fromDecimal,function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'buildVoterRedeem',
+			call: 'dpos_buildVoterRedeem',
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, function(options) {
+				options = options == undefined? {} : options
+				return web3._extend.formatters.inputCallFormatter(options)
+			}],
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'sendRawStakingTx',
+			call: 'dpos_sendRawStakingTx',
+			params: 1
+		}),
 	],
 });
+
+(function(dpos) {
+	var params;
+	var validatorStatus = ['canceled', 'canceling', 'cancelQueue', 'kickout', 'effective'];
+	var proposalStatus = ['pending', 'passed', 'canceled'];
+
+	// stakingParams returns the staking constants of the Base contract, read once
+	// per console session unless a refresh is requested.
+	dpos.stakingParams = function(refresh) {
+		if (params === undefined || refresh) {
+			var base = dpos.base('latest');
+			params = {
+				blockSeconds: Number(base.BLOCK_SECONDS),
+				epochBlocks: Number(base.EPOCH_BLOCKS),
+				minDeposit: web3.toBigNumber(base.MIN_DEPOSIT),
+				minRate: Number(base.MIN_RATE),
+				maxRate: Number(base.MAX_RATE),
+				maxValidators: Number(base.MAX_VALIDATORS_COUNT),
+				maxNameLength: Number(base.MAX_VALIDATOR_NAME_LENGTH),
+				maxDetailsLength: Number(base.MAX_VALIDATOR_DETAIL_LENGTH),
+				rateSetLockEpochs: Number(base.RATE_SET_LOCK_EPOCHS),
+				unstakeLockEpochs: Number(base.VALIDATOR_UNSTAKE_LOCK_EPOCHS),
+				proposalDurationEpochs: Number(base.PROPOSAL_DURATION_EPOCHS),
+				rewardLockEpochs: Number(base.VALIDATOR_REWARD_LOCK_EPOCHS),
+				voteCancelEpochs: Number(base.VOTE_CANCEL_EPOCHS)
+			};
+		}
+		return params;
+	};
+
+	// toWei converts an amount of DX into wei, rejecting anything but positive amounts.
+	function toWei(amount, what) {
+		var wei;
+		try {
+			wei = web3.toBigNumber(web3.toWei(amount, 'ether'));
+		} catch (err) {
+			throw new Error('invalid ' + what + ': ' + amount);
+		}
+		if (!wei.isInt() || wei.lte(0)) {
+			throw new Error('invalid ' + what + ': ' + amount);
+		}
+		return wei;
+	}
+
+	// dx formats an amount of wei in DX.
+	function dx(wei) {
+		return web3.fromWei(web3.toBigNumber(wei || 0), 'ether').toString(10) + ' DX';
+	}
+
+	function sender(from) {
+		from = from || web3.eth.defaultAccount || web3.eth.coinbase;
+		if (!web3.isAddress(from)) {
+			throw new Error('invalid sender: ' + from);
+		}
+		return from;
+	}
+
+	function checkValidator(val) {
+		if (!web3.isAddress(val)) {
+			throw new Error('invalid validator address: ' + val);
+		}
+		if (!dpos.isEffictiveValidator(val, 'latest')) {
+			throw new Error(val + ' is not an effective validator');
+		}
+	}
+
+	function checkRate(rate) {
+		var p = dpos.stakingParams();
+		if (typeof rate !== 'number' || rate % 1 !== 0 || rate < p.minRate || rate > p.maxRate) {
+			throw new Error('rate must be an integer percentage between ' + p.minRate + ' and ' + p.maxRate);
+		}
+	}
+
+	function checkText(text, what, max) {
+		if (typeof text !== 'string') {
+			throw new Error(what + ' must be a string');
+		}
+		if ((web3.fromUtf8(text).length - 2) / 2 > max) {
+			throw new Error(what + ' exceeds ' + max + ' bytes');
+		}
+	}
+
+	function epochOf(block) {
+		return Math.floor(Number(block) / dpos.stakingParams().epochBlocks);
+	}
+
+	// propose submits a validator proposal with the given deposit in DX, once checked
+	// against the limits of the Base contract.
+	dpos.propose = function(name, details, rate, deposit, from) {
+		var p = dpos.stakingParams();
+		checkText(name, 'name', p.maxNameLength);
+		checkText(details, 'details', p.maxDetailsLength);
+		checkRate(rate);
+		var value = toWei(deposit, 'deposit');
+		if (value.lt(p.minDeposit)) {
+			throw new Error('deposit must be at least ' + dx(p.minDeposit));
+		}
+		from = sender(from);
+		if (dpos.isEffictiveValidator(from, 'latest')) {
+			throw new Error(from + ' is already a validator');
+		}
+		return dpos.initProposal(0, rate, name, details, {from: from, value: value});
+	};
+
+	// vote votes for the validator with the given amount in DX, the NodeVotes contract
+	// enforcing the minimum. The raw call taking the transaction options is kept for
+	// an object argument.
+	var vote = dpos.vote;
+	dpos.vote = function(val, amount, from) {
+		if (amount === undefined || amount === null || typeof amount === 'object') {
+			return vote(val, amount);
+		}
+		checkValidator(val);
+		return vote(val, {from: sender(from), value: toWei(amount, 'vote amount')});
+	};
+
+	// unvote cancels the given amount in DX of the votes for the validator, which
+	// can be redeemed once the cancel lock is over.
+	dpos.unvote = function(val, amount, from) {
+		if (!web3.isAddress(val)) {
+			throw new Error('invalid validator address: ' + val);
+		}
+		from = sender(from);
+		var value = toWei(amount, 'vote amount');
+		var info = dpos.votesRewardRedeemInfo(val, from, 'latest');
+		if (value.gt(web3.toBigNumber(info.Amount || 0))) {
+			throw new Error('only ' + dx(info.Amount) + ' voted for ' + val);
+		}
+		return dpos.cancelVote(val, value, {from: from});
+	};
+
+	// proposalStatus formats a validator proposal.
+	dpos.proposalStatus = function(proposal) {
+		if (typeof proposal === 'string') {
+			proposal = dpos.proposal(proposal, 'latest');
+		}
+		var p = dpos.stakingParams();
+		var status = {
+			id: proposal.Id,
+			name: proposal.Name,
+			proposer: proposal.Proposer,
+			deposit: dx(proposal.Deposit),
+			rate: proposal.Rate + '%',
+			status: proposalStatus[proposal.Status] || 'unknown'
+		};
+		if (proposal.Status === 0) {
+			var expiry = Number(proposal.InitBlock) + p.proposalDurationEpochs * p.epochBlocks;
+			if (web3.eth.blockNumber > expiry) {
+				status.status = 'expired';
+			} else {
+				status.expiresAt = expiry;
+			}
+		}
+		if (proposal.Status === 1) {
+			status.guarantee = proposal.Guarantee;
+		}
+		return status;
+	};
+
+	// myPositions reports the validator, the proposals and the votes of the account
+	// along with its pending rewards and redeems.
+	dpos.myPositions = function(addr) {
+		addr = sender(addr);
+		var epoch = epochOf(web3.eth.blockNumber);
+		var positions = {address: addr, epoch: epoch, validator: null};
+
+		var val = dpos.validator(addr, 'latest');
+		if (web3.toBigNumber(val.Deposit || 0).gt(0)) {
+			var reward = dpos.pendingValidatorReward(addr, 'latest');
+			positions.validator = {
+				name: val.Name,
+				status: validatorStatus[val.Status] || 'unknown',
+				deposit: dx(val.Deposit),
+				votes: dx(val.Votes),
+				rate: val.Rate + '%',
+				availableReward: dx(reward.avaliable),
+				frozenReward: dx(reward.frozen),
+				unstakeUnlockBlock: Number(val.UnstakeLockingEndBlock),
+				rateUnlockBlock: Number(val.RateSettLockingEndBlock)
+			};
+		}
+		positions.proposals = (dpos.addressProposals(addr, 'latest') || []).map(dpos.proposalStatus);
+		positions.votes = (dpos.votesRewardRedeemInfos(addr, 'latest') || []).map(function(info) {
+			var locked = (info.LockRedeemEpochs || []).map(function(unlock, i) {
+				return {amount: dx(info.LockRedeemVotes[i]), unlockEpoch: Number(unlock)};
+			});
+			return {
+				validator: info.Validator,
+				name: info.ValidatorName,
+				rate: info.ValidatorRate + '%',
+				votes: dx(info.Amount),
+				pendingReward: dx(info.PendingReward),
+				pendingRedeem: dx(info.PendingRedeem),
+				locked: locked
+			};
+		});
+		return positions;
+	};
+})(web3.dpos);
 `
 
 const EthashJs = `
@@ -69,6 +69,10 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	// Block attestations are not gossiped to light clients.
+	if number == rpc.FinalizedBlockNumber {
+		return nil, errors.New("finalized block not available in light mode")
+	}
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(number))
 }
 
@@ -17,11 +17,14 @@
 package params
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math/big"
 
 	"github.com/DxChainNetwork/dxc/common"
+	"github.com/DxChainNetwork/dxc/common/hexutil"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -117,16 +120,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil, nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil, nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, new(EthashConfig), nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -211,12 +214,42 @@ type ChainConfig struct {
 	RedCoastBlock *big.Int `json:"redCoastBlock,omitempty"` // RedCoast switch block (nil = no fork, 0 = already activated)
 	SophonBlock   *big.Int `json:"sophonBlock,omitempty"`
 
+	SystemContractUpgrades []SystemContractUpgrade `json:"systemContractUpgrades,omitempty"` // Scheduled system contract code replacements, in ascending block order
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 	Dpos   *DposConfig   `json:"dpos,omitempty"`
 }
 
+// SystemContractUpgrade is a scheduled replacement of the code of a system contract.
+// The consensus engine applies it, followed by the optional storage migration, when
+// finalizing the block at the given height, before the punishment and reward calls.
+// User transactions see the new code from the next block on.
+type SystemContractUpgrade struct {
+	Block     *big.Int                    `json:"block"`               // Block number to apply the upgrade at
+	Address   common.Address              `json:"address"`             // Address of the upgraded contract
+	Code      hexutil.Bytes               `json:"code"`                // New runtime code of the contract
+	Storage   map[common.Hash]common.Hash `json:"storage,omitempty"`   // Storage slots to set along with the new code
+	Migration string                      `json:"migration,omitempty"` // Name of the storage migration hook to run after the upgrade
+}
+
+// equal reports whether two upgrades are exactly the same.
+func (u *SystemContractUpgrade) equal(other *SystemContractUpgrade) bool {
+	if !configNumEqual(u.Block, other.Block) || u.Address != other.Address || u.Migration != other.Migration {
+		return false
+	}
+	if !bytes.Equal(u.Code, other.Code) || len(u.Storage) != len(other.Storage) {
+		return false
+	}
+	for slot, value := range u.Storage {
+		if v, ok := other.Storage[slot]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
 type EthashConfig struct{}
 
@@ -242,6 +275,98 @@ type DposConfig struct {
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
 
 	EnableDevVerification bool `json:"enableDevVerification"` // Enable developer address verification
+
+	WeightedScheduleBlock *big.Int `json:"weightedScheduleBlock,omitempty"` // Weighted in-turn scheduling switch block (nil = round-robin only)
+
+	Forks []DposForkConfig `json:"forks,omitempty"` // Scheduled changes of the block period, in ascending order
+
+	InitialValidators []DposInitialValidator `json:"initialValidators,omitempty"` // Genesis validators registered into the system contracts at block 1
+
+	SystemContracts []DposSystemContract `json:"systemContracts,omitempty"` // Overrides of the built-in system contracts
+
+	GovernanceActionsBlock *big.Int              `json:"governanceActionsBlock,omitempty"` // State changing governance actions switch block (nil = evm call and erase only)
+	GovernableParams       []DposGovernableParam `json:"governableParams,omitempty"`       // Chain parameters the governance is allowed to update
+
+	SigningKeysBlock *big.Int `json:"signingKeysBlock,omitempty"` // Validator signing keys switch block (nil = validators seal with their staking account)
+
+	BlacklistV2Block *big.Int `json:"blacklistV2Block,omitempty"` // Expiring and scoped blacklist entries switch block (nil = permanent entries only)
+
+	DevVerificationMode      string   `json:"devVerificationMode,omitempty"`      // Account contract creations are verified against, one of the DevVerification modes
+	DevVerificationModeBlock *big.Int `json:"devVerificationModeBlock,omitempty"` // Developer verification mode switch block (nil = caller mode only)
+}
+
+// The developer verification modes, selecting the account whose allowlisting
+// authorizes a contract creation.
+const (
+	DevVerificationCaller  = "caller"  // The immediate creator, factory contracts must be allowlisted themselves
+	DevVerificationOrigin  = "origin"  // The sender of the transaction, whatever contracts it goes through
+	DevVerificationFactory = "factory" // The immediate creator, factory contracts inheriting the allowlisting of their deployer
+)
+
+// DposGovernableParam is a chain parameter whitelisted for the system governance,
+// stored in a storage slot of a system contract. The governance may only set it
+// within its bounds, a parameter can't be zeroed unless its minimum allows it.
+type DposGovernableParam struct {
+	Name    string         `json:"name"`          // Name the governance proposals refer to the parameter by
+	Address common.Address `json:"address"`       // Contract holding the parameter
+	Slot    common.Hash    `json:"slot"`          // Storage slot of the parameter
+	Min     *big.Int       `json:"min,omitempty"` // Lowest value of the parameter (nil = 1)
+	Max     *big.Int       `json:"max,omitempty"` // Highest value of the parameter (nil = unbounded)
+}
+
+// min returns the lowest value the parameter can be set to.
+func (p DposGovernableParam) min() *big.Int {
+	if p.Min == nil {
+		return common.Big1
+	}
+	return p.Min
+}
+
+// CheckValue checks that the value is within the bounds of the parameter.
+func (p DposGovernableParam) CheckValue(value *big.Int) error {
+	if value.Cmp(p.min()) < 0 {
+		return fmt.Errorf("dpos governable param %q below its minimum %v", p.Name, p.min())
+	}
+	if p.Max != nil && value.Cmp(p.Max) > 0 {
+		return fmt.Errorf("dpos governable param %q above its maximum %v", p.Name, p.Max)
+	}
+	return nil
+}
+
+// DposSystemContract overrides the address, code and ABI of a built-in dpos system
+// contract, the ones not given keep their default values. The code and ABI can be
+// given inline or as file references, the latter are resolved into the inline
+// fields when the genesis is initialized.
+type DposSystemContract struct {
+	Name     string          `json:"name"`               // Name of the system contract, e.g. "Validators"
+	Address  common.Address  `json:"address"`            // Address of the contract (zero = default)
+	Code     hexutil.Bytes   `json:"code,omitempty"`     // Runtime code deployed at genesis
+	CodeFile string          `json:"codeFile,omitempty"` // File holding the hex encoded runtime code
+	ABI      json.RawMessage `json:"abi,omitempty"`      // ABI used to interact with the contract
+	ABIFile  string          `json:"abiFile,omitempty"`  // File holding the JSON ABI
+}
+
+// DposDefaultDeposit is the deposit of each genesis validator if no initial
+// validators are configured.
+var DposDefaultDeposit = new(big.Int).Mul(big.NewInt(Ether), big.NewInt(40000000))
+
+// DposInitialValidator is a genesis validator together with the parameters it's
+// registered with into the system contracts. The deposit is paid from the genesis
+// balance of the validator.
+type DposInitialValidator struct {
+	Address common.Address `json:"address"`
+	Deposit *big.Int       `json:"deposit"`
+	Rate    uint8          `json:"rate"`
+	Name    string         `json:"name"`
+	Details string         `json:"details"`
+}
+
+// DposForkConfig is a scheduled change of the dpos block period. The fork block must
+// be a checkpoint. The epoch length is fixed by the genesis, as the system contracts
+// count the epochs with it.
+type DposForkConfig struct {
+	Block  *big.Int `json:"block"`  // Block number the new period takes effect at
+	Period uint64   `json:"period"` // New number of seconds between blocks
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -249,6 +374,157 @@ func (d *DposConfig) String() string {
 	return "dpos"
 }
 
+// forkAt returns the period in effect at the given block, with the block it took
+// effect at.
+func (d *DposConfig) forkAt(number uint64) DposForkConfig {
+	active := DposForkConfig{Block: new(big.Int), Period: d.Period}
+	for _, fork := range d.Forks {
+		if fork.Block == nil || fork.Block.Uint64() > number {
+			break
+		}
+		active = fork
+	}
+	return active
+}
+
+// PeriodAt returns the number of seconds between blocks in effect at the given block.
+func (d *DposConfig) PeriodAt(number uint64) uint64 {
+	return d.forkAt(number).Period
+}
+
+// IsCheckpoint returns whether the given block is the first block of an epoch.
+func (d *DposConfig) IsCheckpoint(number uint64) bool {
+	return number%d.Epoch == 0
+}
+
+// EpochStart returns the checkpoint block of the epoch the given block belongs to.
+func (d *DposConfig) EpochStart(number uint64) uint64 {
+	return number - number%d.Epoch
+}
+
+// EpochNumber returns the sequence number of the epoch the given block belongs to.
+func (d *DposConfig) EpochNumber(number uint64) uint64 {
+	return number / d.Epoch
+}
+
+// CheckForks checks that the period changes are ordered, that each of them happens
+// at a checkpoint and sets a period.
+func (d *DposConfig) CheckForks() error {
+	var last uint64
+	for i, fork := range d.Forks {
+		if fork.Block == nil || fork.Block.Sign() <= 0 {
+			return fmt.Errorf("invalid dpos fork %d: missing block", i)
+		}
+		number := fork.Block.Uint64()
+		if number <= last {
+			return fmt.Errorf("unsupported dpos fork ordering: fork %d at %d, previous at %d", i, number, last)
+		}
+		if fork.Period == 0 {
+			return fmt.Errorf("invalid dpos fork %d: missing period", i)
+		}
+		if d.Epoch != 0 && !d.IsCheckpoint(number) {
+			return fmt.Errorf("invalid dpos fork %d: block %d is not a checkpoint of epoch length %d", i, number, d.Epoch)
+		}
+		last = number
+	}
+	return nil
+}
+
+// CheckInitialValidators checks that the genesis validators are distinct and that
+// each of them has a deposit.
+func (d *DposConfig) CheckInitialValidators() error {
+	seen := make(map[common.Address]bool)
+	for i, val := range d.InitialValidators {
+		if val.Address == (common.Address{}) {
+			return fmt.Errorf("invalid dpos initial validator %d: missing address", i)
+		}
+		if seen[val.Address] {
+			return fmt.Errorf("duplicate dpos initial validator %x", val.Address)
+		}
+		if val.Deposit == nil || val.Deposit.Sign() <= 0 {
+			return fmt.Errorf("invalid dpos initial validator %x: missing deposit", val.Address)
+		}
+		seen[val.Address] = true
+	}
+	return nil
+}
+
+// CheckGovernableParams checks that the governable parameters are named distinctly
+// and that their bounds are valid storage values.
+func (d *DposConfig) CheckGovernableParams() error {
+	seen := make(map[string]bool)
+	for i, param := range d.GovernableParams {
+		if param.Name == "" {
+			return fmt.Errorf("invalid dpos governable param %d: missing name", i)
+		}
+		if param.Address == (common.Address{}) {
+			return fmt.Errorf("invalid dpos governable param %q: missing address", param.Name)
+		}
+		if seen[param.Name] {
+			return fmt.Errorf("duplicate dpos governable param %q", param.Name)
+		}
+		if param.min().Sign() < 0 || (param.Max != nil && (param.Max.BitLen() > 256 || param.Max.Cmp(param.min()) < 0)) {
+			return fmt.Errorf("invalid dpos governable param %q: invalid bounds", param.Name)
+		}
+		seen[param.Name] = true
+	}
+	return nil
+}
+
+// CheckDevVerificationMode checks that the developer verification mode is known.
+func (d *DposConfig) CheckDevVerificationMode() error {
+	switch d.DevVerificationMode {
+	case "", DevVerificationCaller, DevVerificationOrigin, DevVerificationFactory:
+		return nil
+	}
+	return fmt.Errorf("unknown dpos developer verification mode %q", d.DevVerificationMode)
+}
+
+// GovernableParam returns the whitelisted chain parameter of the given name.
+func (d *DposConfig) GovernableParam(name string) (DposGovernableParam, bool) {
+	for _, param := range d.GovernableParams {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return DposGovernableParam{}, false
+}
+
+// IsGovernanceActions returns whether num is either equal to the governance actions
+// switch block or greater.
+func (d *DposConfig) IsGovernanceActions(num *big.Int) bool {
+	return isForked(d.GovernanceActionsBlock, num)
+}
+
+// IsWeightedSchedule returns whether the epoch starting at checkpoint num uses the
+// stake-proportional in-turn schedule instead of the plain round-robin one.
+func (d *DposConfig) IsWeightedSchedule(num *big.Int) bool {
+	return isForked(d.WeightedScheduleBlock, num)
+}
+
+// IsSigningKeys returns whether num is either equal to the signing keys switch
+// block or greater, from which on validators may seal with a key registered apart
+// from their staking account.
+func (d *DposConfig) IsSigningKeys(num *big.Int) bool {
+	return isForked(d.SigningKeysBlock, num)
+}
+
+// IsBlacklistV2 returns whether num is either equal to the blacklist v2 switch
+// block or greater, from which on the blacklist entries may expire at a block
+// height and be scoped to the events of a single contract.
+func (d *DposConfig) IsBlacklistV2(num *big.Int) bool {
+	return isForked(d.BlacklistV2Block, num)
+}
+
+// DevVerificationModeAt returns the developer verification mode in effect at num,
+// the caller mode until the switch block.
+func (d *DposConfig) DevVerificationModeAt(num *big.Int) string {
+	if d.DevVerificationMode == "" || !isForked(d.DevVerificationModeBlock, num) {
+		return DevVerificationCaller
+	}
+	return d.DevVerificationMode
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -349,6 +625,37 @@ func (c *ChainConfig) IsCatalyst(num *big.Int) bool {
 	return isForked(c.CatalystBlock, num)
 }
 
+// SystemContractUpgradesAt returns the system contract upgrades scheduled at the
+// given block.
+func (c *ChainConfig) SystemContractUpgradesAt(num *big.Int) []SystemContractUpgrade {
+	var upgrades []SystemContractUpgrade
+	for _, upgrade := range c.SystemContractUpgrades {
+		if upgrade.Block != nil && upgrade.Block.Cmp(num) == 0 {
+			upgrades = append(upgrades, upgrade)
+		}
+	}
+	return upgrades
+}
+
+// checkSystemContractUpgrades checks that the system contract upgrades are ordered
+// and complete. Upgrades at the genesis are not allowed, use the genesis alloc instead.
+func (c *ChainConfig) checkSystemContractUpgrades() error {
+	last := common.Big0
+	for i, upgrade := range c.SystemContractUpgrades {
+		if upgrade.Block == nil || upgrade.Block.Sign() <= 0 {
+			return fmt.Errorf("invalid system contract upgrade %d: missing block", i)
+		}
+		if upgrade.Block.Cmp(last) < 0 {
+			return fmt.Errorf("unsupported system contract upgrade ordering: upgrade %d at %v, previous at %v", i, upgrade.Block, last)
+		}
+		if len(upgrade.Code) == 0 {
+			return fmt.Errorf("invalid system contract upgrade %d: missing code", i)
+		}
+		last = upgrade.Block
+	}
+	return nil
+}
+
 // IsRedCoast returns whether num represents a block number after the RedCoast fork
 func (c *ChainConfig) IsRedCoast(num *big.Int) bool {
 	return isForked(c.RedCoastBlock, num)
@@ -420,6 +727,18 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if err := c.checkSystemContractUpgrades(); err != nil {
+		return err
+	}
+	if c.Dpos != nil {
+		if err := c.Dpos.CheckForks(); err != nil {
+			return err
+		}
+		if err := c.Dpos.CheckDevVerificationMode(); err != nil {
+			return err
+		}
+		return c.Dpos.CheckGovernableParams()
+	}
 	return nil
 }
 
@@ -473,6 +792,63 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.RedCoastBlock, newcfg.RedCoastBlock, head) {
 		return newCompatError("RedCoast fork block", c.RedCoastBlock, newcfg.RedCoastBlock)
 	}
+	for i := 0; i < len(c.SystemContractUpgrades) || i < len(newcfg.SystemContractUpgrades); i++ {
+		var stored, next SystemContractUpgrade
+		if i < len(c.SystemContractUpgrades) {
+			stored = c.SystemContractUpgrades[i]
+		}
+		if i < len(newcfg.SystemContractUpgrades) {
+			next = newcfg.SystemContractUpgrades[i]
+		}
+		if isForkIncompatible(stored.Block, next.Block, head) {
+			return newCompatError("System contract upgrade block", stored.Block, next.Block)
+		}
+		if isForked(stored.Block, head) && !stored.equal(&next) {
+			return newCompatError("System contract upgrade", stored.Block, next.Block)
+		}
+	}
+	if c.Dpos != nil && newcfg.Dpos != nil {
+		if err := c.Dpos.checkCompatible(newcfg.Dpos, head); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCompatible checks that no dpos period or epoch change, nor the weighted
+// schedule, governance actions, signing keys, blacklist v2 or developer verification
+// mode fork, at or before head was rescheduled.
+func (d *DposConfig) checkCompatible(newcfg *DposConfig, head *big.Int) *ConfigCompatError {
+	if isForkIncompatible(d.WeightedScheduleBlock, newcfg.WeightedScheduleBlock, head) {
+		return newCompatError("Dpos weighted schedule fork block", d.WeightedScheduleBlock, newcfg.WeightedScheduleBlock)
+	}
+	if isForkIncompatible(d.GovernanceActionsBlock, newcfg.GovernanceActionsBlock, head) {
+		return newCompatError("Dpos governance actions fork block", d.GovernanceActionsBlock, newcfg.GovernanceActionsBlock)
+	}
+	if isForkIncompatible(d.SigningKeysBlock, newcfg.SigningKeysBlock, head) {
+		return newCompatError("Dpos signing keys fork block", d.SigningKeysBlock, newcfg.SigningKeysBlock)
+	}
+	if isForkIncompatible(d.BlacklistV2Block, newcfg.BlacklistV2Block, head) {
+		return newCompatError("Dpos blacklist v2 fork block", d.BlacklistV2Block, newcfg.BlacklistV2Block)
+	}
+	if isForkIncompatible(d.DevVerificationModeBlock, newcfg.DevVerificationModeBlock, head) || d.DevVerificationModeAt(head) != newcfg.DevVerificationModeAt(head) {
+		return newCompatError("Dpos developer verification mode fork block", d.DevVerificationModeBlock, newcfg.DevVerificationModeBlock)
+	}
+	for i := 0; i < len(d.Forks) || i < len(newcfg.Forks); i++ {
+		var stored, next DposForkConfig
+		if i < len(d.Forks) {
+			stored = d.Forks[i]
+		}
+		if i < len(newcfg.Forks) {
+			next = newcfg.Forks[i]
+		}
+		if isForkIncompatible(stored.Block, next.Block, head) {
+			return newCompatError("Dpos fork block", stored.Block, next.Block)
+		}
+		if isForked(stored.Block, head) && (stored.Period != next.Period) {
+			return newCompatError("Dpos fork parameters", stored.Block, next.Block)
+		}
+	}
 	return nil
 }
 
@@ -87,6 +87,17 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     30,
 			},
 		},
+		{
+			stored: &ChainConfig{Dpos: &DposConfig{Epoch: 10, WeightedScheduleBlock: big.NewInt(30)}},
+			new:    &ChainConfig{Dpos: &DposConfig{Epoch: 10, WeightedScheduleBlock: big.NewInt(50)}},
+			head:   40,
+			wantErr: &ConfigCompatError{
+				What:         "Dpos weighted schedule fork block",
+				StoredConfig: big.NewInt(30),
+				NewConfig:    big.NewInt(50),
+				RewindTo:     29,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -96,3 +107,21 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestDposCheckForks(t *testing.T) {
+	tests := []struct {
+		forks   []DposForkConfig
+		wantErr bool
+	}{
+		{forks: []DposForkConfig{{Block: big.NewInt(100), Period: 3}}},
+		{forks: []DposForkConfig{{Block: big.NewInt(105), Period: 3}}, wantErr: true},
+		{forks: []DposForkConfig{{Block: big.NewInt(200), Period: 3}, {Block: big.NewInt(100), Period: 2}}, wantErr: true},
+		{forks: []DposForkConfig{{Block: big.NewInt(100)}}, wantErr: true},
+	}
+	for i, test := range tests {
+		config := &DposConfig{Period: 6, Epoch: 10, Forks: test.forks}
+		if err := config.CheckForks(); (err != nil) != test.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %v", i, err, test.wantErr)
+		}
+	}
+}